@@ -22,6 +22,12 @@ const (
 
 	CCMNamespaceKey = "POD_NAMESPACE"
 
+	// ConfigMapNameKey is the environment variable naming the ConfigMap of the cloud config,
+	// which is reloaded when it changes
+	ConfigMapNameKey     = "NUTANIX_CONFIG_MAP_NAME"
+	DefaultConfigMapName = "nutanix-config"
+	ConfigMapKey         = "nutanix_config.json"
+
 	InstanceType string = "ahv-vm"
 
 	PoweredOffState string = "OFF"
@@ -33,6 +39,14 @@ const (
 	CustomHostNameLabel            string = "nutanix.com/prism-host-name"
 	MetroNodeGroupLabel            string = "nutanix.com/metro-site-group"
 	MetroNodeGroupNameAttributeKey string = "nutanix.com/metro-node-group-name"
+	ProviderIDAttributeKey         string = "providerID"
 
 	PrismCentralService string = "PRISM_CENTRAL"
+
+	// PrismCentralAnnotation names the Prism Central managing the VM of the node when several
+	// Prism Centrals are configured
+	PrismCentralAnnotation string = "nutanix.com/prism-central"
+
+	LoadBalancerAllocationsConfigMapName string = "nutanix-lb-allocations"
+	LoadBalancerClientContextPrefix      string = "k8s-service-"
 )
@@ -22,6 +22,7 @@ const (
 	MockPrismCentral = "mock-pc"
 	MockRegion       = "mock-region"
 	MockZone         = "mock-zone"
+	MockHostZone     = "mock-host-zone"
 
 	MockDefaultRegion = "region"
 	MockDefaultZone   = "zone"
@@ -36,12 +37,30 @@ const (
 	MockVMNameSecondaryIPs               = "mock-vm-secondary-ips"
 	MockVMNameCustomProviderID           = "mock-vm-custom-provider-id"
 	MockVMNameMetro                      = "mock-vm-metro"
+	MockVMNameVPC                        = "mock-vm-vpc"
+	MockVMNameMultiNIC                   = "mock-vm-multi-nic"
+	MockVMNameHostCategories             = "mock-vm-host-categories"
 
 	MockSecondaryIP1       = "2.2.2.2"
 	MockSecondaryIP2       = "3.3.3.3"
 	MockCustomProviderID   = "custom-provider-uuid-1234"
 	MockMetroNodeGroupName = "mock-metro-group"
 
+	MockVMNumSockets              = 2
+	MockVMNumCoresPerSocket       = 2
+	MockVMMemorySizeBytes   int64 = 8 << 30
+	MockInstanceType              = "ahv-4c-8g"
+
+	MockSubnetIP1 = "10.10.0.10"
+	MockSubnetIP2 = "10.10.0.11"
+
+	MockSubnetName        = "mock-subnet"
+	MockStorageSubnetName = "mock-storage-subnet"
+	MockStorageIP         = "4.4.4.4"
+
+	MockFloatingIP1 = "192.0.2.10"
+	MockFloatingIP2 = "192.0.2.11"
+
 	MockNodeNameVMNotExisting = "mock-node-no-vm-exists"
 	MockNodeNameNoSystemUUID  = "mock-node-no-system-uuid"
 
@@ -55,12 +74,14 @@ const (
 	mockPort              = 9440
 	mockInsecure          = false
 	mockClusterCategories = "mock-cluster-categories"
+	mockHostCategories    = "mock-host-categories"
 
 	// Consistent UUIDs for all mock entities
 	MockClusterUUID                      = "00000000-0000-0000-0000-000000000001"
 	MockPrismCentralUUID                 = "00000000-0000-0000-0000-000000000002"
 	MockClusterCategoriesUUID            = "00000000-0000-0000-0000-000000000003"
 	MockHostUUID                         = "00000000-0000-0000-0000-000000000010"
+	MockHostCategoriesUUID               = "00000000-0000-0000-0000-000000000011"
 	MockVMPoweredOnUUID                  = "00000000-0000-0000-0000-000000000100"
 	MockVMPoweredOffUUID                 = "00000000-0000-0000-0000-000000000101"
 	MockVMCategoriesUUID                 = "00000000-0000-0000-0000-000000000102"
@@ -71,6 +92,19 @@ const (
 	MockVMSecondaryIPsUUID               = "00000000-0000-0000-0000-000000000107"
 	MockVMCustomProviderIDUUID           = "00000000-0000-0000-0000-000000000108"
 	MockVMMetroUUID                      = "00000000-0000-0000-0000-000000000109"
+	MockVMVPCUUID                        = "00000000-0000-0000-0000-000000000110"
+	MockVMMultiNICUUID                   = "00000000-0000-0000-0000-000000000111"
+	MockVMHostCategoriesUUID             = "00000000-0000-0000-0000-000000000112"
 	MockCategoryRegionUUID               = "00000000-0000-0000-0000-000000000200"
 	MockCategoryZoneUUID                 = "00000000-0000-0000-0000-000000000201"
+	MockCategoryHostZoneUUID             = "00000000-0000-0000-0000-000000000202"
+	MockSubnetUUID                       = "00000000-0000-0000-0000-000000000300"
+	MockVPCSubnetUUID                    = "00000000-0000-0000-0000-000000000301"
+	MockExternalSubnetUUID               = "00000000-0000-0000-0000-000000000302"
+	MockStorageSubnetUUID                = "00000000-0000-0000-0000-000000000303"
+	MockVPCUUID                          = "00000000-0000-0000-0000-000000000400"
+	MockVPCNicUUID                       = "00000000-0000-0000-0000-000000000500"
+	MockClusterNicUUID                   = "00000000-0000-0000-0000-000000000501"
+	MockStorageNicUUID                   = "00000000-0000-0000-0000-000000000502"
+	MockRouteTableUUID                   = "00000000-0000-0000-0000-000000000600"
 )
@@ -0,0 +1,51 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mock
+
+import (
+	"net/http"
+
+	"github.com/nutanix-cloud-native/prism-go-client/converged"
+)
+
+// HTTPError is the error of an HTTP response, carrying its status and headers.
+type HTTPError struct {
+	Status string
+	Header http.Header
+}
+
+func (e *HTTPError) Error() string {
+	return e.Status
+}
+
+// ResponseHeader returns the headers of the response.
+func (e *HTTPError) ResponseHeader() http.Header {
+	return e.Header
+}
+
+// RateLimitError returns the error of a 429 response with the Retry-After header, which is
+// omitted if retryAfter is empty.
+func RateLimitError(retryAfter string) error {
+	header := http.Header{}
+	if retryAfter != "" {
+		header.Set("Retry-After", retryAfter)
+	}
+	return &converged.APIError{
+		Kind:  converged.ErrRateLimit,
+		Cause: &HTTPError{Status: "429 Too Many Requests", Header: header},
+	}
+}
@@ -73,6 +73,10 @@ func getDefaultVM(vmName string, vmUUID string, cluster *clusterModels.Cluster,
 		Nics: []vmmModels.Nic{
 			*nic,
 		},
+		NumSockets:        ptr.To(MockVMNumSockets),
+		NumCoresPerSocket: ptr.To(MockVMNumCoresPerSocket),
+		NumThreadsPerCore: ptr.To(1),
+		MemorySizeBytes:   ptr.To(MockVMMemorySizeBytes),
 	}
 	if host != nil {
 		vm.Host = &vmmModels.HostReference{
@@ -218,6 +222,55 @@ func getDefaultVMWithCustomAttributes(vmName string, vmUUID string, cluster *clu
 	return vm
 }
 
+func getDefaultVMWithSubnet(vmName string, vmUUID string, nicUUID string, subnetUUID string, cluster *clusterModels.Cluster, host *clusterModels.Host) *vmmModels.Vm {
+	nic := getNicWithSubnet(nicUUID, subnetUUID, MockIP)
+	if nic == nil {
+		return nil
+	}
+
+	vm := getDefaultVM(vmName, vmUUID, cluster, host)
+	vm.Nics = []vmmModels.Nic{
+		*nic,
+	}
+	return vm
+}
+
+// getDefaultVMWithStorageNIC returns a VM with a cluster network NIC and a storage network NIC.
+func getDefaultVMWithStorageNIC(vmName string, vmUUID string, cluster *clusterModels.Cluster, host *clusterModels.Host) *vmmModels.Vm {
+	storageNic := getNicWithSubnet(MockStorageNicUUID, MockStorageSubnetUUID, MockStorageIP)
+	if storageNic == nil {
+		return nil
+	}
+
+	vm := getDefaultVMWithSubnet(vmName, vmUUID, MockClusterNicUUID, MockSubnetUUID, cluster, host)
+	if vm == nil {
+		return nil
+	}
+	vm.Nics = append(vm.Nics, *storageNic)
+	return vm
+}
+
+func getNicWithSubnet(nicUUID string, subnetUUID string, ip string) *vmmModels.Nic {
+	nic := vmmModels.NewNic()
+	nic.ExtId = ptr.To(nicUUID)
+	nicNetInfo := vmmModels.NewVirtualEthernetNicNetworkInfo()
+	nicNetInfo.Subnet = &vmmModels.SubnetReference{
+		ExtId: ptr.To(subnetUUID),
+	}
+
+	nicNetInfo.Ipv4Config = vmmModels.NewIpv4Config()
+	nicNetInfo.Ipv4Config.IpAddress = &vmmCommonModels.IPv4Address{
+		Value: ptr.To(ip),
+	}
+
+	err := nic.SetNicNetworkInfo(*nicNetInfo)
+	if err != nil {
+		fmt.Printf("error setting nic network info: %+v\n", err)
+		return nil
+	}
+	return nic
+}
+
 func getDefaultCluster(clusterName string, clusterUUID string) *clusterModels.Cluster {
 	cluster := clusterModels.NewCluster()
 	cluster.ExtId = ptr.To(clusterUUID)
@@ -235,6 +288,9 @@ func getDefaultHost(hostName string, hostUUID string, clusterUUID string) *clust
 	host.Cluster = &clusterModels.ClusterReference{
 		Uuid: ptr.To(clusterUUID),
 	}
+	host.Hypervisor = &clusterModels.HypervisorReference{
+		State: clusterModels.HYPERVISORSTATE_ACROPOLIS_NORMAL.Ref(),
+	}
 	return host
 }
 
@@ -246,6 +302,14 @@ func getDefaultCategory(categoryName string, categoryUUID string, categoryValue
 	return category
 }
 
+func associateCategory(category *prismModels.Category, resourceType prismModels.ResourceType, resourceUUID string) {
+	category.DetailedAssociations = append(category.DetailedAssociations, prismModels.AssociationDetail{
+		CategoryId:   category.ExtId,
+		ResourceType: resourceType.Ref(),
+		ResourceId:   ptr.To(resourceUUID),
+	})
+}
+
 func createNodeForVM(ctx context.Context, kClient *fake.Clientset, vm *vmmModels.Vm) (*v1.Node, error) {
 	n := &v1.Node{
 		ObjectMeta: metav1.ObjectMeta{
@@ -268,7 +332,7 @@ func createNodeForVM(ctx context.Context, kClient *fake.Clientset, vm *vmmModels
 
 func ValidateInstanceMetadata(metadata *cloudprovider.InstanceMetadata, vm *vmmModels.Vm, region, zone string) {
 	Expect(metadata).NotTo(BeNil())                                               // nolint:typecheck
-	Expect(metadata.InstanceType).To(Equal(constants.InstanceType))               // nolint:typecheck
+	Expect(metadata.InstanceType).To(Equal(MockInstanceType))                     // nolint:typecheck
 	Expect(metadata.ProviderID).To(Equal(fmt.Sprintf("nutanix://%s", *vm.ExtId))) // nolint:typecheck
 	Expect(metadata.Region).To(Equal(region))                                     // nolint:typecheck
 	Expect(metadata.Zone).To(Equal(zone))                                         // nolint:typecheck
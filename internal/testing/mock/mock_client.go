@@ -17,6 +17,8 @@ limitations under the License.
 package mock
 
 import (
+	"sync/atomic"
+
 	"k8s.io/client-go/informers"
 
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
@@ -26,6 +28,7 @@ import (
 type MockClient struct {
 	mockPrism       MockPrism
 	sharedInformers informers.SharedInformerFactory
+	invalidations   atomic.Int32
 }
 
 // CreateMockClient creates a new MockClient
@@ -42,7 +45,27 @@ func (mc *MockClient) Get() (interfaces.Prism, error) {
 	return &mc.mockPrism, nil
 }
 
+// Calls returns the number of calls of the Prism method.
+func (mc *MockClient) Calls(method string) int {
+	return mc.mockPrism.Calls(method)
+}
+
+// InjectFailures makes the next calls of the Prism method return the errors, one per call.
+func (mc *MockClient) InjectFailures(method string, errs ...error) {
+	mc.mockPrism.InjectFailures(method, errs...)
+}
+
 // SetInformers sets the sharedInformers
 func (mc *MockClient) SetInformers(sharedInformers informers.SharedInformerFactory) {
 	mc.sharedInformers = sharedInformers
 }
+
+// Invalidate counts the invalidations of the client
+func (mc *MockClient) Invalidate() {
+	mc.invalidations.Add(1)
+}
+
+// Invalidations returns the number of invalidations of the client.
+func (mc *MockClient) Invalidations() int {
+	return int(mc.invalidations.Load())
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
 	clusterModels "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/models/clustermgmt/v4/config"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
 	prismModels "github.com/nutanix/ntnx-api-golang-clients/prism-go-client/v4/models/prism/v4/config"
 	vmmCommonModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/common/v1/config"
 	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
@@ -33,12 +34,42 @@ import (
 )
 
 type MockEnvironment struct {
-	managedMockMachines   map[string]*vmmModels.Vm
-	managedMockClusters   map[string]*clusterModels.Cluster
-	managedMockHosts      map[string]*clusterModels.Host
-	managedMockCategories map[string]*prismModels.Category
-	managedNodes          map[string]*v1.Node
-	vmNameToExtId         map[string]string
+	managedMockMachines    map[string]*vmmModels.Vm
+	managedMockClusters    map[string]*clusterModels.Cluster
+	managedMockHosts       map[string]*clusterModels.Host
+	managedMockCategories  map[string]*prismModels.Category
+	managedMockSubnets     map[string]*MockSubnet
+	managedMockVPCs        map[string]*networkingModels.Vpc
+	managedMockFloatingIPs map[string]*networkingModels.FloatingIp
+	managedMockRouteTables map[string]*MockRouteTable
+	managedNodes           map[string]*v1.Node
+	vmNameToExtId          map[string]string
+}
+
+// MockSubnet models the IPAM of a Nutanix managed subnet
+type MockSubnet struct {
+	Name string
+	// VPCUUID is set for subnets attached to a VPC
+	VPCUUID string
+	// FreeIPs are handed out in order by count based reservations
+	FreeIPs []string
+	// ReservedIPs maps each reserved IP to the client context of its reservation
+	ReservedIPs map[string]string
+}
+
+// MockRouteTable models the route table of a VPC
+type MockRouteTable struct {
+	VPCUUID string
+	// Routes maps each route UUID to its route
+	Routes map[string]*networkingModels.Route
+}
+
+func (m *MockEnvironment) GetRouteTable(routeTableUUID string) *MockRouteTable {
+	return m.managedMockRouteTables[routeTableUUID]
+}
+
+func (m *MockEnvironment) GetSubnet(subnetUUID string) *MockSubnet {
+	return m.managedMockSubnets[subnetUUID]
 }
 
 func (m *MockEnvironment) GetVM(ctx context.Context, vmName string) *vmmModels.Vm {
@@ -64,6 +95,21 @@ func (m *MockEnvironment) GetCluster(ctx context.Context, clusterName string) *c
 	return nil
 }
 
+// SetHostMaintenanceState sets the hypervisor state of the host, e.g. ENTERED_MAINTENANCE_MODE.
+func (m *MockEnvironment) SetHostMaintenanceState(hostUUID string, state clusterModels.HypervisorState) {
+	host, ok := m.managedMockHosts[hostUUID]
+	Expect(ok).To(BeTrue()) // nolint:typecheck
+	host.Hypervisor.State = state.Ref()
+}
+
+// DeleteVM deletes the VM, e.g. to simulate a VM managed by another Prism Central.
+func (m *MockEnvironment) DeleteVM(vmName string) {
+	extId, ok := m.vmNameToExtId[vmName]
+	Expect(ok).To(BeTrue()) // nolint:typecheck
+	delete(m.managedMockMachines, extId)
+	delete(m.vmNameToExtId, vmName)
+}
+
 func (m *MockEnvironment) AddCluster(cluster *clusterModels.Cluster) *clusterModels.Cluster {
 	Expect(cluster).ToNot(BeNil()) // nolint:typecheck
 	m.managedMockClusters[*cluster.ExtId] = cluster
@@ -83,10 +129,14 @@ func CreateMockEnvironment(ctx context.Context, kClient *fake.Clientset) (*MockE
 
 	// Create host with consistent UUID
 	host := getDefaultHost(mockHost, MockHostUUID, MockClusterUUID)
+	hostCategories := getDefaultHost(mockHostCategories, MockHostCategoriesUUID, MockClusterUUID)
 
 	// Create categories with consistent UUIDs
 	regionCategory := getDefaultCategory(MockDefaultRegion, MockCategoryRegionUUID, MockRegion)
 	zoneCategory := getDefaultCategory(MockDefaultZone, MockCategoryZoneUUID, MockZone)
+	hostZoneCategory := getDefaultCategory(MockDefaultZone, MockCategoryHostZoneUUID, MockHostZone)
+	associateCategory(regionCategory, prismModels.RESOURCETYPE_HOST, MockHostCategoriesUUID)
+	associateCategory(hostZoneCategory, prismModels.RESOURCETYPE_HOST, MockHostCategoriesUUID)
 
 	// Create VMs with consistent UUIDs
 	poweredOnVM := getDefaultVM(MockVMNamePoweredOn, MockVMPoweredOnUUID, cluster, host)
@@ -208,6 +258,31 @@ func CreateMockEnvironment(ctx context.Context, kClient *fake.Clientset) (*MockE
 		return nil, err
 	}
 
+	vpcVM := getDefaultVMWithSubnet(MockVMNameVPC, MockVMVPCUUID, MockVPCNicUUID, MockVPCSubnetUUID, cluster, host)
+	vpcNode, err := createNodeForVM(ctx, kClient, vpcVM)
+	if err != nil {
+		return nil, err
+	}
+
+	multiNICVM := getDefaultVMWithStorageNIC(MockVMNameMultiNIC, MockVMMultiNICUUID, cluster, host)
+	multiNICNode, err := createNodeForVM(ctx, kClient, multiNICVM)
+	if err != nil {
+		return nil, err
+	}
+
+	vpc := &networkingModels.Vpc{
+		ExtId: ptr.To(MockVPCUUID),
+		ExternalSubnets: []networkingModels.ExternalSubnet{
+			{SubnetReference: ptr.To(MockExternalSubnetUUID)},
+		},
+	}
+
+	hostCategoriesVM := getDefaultVM(MockVMNameHostCategories, MockVMHostCategoriesUUID, cluster, hostCategories)
+	hostCategoriesNode, err := createNodeForVM(ctx, kClient, hostCategoriesVM)
+	if err != nil {
+		return nil, err
+	}
+
 	return &MockEnvironment{
 		managedMockMachines: map[string]*vmmModels.Vm{
 			*poweredOnVM.ExtId:                  poweredOnVM,
@@ -220,6 +295,9 @@ func CreateMockEnvironment(ctx context.Context, kClient *fake.Clientset) (*MockE
 			*secondaryIPsVM.ExtId:               secondaryIPsVM,
 			*customProviderIDVM.ExtId:           customProviderIDVM,
 			*metroVM.ExtId:                      metroVM,
+			*vpcVM.ExtId:                        vpcVM,
+			*multiNICVM.ExtId:                   multiNICVM,
+			*hostCategoriesVM.ExtId:             hostCategoriesVM,
 		},
 		managedMockClusters: map[string]*clusterModels.Cluster{
 			*cluster.ExtId:           cluster,
@@ -227,11 +305,42 @@ func CreateMockEnvironment(ctx context.Context, kClient *fake.Clientset) (*MockE
 			*pc.ExtId:                pc,
 		},
 		managedMockHosts: map[string]*clusterModels.Host{
-			*host.ExtId: host,
+			*host.ExtId:           host,
+			*hostCategories.ExtId: hostCategories,
 		},
 		managedMockCategories: map[string]*prismModels.Category{
-			*regionCategory.ExtId: regionCategory,
-			*zoneCategory.ExtId:   zoneCategory,
+			*regionCategory.ExtId:   regionCategory,
+			*zoneCategory.ExtId:     zoneCategory,
+			*hostZoneCategory.ExtId: hostZoneCategory,
+		},
+		managedMockSubnets: map[string]*MockSubnet{
+			MockSubnetUUID: {
+				Name:        MockSubnetName,
+				FreeIPs:     []string{MockSubnetIP1, MockSubnetIP2},
+				ReservedIPs: map[string]string{},
+			},
+			MockVPCSubnetUUID: {
+				VPCUUID:     MockVPCUUID,
+				ReservedIPs: map[string]string{},
+			},
+			MockExternalSubnetUUID: {
+				FreeIPs:     []string{MockFloatingIP1, MockFloatingIP2},
+				ReservedIPs: map[string]string{},
+			},
+			MockStorageSubnetUUID: {
+				Name:        MockStorageSubnetName,
+				ReservedIPs: map[string]string{},
+			},
+		},
+		managedMockVPCs: map[string]*networkingModels.Vpc{
+			*vpc.ExtId: vpc,
+		},
+		managedMockFloatingIPs: map[string]*networkingModels.FloatingIp{},
+		managedMockRouteTables: map[string]*MockRouteTable{
+			MockRouteTableUUID: {
+				VPCUUID: MockVPCUUID,
+				Routes:  map[string]*networkingModels.Route{},
+			},
 		},
 		managedNodes: map[string]*v1.Node{
 			MockVMNamePoweredOn:                  poweredOnNode,
@@ -246,6 +355,9 @@ func CreateMockEnvironment(ctx context.Context, kClient *fake.Clientset) (*MockE
 			MockVMNameSecondaryIPs:               secondaryIPsNode,
 			MockVMNameCustomProviderID:           customProviderIDNode,
 			MockVMNameMetro:                      metroNode,
+			MockVMNameVPC:                        vpcNode,
+			MockVMNameMultiNIC:                   multiNICNode,
+			MockVMNameHostCategories:             hostCategoriesNode,
 		},
 		vmNameToExtId: map[string]string{
 			MockVMNamePoweredOn:                  *poweredOnVM.ExtId,
@@ -258,6 +370,9 @@ func CreateMockEnvironment(ctx context.Context, kClient *fake.Clientset) (*MockE
 			MockVMNameSecondaryIPs:               *secondaryIPsVM.ExtId,
 			MockVMNameCustomProviderID:           *customProviderIDVM.ExtId,
 			MockVMNameMetro:                      *metroVM.ExtId,
+			MockVMNameVPC:                        *vpcVM.ExtId,
+			MockVMNameMultiNIC:                   *multiNICVM.ExtId,
+			MockVMNameHostCategories:             *hostCategoriesVM.ExtId,
 		},
 	}, nil
 }
@@ -19,29 +19,121 @@ package mock
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
 
 	"github.com/nutanix-cloud-native/prism-go-client/converged"
 	clusterModels "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/models/clustermgmt/v4/config"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
 	prismModels "github.com/nutanix/ntnx-api-golang-clients/prism-go-client/v4/models/prism/v4/config"
 	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/utils/ptr"
 )
 
 type MockPrism struct {
 	mockEnvironment MockEnvironment
+
+	mu sync.Mutex
+	// calls counts the calls of each method
+	calls map[string]int
+	// failures holds the errors returned by the next calls of each method
+	failures map[string][]error
+}
+
+// recordCall records a call of the method and returns the injected failure of the call, if any.
+func (mp *MockPrism) recordCall(method string) error {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	if mp.calls == nil {
+		mp.calls = make(map[string]int)
+	}
+	mp.calls[method]++
+	if failures := mp.failures[method]; len(failures) > 0 {
+		mp.failures[method] = failures[1:]
+		return failures[0]
+	}
+	return nil
+}
+
+// Calls returns the number of calls of the method.
+func (mp *MockPrism) Calls(method string) int {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	return mp.calls[method]
+}
+
+// InjectFailures makes the next calls of the method return the errors, one per call.
+func (mp *MockPrism) InjectFailures(method string, errs ...error) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	if mp.failures == nil {
+		mp.failures = make(map[string][]error)
+	}
+	mp.failures[method] = append(mp.failures[method], errs...)
 }
 
 func (mp *MockPrism) GetVM(ctx context.Context, vmUUID string) (*vmmModels.Vm, error) {
+	if err := mp.recordCall("GetVM"); err != nil {
+		return nil, err
+	}
 	if v, ok := mp.mockEnvironment.managedMockMachines[vmUUID]; ok {
 		return v, nil
 	}
 	return nil, &converged.APIError{Kind: converged.ErrNotFound, Cause: fmt.Errorf("%s", vmNotFoundError)}
 }
 
+// ListVMs supports an empty filter, and filters on the name or on custom attributes with
+// customAttributes/any(a:a eq '<key>:<value>') terms joined by or
+func (mp *MockPrism) ListVMs(ctx context.Context, filter string) ([]vmmModels.Vm, error) {
+	if err := mp.recordCall("ListVMs"); err != nil {
+		return nil, err
+	}
+	var match func(vm *vmmModels.Vm) bool
+	if strings.HasPrefix(filter, "customAttributes/") {
+		var attrs []string
+		for _, term := range strings.Split(filter, " or ") {
+			attr, ok := strings.CutPrefix(term, "customAttributes/any(a:a eq '")
+			if !ok {
+				return nil, fmt.Errorf("unsupported filter %q", filter)
+			}
+			if attr, ok = strings.CutSuffix(attr, "')"); !ok {
+				return nil, fmt.Errorf("unsupported filter %q", filter)
+			}
+			attrs = append(attrs, strings.ReplaceAll(attr, "''", "'"))
+		}
+		match = func(vm *vmmModels.Vm) bool {
+			return slices.ContainsFunc(vm.CustomAttributes, func(attr string) bool { return slices.Contains(attrs, attr) })
+		}
+	} else {
+		name, err := parseEqFilter(filter, "name")
+		if err != nil {
+			return nil, err
+		}
+		match = func(vm *vmmModels.Vm) bool { return name == "" || ptr.Deref(vm.Name, "") == name }
+	}
+
+	vms := make([]vmmModels.Vm, 0)
+	for _, vm := range mp.mockEnvironment.managedMockMachines {
+		if match(vm) {
+			vms = append(vms, *vm)
+		}
+	}
+	return vms, nil
+}
+
 func (mp *MockPrism) GetCluster(ctx context.Context, clusterUUID string) (*clusterModels.Cluster, error) {
+	if err := mp.recordCall("GetCluster"); err != nil {
+		return nil, err
+	}
 	return mp.mockEnvironment.managedMockClusters[clusterUUID], nil
 }
 
 func (mp *MockPrism) ListAllCluster(ctx context.Context) ([]clusterModels.Cluster, error) {
+	if err := mp.recordCall("ListAllCluster"); err != nil {
+		return nil, err
+	}
 	entities := make([]clusterModels.Cluster, 0)
 
 	for _, e := range mp.mockEnvironment.managedMockClusters {
@@ -51,15 +143,296 @@ func (mp *MockPrism) ListAllCluster(ctx context.Context) ([]clusterModels.Cluste
 }
 
 func (mp *MockPrism) GetCategory(ctx context.Context, categoryUUID string) (*prismModels.Category, error) {
+	if err := mp.recordCall("GetCategory"); err != nil {
+		return nil, err
+	}
 	if cat, ok := mp.mockEnvironment.managedMockCategories[categoryUUID]; ok {
 		return cat, nil
 	}
 	return nil, &converged.APIError{Kind: converged.ErrNotFound, Cause: fmt.Errorf("%s", entityNotFoundError)}
 }
 
+// ListCategories supports an empty filter and filters on the key
+func (mp *MockPrism) ListCategories(ctx context.Context, filter string) ([]prismModels.Category, error) {
+	if err := mp.recordCall("ListCategories"); err != nil {
+		return nil, err
+	}
+	key, err := parseEqFilter(filter, "key")
+	if err != nil {
+		return nil, err
+	}
+
+	categories := make([]prismModels.Category, 0)
+	for _, category := range mp.mockEnvironment.managedMockCategories {
+		if key == "" || ptr.Deref(category.Key, "") == key {
+			categories = append(categories, *category)
+		}
+	}
+	return categories, nil
+}
+
 func (mp *MockPrism) GetClusterHost(ctx context.Context, clusterUuid string, hostUUID string) (*clusterModels.Host, error) {
+	if err := mp.recordCall("GetClusterHost"); err != nil {
+		return nil, err
+	}
 	if host, ok := mp.mockEnvironment.managedMockHosts[hostUUID]; ok {
 		return host, nil
 	}
 	return nil, &converged.APIError{Kind: converged.ErrNotFound, Cause: fmt.Errorf("%s", entityNotFoundError)}
 }
+
+func (mp *MockPrism) ReserveSubnetIPs(ctx context.Context, subnetUUID string, spec *networkingModels.IpReserveSpec) error {
+	subnet, ok := mp.mockEnvironment.managedMockSubnets[subnetUUID]
+	if !ok {
+		return &converged.APIError{Kind: converged.ErrNotFound, Cause: fmt.Errorf("%s", entityNotFoundError)}
+	}
+	clientContext := ptr.Deref(spec.ClientContext, "")
+
+	switch ptr.Deref(spec.ReserveType, networkingModels.RESERVETYPE_UNKNOWN) {
+	case networkingModels.RESERVETYPE_IP_ADDRESS_COUNT:
+		count := int(ptr.Deref(spec.Count, 0))
+		if count > len(subnet.FreeIPs) {
+			return fmt.Errorf("not enough free IPs in subnet %s", subnetUUID)
+		}
+		for _, ip := range subnet.FreeIPs[:count] {
+			subnet.ReservedIPs[ip] = clientContext
+		}
+		subnet.FreeIPs = subnet.FreeIPs[count:]
+	case networkingModels.RESERVETYPE_IP_ADDRESS_LIST:
+		for _, address := range spec.IpAddresses {
+			ip := ptr.Deref(address.Ipv4.Value, "")
+			idx := slices.Index(subnet.FreeIPs, ip)
+			if idx < 0 {
+				return fmt.Errorf("IP %s is not free in subnet %s", ip, subnetUUID)
+			}
+			subnet.FreeIPs = slices.Delete(subnet.FreeIPs, idx, idx+1)
+			subnet.ReservedIPs[ip] = clientContext
+		}
+	default:
+		return fmt.Errorf("unsupported reserve type %v", spec.ReserveType)
+	}
+	return nil
+}
+
+func (mp *MockPrism) UnreserveSubnetIPs(ctx context.Context, subnetUUID string, spec *networkingModels.IpUnreserveSpec) error {
+	subnet, ok := mp.mockEnvironment.managedMockSubnets[subnetUUID]
+	if !ok {
+		return &converged.APIError{Kind: converged.ErrNotFound, Cause: fmt.Errorf("%s", entityNotFoundError)}
+	}
+
+	switch ptr.Deref(spec.UnreserveType, networkingModels.UNRESERVETYPE_UNKNOWN) {
+	case networkingModels.UNRESERVETYPE_CONTEXT:
+		for ip, clientContext := range subnet.ReservedIPs {
+			if clientContext == ptr.Deref(spec.ClientContext, "") {
+				delete(subnet.ReservedIPs, ip)
+				subnet.FreeIPs = append(subnet.FreeIPs, ip)
+			}
+		}
+	case networkingModels.UNRESERVETYPE_IP_ADDRESS_LIST:
+		for _, address := range spec.IpAddresses {
+			ip := ptr.Deref(address.Ipv4.Value, "")
+			if _, ok := subnet.ReservedIPs[ip]; ok {
+				delete(subnet.ReservedIPs, ip)
+				subnet.FreeIPs = append(subnet.FreeIPs, ip)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported unreserve type %v", spec.UnreserveType)
+	}
+	return nil
+}
+
+func (mp *MockPrism) ListReservedSubnetIPs(ctx context.Context, subnetUUID string) ([]networkingModels.ReservedIp, error) {
+	subnet, ok := mp.mockEnvironment.managedMockSubnets[subnetUUID]
+	if !ok {
+		return nil, &converged.APIError{Kind: converged.ErrNotFound, Cause: fmt.Errorf("%s", entityNotFoundError)}
+	}
+
+	reservedIPs := make([]networkingModels.ReservedIp, 0, len(subnet.ReservedIPs))
+	for ip, clientContext := range subnet.ReservedIPs {
+		reservedIPs = append(reservedIPs, networkingModels.ReservedIp{
+			Ipv4Address:   ptr.To(ip),
+			ClientContext: ptr.To(clientContext),
+		})
+	}
+	return reservedIPs, nil
+}
+
+func (mp *MockPrism) GetSubnet(ctx context.Context, subnetUUID string) (*networkingModels.Subnet, error) {
+	if err := mp.recordCall("GetSubnet"); err != nil {
+		return nil, err
+	}
+	subnet, ok := mp.mockEnvironment.managedMockSubnets[subnetUUID]
+	if !ok {
+		return nil, &converged.APIError{Kind: converged.ErrNotFound, Cause: fmt.Errorf("%s", entityNotFoundError)}
+	}
+	s := &networkingModels.Subnet{
+		ExtId: ptr.To(subnetUUID),
+		Name:  ptr.To(subnet.Name),
+	}
+	if subnet.VPCUUID != "" {
+		s.VpcReference = ptr.To(subnet.VPCUUID)
+	}
+	return s, nil
+}
+
+func (mp *MockPrism) GetVPC(ctx context.Context, vpcUUID string) (*networkingModels.Vpc, error) {
+	if err := mp.recordCall("GetVPC"); err != nil {
+		return nil, err
+	}
+	if vpc, ok := mp.mockEnvironment.managedMockVPCs[vpcUUID]; ok {
+		return vpc, nil
+	}
+	return nil, &converged.APIError{Kind: converged.ErrNotFound, Cause: fmt.Errorf("%s", entityNotFoundError)}
+}
+
+func (mp *MockPrism) ListVMNics(ctx context.Context, vmUUID string) ([]vmmModels.Nic, error) {
+	if err := mp.recordCall("ListVMNics"); err != nil {
+		return nil, err
+	}
+	if vm, ok := mp.mockEnvironment.managedMockMachines[vmUUID]; ok {
+		return vm.Nics, nil
+	}
+	return nil, &converged.APIError{Kind: converged.ErrNotFound, Cause: fmt.Errorf("%s", vmNotFoundError)}
+}
+
+// ListFloatingIPs supports an empty filter and filters on the name
+func (mp *MockPrism) ListFloatingIPs(ctx context.Context, filter string) ([]networkingModels.FloatingIp, error) {
+	name, err := parseEqFilter(filter, "name")
+	if err != nil {
+		return nil, err
+	}
+
+	fips := make([]networkingModels.FloatingIp, 0)
+	for _, fip := range mp.mockEnvironment.managedMockFloatingIPs {
+		if name == "" || ptr.Deref(fip.Name, "") == name {
+			fips = append(fips, *fip)
+		}
+	}
+	return fips, nil
+}
+
+func (mp *MockPrism) CreateFloatingIP(ctx context.Context, floatingIP *networkingModels.FloatingIp) error {
+	subnetUUID := ptr.Deref(floatingIP.ExternalSubnetReference, "")
+	subnet, ok := mp.mockEnvironment.managedMockSubnets[subnetUUID]
+	if !ok {
+		return &converged.APIError{Kind: converged.ErrNotFound, Cause: fmt.Errorf("%s", entityNotFoundError)}
+	}
+	if len(subnet.FreeIPs) == 0 {
+		return fmt.Errorf("no free IPs in subnet %s", subnetUUID)
+	}
+	ip := subnet.FreeIPs[0]
+	subnet.FreeIPs = subnet.FreeIPs[1:]
+
+	fip := *floatingIP
+	fip.ExtId = ptr.To(string(uuid.NewUUID()))
+	fip.FloatingIp = networkingModels.NewFloatingIPAddress()
+	fip.FloatingIp.Ipv4 = networkingModels.NewFloatingIPv4Address()
+	fip.FloatingIp.Ipv4.Value = ptr.To(ip)
+	subnet.ReservedIPs[ip] = *fip.ExtId
+	mp.mockEnvironment.managedMockFloatingIPs[*fip.ExtId] = &fip
+	return nil
+}
+
+func (mp *MockPrism) UpdateFloatingIP(ctx context.Context, floatingIP *networkingModels.FloatingIp) error {
+	fipUUID := ptr.Deref(floatingIP.ExtId, "")
+	if _, ok := mp.mockEnvironment.managedMockFloatingIPs[fipUUID]; !ok {
+		return &converged.APIError{Kind: converged.ErrNotFound, Cause: fmt.Errorf("%s", entityNotFoundError)}
+	}
+	fip := *floatingIP
+	mp.mockEnvironment.managedMockFloatingIPs[fipUUID] = &fip
+	return nil
+}
+
+func (mp *MockPrism) DeleteFloatingIP(ctx context.Context, floatingIPUUID string) error {
+	fip, ok := mp.mockEnvironment.managedMockFloatingIPs[floatingIPUUID]
+	if !ok {
+		return &converged.APIError{Kind: converged.ErrNotFound, Cause: fmt.Errorf("%s", entityNotFoundError)}
+	}
+	if subnet, ok := mp.mockEnvironment.managedMockSubnets[ptr.Deref(fip.ExternalSubnetReference, "")]; ok {
+		ip := ptr.Deref(fip.FloatingIp.Ipv4.Value, "")
+		delete(subnet.ReservedIPs, ip)
+		subnet.FreeIPs = append(subnet.FreeIPs, ip)
+	}
+	delete(mp.mockEnvironment.managedMockFloatingIPs, floatingIPUUID)
+	return nil
+}
+
+// ListRouteTables supports an empty filter and filters on the VPC reference
+func (mp *MockPrism) ListRouteTables(ctx context.Context, filter string) ([]networkingModels.RouteTable, error) {
+	vpcUUID, err := parseEqFilter(filter, "vpcReference")
+	if err != nil {
+		return nil, err
+	}
+
+	routeTables := make([]networkingModels.RouteTable, 0)
+	for routeTableUUID, routeTable := range mp.mockEnvironment.managedMockRouteTables {
+		if vpcUUID == "" || routeTable.VPCUUID == vpcUUID {
+			routeTables = append(routeTables, networkingModels.RouteTable{
+				ExtId:        ptr.To(routeTableUUID),
+				VpcReference: ptr.To(routeTable.VPCUUID),
+			})
+		}
+	}
+	return routeTables, nil
+}
+
+// ListRoutes supports an empty filter and filters on the description
+func (mp *MockPrism) ListRoutes(ctx context.Context, routeTableUUID string, filter string) ([]networkingModels.Route, error) {
+	routeTable, ok := mp.mockEnvironment.managedMockRouteTables[routeTableUUID]
+	if !ok {
+		return nil, &converged.APIError{Kind: converged.ErrNotFound, Cause: fmt.Errorf("%s", entityNotFoundError)}
+	}
+	description, err := parseEqFilter(filter, "description")
+	if err != nil {
+		return nil, err
+	}
+
+	routes := make([]networkingModels.Route, 0)
+	for _, route := range routeTable.Routes {
+		if description == "" || ptr.Deref(route.Description, "") == description {
+			routes = append(routes, *route)
+		}
+	}
+	return routes, nil
+}
+
+func (mp *MockPrism) CreateRoute(ctx context.Context, routeTableUUID string, route *networkingModels.Route) error {
+	if err := mp.recordCall("CreateRoute"); err != nil {
+		return err
+	}
+	routeTable, ok := mp.mockEnvironment.managedMockRouteTables[routeTableUUID]
+	if !ok {
+		return &converged.APIError{Kind: converged.ErrNotFound, Cause: fmt.Errorf("%s", entityNotFoundError)}
+	}
+	r := *route
+	r.ExtId = ptr.To(string(uuid.NewUUID()))
+	r.RouteTableReference = ptr.To(routeTableUUID)
+	routeTable.Routes[*r.ExtId] = &r
+	return nil
+}
+
+func (mp *MockPrism) DeleteRoute(ctx context.Context, routeTableUUID string, routeUUID string) error {
+	routeTable, ok := mp.mockEnvironment.managedMockRouteTables[routeTableUUID]
+	if !ok {
+		return &converged.APIError{Kind: converged.ErrNotFound, Cause: fmt.Errorf("%s", entityNotFoundError)}
+	}
+	if _, ok := routeTable.Routes[routeUUID]; !ok {
+		return &converged.APIError{Kind: converged.ErrNotFound, Cause: fmt.Errorf("%s", entityNotFoundError)}
+	}
+	delete(routeTable.Routes, routeUUID)
+	return nil
+}
+
+// parseEqFilter returns the value of an OData filter of the form "<field> eq '<value>'",
+// or an empty value for an empty filter
+func parseEqFilter(filter string, field string) (string, error) {
+	if filter == "" {
+		return "", nil
+	}
+	value, ok := strings.CutPrefix(filter, field+" eq '")
+	if !ok || !strings.HasSuffix(value, "'") {
+		return "", fmt.Errorf("unsupported filter %q", filter)
+	}
+	return strings.TrimSuffix(value, "'"), nil
+}
@@ -46,11 +46,10 @@ func main() {
 	fss := cliflag.NamedFlagSets{}
 
 	controllerInitializers := app.DefaultInitFuncConstructors
-	delete(controllerInitializers, "service")
-	delete(controllerInitializers, "route")
 
 	command := app.NewCloudControllerManagerCommand(ccmOptions,
 		cloudInitializer, controllerInitializers, map[string]string{}, fss, wait.NeverStop)
+	command.AddCommand(newValidateConfigCommand())
 
 	code := cli.Run(command)
 	os.Exit(code)
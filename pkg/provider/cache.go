@@ -0,0 +1,255 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nutanix-cloud-native/prism-go-client/converged"
+	clusterModels "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/models/clustermgmt/v4/config"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
+	prismModels "github.com/nutanix/ntnx-api-golang-clients/prism-go-client/v4/models/prism/v4/config"
+	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/client-go/informers"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+type prismCacheKind string
+
+const (
+	vmCacheKind           = prismCacheKind("vm")
+	clusterCacheKind      = prismCacheKind("cluster")
+	clusterListCacheKind  = prismCacheKind("clusterList")
+	hostCacheKind         = prismCacheKind("host")
+	categoryCacheKind     = prismCacheKind("category")
+	categoryListCacheKind = prismCacheKind("categoryList")
+	subnetCacheKind       = prismCacheKind("subnet")
+	vpcCacheKind          = prismCacheKind("vpc")
+)
+
+type prismCacheKey struct {
+	kind prismCacheKind
+	uuid string
+}
+
+// notFoundEntry caches the error of a not found response.
+type notFoundEntry struct {
+	err error
+}
+
+// prismCache caches the responses of the Prism Central read APIs used on every node sync.
+// Entries expire after the TTL of their kind and the least recently used entries are evicted
+// once the cache is full.
+type prismCache struct {
+	entries *cache.LRUExpireCache
+	config  config.CacheConfig
+}
+
+func newPrismCache(cacheConfig config.CacheConfig, clock cache.Clock) *prismCache {
+	maxEntries := cacheConfig.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = config.DefaultCacheMaxEntries
+	}
+	return &prismCache{
+		entries: cache.NewLRUExpireCacheWithClock(maxEntries, clock),
+		config:  cacheConfig,
+	}
+}
+
+func (c *prismCache) ttl(kind prismCacheKind) time.Duration {
+	switch kind {
+	case vmCacheKind:
+		return c.config.VMTTL.Duration
+	case hostCacheKind:
+		return c.config.HostTTL.Duration
+	case categoryCacheKind, categoryListCacheKind:
+		return c.config.CategoryTTL.Duration
+	case subnetCacheKind, vpcCacheKind:
+		return c.config.NetworkTTL.Duration
+	default:
+		return c.config.ClusterTTL.Duration
+	}
+}
+
+// invalidate removes the cached response for the entity.
+func (c *prismCache) invalidate(kind prismCacheKind, uuid string) {
+	c.entries.Remove(prismCacheKey{kind: kind, uuid: uuid})
+}
+
+// getCached returns a deep copy of the cached entity, fetching and caching it on a miss, so that
+// callers may modify the returned entity. Not found errors are cached as well, so that missing
+// entities are not fetched on every call.
+func getCached[T any](c *prismCache, kind prismCacheKind, uuid string, fetch func() (*T, error)) (*T, error) {
+	key := prismCacheKey{kind: kind, uuid: uuid}
+	if entry, ok := c.entries.Get(key); ok {
+		switch entry := entry.(type) {
+		case *T:
+			return deepCopy(entry)
+		case notFoundEntry:
+			return nil, entry.err
+		}
+	}
+
+	value, err := fetch()
+	if err != nil {
+		if converged.IsNotFound(err) {
+			c.entries.Add(key, notFoundEntry{err: err}, c.config.NotFoundTTL.Duration)
+		}
+		return nil, err
+	}
+	if value == nil {
+		return nil, nil
+	}
+	c.entries.Add(key, value, c.ttl(kind))
+	return deepCopy(value)
+}
+
+// deepCopy copies the entity through its JSON encoding, since the models of the v4 APIs have
+// no copy methods and hold nested pointers, slices and one-of values.
+func deepCopy[T any](value *T) (*T, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy cached %T: %w", value, err)
+	}
+	out := new(T)
+	if err := json.Unmarshal(data, out); err != nil {
+		return nil, fmt.Errorf("failed to copy cached %T: %w", value, err)
+	}
+	return out, nil
+}
+
+// cachedClient wraps a client so that the Prism clients it returns share a prismCache.
+type cachedClient struct {
+	client interfaces.Client
+	cache  *prismCache
+}
+
+func newCachedClient(client interfaces.Client, cacheConfig config.CacheConfig) *cachedClient {
+	return &cachedClient{
+		client: client,
+		cache:  newPrismCache(cacheConfig, clock.RealClock{}),
+	}
+}
+
+func (c *cachedClient) Get() (interfaces.Prism, error) {
+	nClient, err := c.client.Get()
+	if err != nil {
+		return nil, err
+	}
+	return &cachedPrism{Prism: nClient, cache: c.cache}, nil
+}
+
+func (c *cachedClient) SetInformers(sharedInformers informers.SharedInformerFactory) {
+	c.client.SetInformers(sharedInformers)
+}
+
+func (c *cachedClient) Invalidate() {
+	c.client.Invalidate()
+}
+
+// invalidateVM drops the cached VM, e.g. when its node is added or deleted.
+func (c *cachedClient) invalidateVM(vmUUID string) {
+	klog.V(4).Infof("invalidating cached VM %s", vmUUID) //nolint:typecheck
+	c.cache.invalidate(vmCacheKind, vmUUID)
+}
+
+// cachedPrism serves the read APIs of the embedded Prism client from the cache. The other
+// APIs are not cached.
+type cachedPrism struct {
+	interfaces.Prism
+	cache *prismCache
+}
+
+func (p *cachedPrism) GetVM(ctx context.Context, vmUUID string) (*vmmModels.Vm, error) {
+	return getCached(p.cache, vmCacheKind, vmUUID, func() (*vmmModels.Vm, error) {
+		return p.Prism.GetVM(ctx, vmUUID)
+	})
+}
+
+func (p *cachedPrism) GetCluster(ctx context.Context, clusterUUID string) (*clusterModels.Cluster, error) {
+	return getCached(p.cache, clusterCacheKind, clusterUUID, func() (*clusterModels.Cluster, error) {
+		return p.Prism.GetCluster(ctx, clusterUUID)
+	})
+}
+
+// ListAllCluster caches the list of clusters and each of the listed clusters.
+func (p *cachedPrism) ListAllCluster(ctx context.Context) ([]clusterModels.Cluster, error) {
+	clusters, err := getCached(p.cache, clusterListCacheKind, "", func() (*[]clusterModels.Cluster, error) {
+		clusters, err := p.Prism.ListAllCluster(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for i := range clusters {
+			if clusters[i].ExtId != nil {
+				cluster := clusters[i]
+				p.cache.entries.Add(prismCacheKey{kind: clusterCacheKind, uuid: *cluster.ExtId}, &cluster, p.cache.ttl(clusterCacheKind))
+			}
+		}
+		return &clusters, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return *clusters, nil
+}
+
+func (p *cachedPrism) GetCategory(ctx context.Context, categoryUUID string) (*prismModels.Category, error) {
+	return getCached(p.cache, categoryCacheKind, categoryUUID, func() (*prismModels.Category, error) {
+		return p.Prism.GetCategory(ctx, categoryUUID)
+	})
+}
+
+// ListCategories caches the categories listed with each filter.
+func (p *cachedPrism) ListCategories(ctx context.Context, filter string) ([]prismModels.Category, error) {
+	categories, err := getCached(p.cache, categoryListCacheKind, filter, func() (*[]prismModels.Category, error) {
+		categories, err := p.Prism.ListCategories(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		return &categories, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return *categories, nil
+}
+
+func (p *cachedPrism) GetClusterHost(ctx context.Context, clusterUUID string, hostUUID string) (*clusterModels.Host, error) {
+	return getCached(p.cache, hostCacheKind, clusterUUID+"/"+hostUUID, func() (*clusterModels.Host, error) {
+		return p.Prism.GetClusterHost(ctx, clusterUUID, hostUUID)
+	})
+}
+
+func (p *cachedPrism) GetSubnet(ctx context.Context, subnetUUID string) (*networkingModels.Subnet, error) {
+	return getCached(p.cache, subnetCacheKind, subnetUUID, func() (*networkingModels.Subnet, error) {
+		return p.Prism.GetSubnet(ctx, subnetUUID)
+	})
+}
+
+func (p *cachedPrism) GetVPC(ctx context.Context, vpcUUID string) (*networkingModels.Vpc, error) {
+	return getCached(p.cache, vpcCacheKind, vpcUUID, func() (*networkingModels.Vpc, error) {
+		return p.Prism.GetVPC(ctx, vpcUUID)
+	})
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:typecheck // Test file uses ginkgo/gomega which typecheck doesn't understand well
+package provider
+
+import (
+	"context"
+	"time"
+
+	"github.com/nutanix-cloud-native/prism-go-client/converged"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+)
+
+var _ = Describe("Test Prism cache", func() { // nolint:typecheck
+	const missingUUID = "00000000-0000-0000-0000-999999999999"
+
+	var (
+		ctx             context.Context
+		kClient         *fake.Clientset
+		mockEnvironment *mock.MockEnvironment
+		mockClient      *mock.MockClient
+		fakeClock       *clocktesting.FakeClock
+		cacheConfig     config.CacheConfig
+		client          *cachedClient
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		kClient = fake.NewSimpleClientset()
+		var err error
+		mockEnvironment, err = mock.CreateMockEnvironment(ctx, kClient)
+		Expect(err).ToNot(HaveOccurred())
+		mockClient = mock.CreateMockClient(*mockEnvironment)
+		fakeClock = clocktesting.NewFakeClock(time.Now())
+
+		c, err := config.NewConfigFromBytes([]byte(`{"prismCentral": {"address": "pc.example.com"}}`))
+		Expect(err).ToNot(HaveOccurred())
+		cacheConfig = *c.Cache
+	})
+
+	JustBeforeEach(func() {
+		client = &cachedClient{
+			client: mockClient,
+			cache:  newPrismCache(cacheConfig, fakeClock),
+		}
+	})
+
+	getVM := func(vmName string) error {
+		nClient, err := client.Get()
+		Expect(err).ToNot(HaveOccurred())
+		vm := mockEnvironment.GetVM(ctx, vmName)
+		Expect(vm).ToNot(BeNil())
+		_, err = nClient.GetVM(ctx, *vm.ExtId)
+		return err
+	}
+
+	Context("Test GetVM", func() {
+		It("should serve the VM from the cache until its TTL expires", func() {
+			Expect(getVM(mock.MockVMNamePoweredOn)).To(Succeed())
+			Expect(getVM(mock.MockVMNamePoweredOn)).To(Succeed())
+			Expect(mockClient.Calls("GetVM")).To(Equal(1))
+
+			fakeClock.Step(cacheConfig.VMTTL.Duration + time.Second)
+			Expect(getVM(mock.MockVMNamePoweredOn)).To(Succeed())
+			Expect(mockClient.Calls("GetVM")).To(Equal(2))
+		})
+
+		It("should return a copy of the cached VM", func() {
+			nClient, err := client.Get()
+			Expect(err).ToNot(HaveOccurred())
+			vm, err := nClient.GetVM(ctx, mock.MockVMPoweredOnUUID)
+			Expect(err).ToNot(HaveOccurred())
+			vm.Nics = nil
+
+			vm, err = nClient.GetVM(ctx, mock.MockVMPoweredOnUUID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Nics).ToNot(BeEmpty())
+		})
+
+		It("should return a deep copy of the cached VM", func() {
+			nClient, err := client.Get()
+			Expect(err).ToNot(HaveOccurred())
+			vm, err := nClient.GetVM(ctx, mock.MockVMPoweredOnUUID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.Cluster.ExtId).ToNot(BeNil())
+			clusterUUID := *vm.Cluster.ExtId
+			*vm.Cluster.ExtId = missingUUID
+
+			vm, err = nClient.GetVM(ctx, mock.MockVMPoweredOnUUID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*vm.Cluster.ExtId).To(Equal(clusterUUID))
+			Expect(mockClient.Calls("GetVM")).To(Equal(1))
+		})
+
+		It("should cache not found responses for the not found TTL", func() {
+			nClient, err := client.Get()
+			Expect(err).ToNot(HaveOccurred())
+			for range 2 {
+				_, err = nClient.GetVM(ctx, missingUUID)
+				Expect(converged.IsNotFound(err)).To(BeTrue())
+			}
+			Expect(mockClient.Calls("GetVM")).To(Equal(1))
+
+			fakeClock.Step(cacheConfig.NotFoundTTL.Duration + time.Second)
+			_, err = nClient.GetVM(ctx, missingUUID)
+			Expect(converged.IsNotFound(err)).To(BeTrue())
+			Expect(mockClient.Calls("GetVM")).To(Equal(2))
+		})
+
+		It("should invalidate the VM", func() {
+			Expect(getVM(mock.MockVMNamePoweredOn)).To(Succeed())
+			client.invalidateVM(mock.MockVMPoweredOnUUID)
+			Expect(getVM(mock.MockVMNamePoweredOn)).To(Succeed())
+			Expect(mockClient.Calls("GetVM")).To(Equal(2))
+		})
+	})
+
+	Context("Test size bounds", func() {
+		BeforeEach(func() {
+			cacheConfig.MaxEntries = 1
+		})
+
+		It("should evict the least recently used entry", func() {
+			Expect(getVM(mock.MockVMNamePoweredOn)).To(Succeed())
+			Expect(getVM(mock.MockVMNamePoweredOff)).To(Succeed())
+			Expect(getVM(mock.MockVMNamePoweredOn)).To(Succeed())
+			Expect(mockClient.Calls("GetVM")).To(Equal(3))
+		})
+	})
+
+	Context("Test clusters", func() {
+		It("should cache the list of clusters and the listed clusters", func() {
+			nClient, err := client.Get()
+			Expect(err).ToNot(HaveOccurred())
+			clusters, err := nClient.ListAllCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(clusters).ToNot(BeEmpty())
+			_, err = nClient.ListAllCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+
+			cluster, err := nClient.GetCluster(ctx, mock.MockClusterUUID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*cluster.Name).To(Equal(mock.MockCluster))
+			Expect(mockClient.Calls("ListAllCluster")).To(Equal(1))
+			Expect(mockClient.Calls("GetCluster")).To(Equal(0))
+		})
+	})
+
+	Context("Test categories", func() {
+		BeforeEach(func() {
+			cacheConfig.CategoryTTL = metav1.Duration{Duration: time.Minute}
+		})
+
+		It("should use the TTL of categories", func() {
+			nClient, err := client.Get()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = nClient.GetCategory(ctx, mock.MockCategoryRegionUUID)
+			Expect(err).ToNot(HaveOccurred())
+
+			fakeClock.Step(30 * time.Second)
+			_, err = nClient.GetCategory(ctx, mock.MockCategoryRegionUUID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mockClient.Calls("GetCategory")).To(Equal(1))
+
+			fakeClock.Step(time.Minute)
+			_, err = nClient.GetCategory(ctx, mock.MockCategoryRegionUUID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mockClient.Calls("GetCategory")).To(Equal(2))
+		})
+	})
+
+	Context("Test node informer", func() {
+		It("should invalidate the VM of a deleted node", func() {
+			m := &nutanixManager{nutanixClient: client}
+			informerFactory := informers.NewSharedInformerFactory(kClient, 0)
+			m.setNodeInformer(informerFactory.Core().V1().Nodes())
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+			informerFactory.Start(stopCh)
+			informerFactory.WaitForCacheSync(stopCh)
+
+			Expect(getVM(mock.MockVMNamePoweredOn)).To(Succeed())
+			Expect(getVM(mock.MockVMNamePoweredOn)).To(Succeed())
+			Expect(mockClient.Calls("GetVM")).To(Equal(1))
+
+			Expect(kClient.CoreV1().Nodes().Delete(ctx, mock.MockVMNamePoweredOn, metav1.DeleteOptions{})).To(Succeed())
+			Eventually(func() int {
+				Expect(getVM(mock.MockVMNamePoweredOn)).To(Succeed())
+				return mockClient.Calls("GetVM")
+			}).Should(BeNumerically(">", 1))
+		})
+	})
+})
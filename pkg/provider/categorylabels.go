@@ -0,0 +1,145 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
+	v1 "k8s.io/api/core/v1"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/cloud-provider/node/helpers"
+	"k8s.io/klog/v2"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+// parseCategoryLabelKeys returns the label keys of the allowed category keys: the mapped label
+// key, or the prefix followed by the sanitized category key.
+func parseCategoryLabelKeys(categoryLabels *config.CategoryLabelsConfig) (map[string]string, error) {
+	if categoryLabels == nil {
+		return nil, nil
+	}
+	labelKeys := make(map[string]string, len(categoryLabels.AllowedKeys))
+	categoryKeys := make(map[string]string, len(categoryLabels.AllowedKeys))
+	for _, key := range categoryLabels.AllowedKeys {
+		labelKey, ok := categoryLabels.LabelKeys[key]
+		if !ok {
+			labelKey = categoryLabels.Prefix + SanitizeK8sLabelValue(key)
+		}
+		if errs := k8svalidation.IsQualifiedName(labelKey); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid label key %q for category key %s: %s", labelKey, key, strings.Join(errs, ", "))
+		}
+		if other, ok := categoryKeys[labelKey]; ok && other != key {
+			return nil, fmt.Errorf("category keys %s and %s are both synced onto label %s", other, key, labelKey)
+		}
+		labelKeys[key] = labelKey
+		categoryKeys[labelKey] = key
+	}
+	return labelKeys, nil
+}
+
+// getCategoryLabels returns the node labels of the allowed category keys assigned to the VM,
+// its AHV host or its cluster. A category key is read from the first source it is assigned by.
+func (n *nutanixManager) getCategoryLabels(ctx context.Context, nClient interfaces.Prism, vm *vmmModels.Vm) (map[string]string, error) {
+	nodeLabels := map[string]string{}
+	categoryLabels := n.config.CategoryLabels
+	if categoryLabels == nil || vm == nil {
+		return nodeLabels, nil
+	}
+
+	found := map[string]bool{}
+	for _, source := range categoryLabels.Sources {
+		categories, err := n.getCategoriesFromSource(ctx, nClient, vm, source, categoryLabels.AllowedKeys)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range categoryLabels.AllowedKeys {
+			values := categories[key]
+			if found[key] || len(values) == 0 {
+				continue
+			}
+			found[key] = true
+			if len(values) > 1 {
+				klog.Warningf("skipping label of category %s with multiple values %v from source %s of VM %s", key, values, source, *vm.ExtId) //nolint:typecheck
+				continue
+			}
+			if value := SanitizeK8sLabelValue(values[0]); value != "" {
+				nodeLabels[n.categoryLabelKeys[key]] = value
+			}
+		}
+	}
+	return nodeLabels, nil
+}
+
+// getCategoriesFromSource returns the values of the categories with one of the keys assigned to
+// the source entity of the VM, by category key.
+func (n *nutanixManager) getCategoriesFromSource(ctx context.Context, nClient interfaces.Prism, vm *vmmModels.Vm, source config.CategorySourceType, keys []string) (map[string][]string, error) {
+	switch source {
+	case config.VMCategorySourceType:
+		return n.getVMCategories(ctx, nClient, vm)
+	case config.HostCategorySourceType:
+		if vm.Host == nil || vm.Host.ExtId == nil {
+			return map[string][]string{}, nil
+		}
+		categories, err := n.getHostCategories(ctx, nClient, *vm.Host.ExtId, keys)
+		if err != nil {
+			return nil, err
+		}
+		return categoryValuesByKey(categories), nil
+	case config.ClusterCategorySourceType:
+		if vm.Cluster == nil || vm.Cluster.ExtId == nil {
+			return map[string][]string{}, nil
+		}
+		cluster, err := nClient.GetCluster(ctx, *vm.Cluster.ExtId)
+		if err != nil {
+			return nil, err
+		}
+		categories, err := getCategories(ctx, nClient, cluster.Categories)
+		if err != nil {
+			return nil, err
+		}
+		return categoryValuesByKey(categories), nil
+	}
+	return nil, fmt.Errorf("unsupported category source: %s", source)
+}
+
+// addCategoryLabelsToNode adds the category labels to the node when initializing it. The node
+// label controller keeps them in sync afterwards.
+func (n *nutanixManager) addCategoryLabelsToNode(ctx context.Context, nClient interfaces.Prism, vm *vmmModels.Vm, node *v1.Node) error {
+	nodeLabels, err := n.getCategoryLabels(ctx, nClient, vm)
+	if err != nil {
+		return err
+	}
+	changed := false
+	for key, value := range nodeLabels {
+		if node.Labels[key] != value {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if ok := helpers.AddOrUpdateLabelsOnNode(n.client, nodeLabels, node); !ok {
+		return fmt.Errorf("error occurred while updating category labels on node %s", node.Name)
+	}
+	return nil
+}
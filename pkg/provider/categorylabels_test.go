@@ -0,0 +1,204 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:typecheck // Test file uses ginkgo/gomega which typecheck doesn't understand well
+package provider
+
+import (
+	"context"
+	"encoding/json"
+
+	credentialTypes "github.com/nutanix-cloud-native/prism-go-client/environment/credentials"
+	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+var _ = Describe("Test Category Labels", func() { // nolint:typecheck
+	const prefix = "categories.nutanix.com/"
+
+	var (
+		ctx             context.Context
+		kClient         *fake.Clientset
+		mockEnvironment *mock.MockEnvironment
+		nClient         interfaces.Prism
+		categoryLabels  *config.CategoryLabelsConfig
+		m               *nutanixManager
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		kClient = fake.NewSimpleClientset()
+		var err error
+		mockEnvironment, err = mock.CreateMockEnvironment(ctx, kClient)
+		Expect(err).ToNot(HaveOccurred())
+		categoryLabels = &config.CategoryLabelsConfig{
+			AllowedKeys: []string{mock.MockDefaultRegion, mock.MockDefaultZone},
+			Prefix:      prefix,
+		}
+	})
+
+	JustBeforeEach(func() {
+		cBytes, err := json.Marshal(config.Config{
+			PrismCentral:   credentialTypes.NutanixPrismEndpoint{Address: "pc.example.com"},
+			CategoryLabels: categoryLabels,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		c, err := config.NewConfigFromBytes(cBytes)
+		Expect(err).ToNot(HaveOccurred())
+		m, err = newNutanixManager(c)
+		Expect(err).ToNot(HaveOccurred())
+		m.client = kClient
+		nutanixClient := mock.CreateMockClient(*mockEnvironment)
+		m.nutanixClient = nutanixClient
+		nClient, err = nutanixClient.Get()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should label nodes with the categories of the VM", func() {
+		vm := mockEnvironment.GetVM(ctx, mock.MockVMNameCategories)
+		nodeLabels, err := m.getCategoryLabels(ctx, nClient, vm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodeLabels).To(Equal(map[string]string{
+			prefix + mock.MockDefaultRegion: mock.MockRegion,
+			prefix + mock.MockDefaultZone:   mock.MockZone,
+		}))
+	})
+
+	It("should label nodes with the categories of the AHV host", func() {
+		vm := mockEnvironment.GetVM(ctx, mock.MockVMNameHostCategories)
+		nodeLabels, err := m.getCategoryLabels(ctx, nClient, vm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodeLabels).To(Equal(map[string]string{
+			prefix + mock.MockDefaultRegion: mock.MockRegion,
+			prefix + mock.MockDefaultZone:   mock.MockHostZone,
+		}))
+	})
+
+	It("should not label nodes without categories", func() {
+		vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+		nodeLabels, err := m.getCategoryLabels(ctx, nClient, vm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodeLabels).To(BeEmpty())
+	})
+
+	It("should skip categories with multiple values", func() {
+		vm := mockEnvironment.GetVM(ctx, mock.MockVMNameCategories)
+		vm.Categories = append(vm.Categories, vmmModels.CategoryReference{ExtId: ptr.To(mock.MockCategoryHostZoneUUID)})
+		nodeLabels, err := m.getCategoryLabels(ctx, nClient, vm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodeLabels).To(Equal(map[string]string{prefix + mock.MockDefaultRegion: mock.MockRegion}))
+	})
+
+	Context("Test sources", func() {
+		BeforeEach(func() {
+			categoryLabels.AllowedKeys = []string{mock.MockDefaultZone}
+		})
+
+		JustBeforeEach(func() {
+			cluster := mockEnvironment.GetCluster(ctx, mock.MockCluster)
+			cluster.Categories = []string{mock.MockCategoryHostZoneUUID}
+		})
+
+		It("should read categories from the VM first by default", func() {
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNameCategories)
+			nodeLabels, err := m.getCategoryLabels(ctx, nClient, vm)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nodeLabels).To(Equal(map[string]string{prefix + mock.MockDefaultZone: mock.MockZone}))
+		})
+
+		It("should read categories from the cluster", func() {
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+			nodeLabels, err := m.getCategoryLabels(ctx, nClient, vm)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nodeLabels).To(Equal(map[string]string{prefix + mock.MockDefaultZone: mock.MockHostZone}))
+		})
+
+		Context("Test configured sources", func() {
+			BeforeEach(func() {
+				categoryLabels.Sources = []config.CategorySourceType{config.ClusterCategorySourceType, config.VMCategorySourceType}
+			})
+
+			It("should read categories in the order of the sources", func() {
+				vm := mockEnvironment.GetVM(ctx, mock.MockVMNameCategories)
+				nodeLabels, err := m.getCategoryLabels(ctx, nClient, vm)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(nodeLabels).To(Equal(map[string]string{prefix + mock.MockDefaultZone: mock.MockHostZone}))
+			})
+		})
+	})
+
+	Context("Test label keys", func() {
+		BeforeEach(func() {
+			categoryLabels.LabelKeys = map[string]string{mock.MockDefaultZone: "example.com/zone"}
+		})
+
+		It("should use the mapped label keys", func() {
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNameCategories)
+			nodeLabels, err := m.getCategoryLabels(ctx, nClient, vm)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nodeLabels).To(Equal(map[string]string{
+				prefix + mock.MockDefaultRegion: mock.MockRegion,
+				"example.com/zone":              mock.MockZone,
+			}))
+			Expect(m.isOwnedNodeLabel("example.com/zone")).To(BeTrue())
+			Expect(m.isOwnedNodeLabel("example.com/region")).To(BeFalse())
+		})
+	})
+
+	It("should add the category labels when initializing nodes", func() {
+		node := mockEnvironment.GetNode(mock.MockVMNameCategories)
+		_, err := m.getInstanceMetadata(ctx, node)
+		Expect(err).ToNot(HaveOccurred())
+		updatedNode, err := kClient.CoreV1().Nodes().Get(ctx, node.Name, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(updatedNode.Labels).To(HaveKeyWithValue(prefix+mock.MockDefaultRegion, mock.MockRegion))
+		Expect(updatedNode.Labels).To(HaveKeyWithValue(prefix+mock.MockDefaultZone, mock.MockZone))
+	})
+
+	It("should remove the category labels that no longer apply and keep the other labels with the prefix", func() {
+		node, err := kClient.CoreV1().Nodes().Get(ctx, mock.MockVMNameCategories, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		node.Labels = map[string]string{
+			prefix + mock.MockDefaultRegion: mock.MockRegion,
+			prefix + mock.MockDefaultZone:   mock.MockZone,
+			prefix + "Environment":          "Production",
+			"example.com/zone":              mock.MockZone,
+		}
+		node, err = kClient.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		vm := mockEnvironment.GetVM(ctx, mock.MockVMNameCategories)
+		vm.Categories = vm.Categories[:1]
+		c := &nodeLabelController{manager: m}
+		Expect(c.reconcileNode(ctx, node)).To(Succeed())
+		updatedNode, err := kClient.CoreV1().Nodes().Get(ctx, node.Name, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(updatedNode.Labels).To(Equal(map[string]string{
+			prefix + mock.MockDefaultRegion: mock.MockRegion,
+			prefix + "Environment":          "Production",
+			"example.com/zone":              mock.MockZone,
+		}))
+		Expect(m.isOwnedNodeLabel(prefix + "Environment")).To(BeFalse())
+	})
+})
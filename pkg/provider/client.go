@@ -19,12 +19,16 @@ package provider
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/nutanix-cloud-native/prism-go-client/converged"
 	convergedV4 "github.com/nutanix-cloud-native/prism-go-client/converged/v4"
 	"github.com/nutanix-cloud-native/prism-go-client/environment"
 	credentialtypes "github.com/nutanix-cloud-native/prism-go-client/environment/credentials"
 	kubernetesenv "github.com/nutanix-cloud-native/prism-go-client/environment/providers/kubernetes"
 	envtypes "github.com/nutanix-cloud-native/prism-go-client/environment/types"
+	prismclientv4 "github.com/nutanix-cloud-native/prism-go-client/v4"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/tools/cache"
@@ -34,11 +38,24 @@ import (
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
 	clusterModels "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/models/clustermgmt/v4/config"
+	networkingApi "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/api"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
+	networkingPrismModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/prism/v4/config"
 	prismModels "github.com/nutanix/ntnx-api-golang-clients/prism-go-client/v4/models/prism/v4/config"
 	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
 )
 
-const errEnvironmentNotReady = "environment not initialized or ready yet"
+const (
+	errEnvironmentNotReady        = "environment not initialized or ready yet"
+	errFlowNetworkingNotAvailable = "flow networking APIs are not available"
+)
+
+const (
+	taskPollInterval = 2 * time.Second
+	taskPollTimeout  = 5 * time.Minute
+
+	networkingPageLimit = 100
+)
 
 type nutanixClientEnvironment struct {
 	env               envtypes.Environment
@@ -47,11 +64,19 @@ type nutanixClientEnvironment struct {
 	sharedInformers   informers.SharedInformerFactory
 	configMapInformer coreinformers.ConfigMapInformer
 	clientCache       *convergedV4.ClientCache
+	// v4ClientCache provides the Flow Virtual Networking APIs that the converged client does not expose
+	v4ClientCache *prismclientv4.ClientCache
+	// prismCentral is the name of the Prism Central of config.PrismCentrals the client connects
+	// to, or empty to connect to config.PrismCentral
+	prismCentral string
 }
 
-// Key returns the constant client name
+// Key returns the constant client name, suffixed with the name of the Prism Central if set
 // This implements the CachedClientParams interface of prism-go-client
 func (n *nutanixClientEnvironment) Key() string {
+	if n.prismCentral != "" {
+		return constants.ClientName + "-" + n.prismCentral
+	}
 	return constants.ClientName
 }
 
@@ -89,6 +114,16 @@ func (n *nutanixClientEnvironment) Get() (interfaces.Prism, error) {
 	client := &nutanixClient{
 		convergedClient: convergedClient,
 	}
+	if n.v4ClientCache != nil {
+		v4Client, err := n.v4ClientCache.GetOrCreate(n)
+		if err != nil {
+			return nil, err
+		}
+		client.floatingIPsApi = networkingApi.NewFloatingIpsApi(v4Client.SubnetsApiInstance.ApiClient)
+		client.vpcsApi = networkingApi.NewVpcsApi(v4Client.SubnetsApiInstance.ApiClient)
+		client.routeTablesApi = networkingApi.NewRouteTablesApi(v4Client.SubnetsApiInstance.ApiClient)
+		client.routesApi = networkingApi.NewRoutesApi(v4Client.SubnetsApiInstance.ApiClient)
+	}
 	return client, nil
 }
 
@@ -102,7 +137,20 @@ func (n *nutanixClientEnvironment) setupEnvironment() error {
 		return err
 	}
 
-	pc := n.config.PrismCentral
+	pc, err := n.getPrismCentralEndpoint()
+	if err != nil {
+		return err
+	}
+	setDefaultNamespaces(pc, ccmNamespace)
+
+	n.env = environment.NewEnvironment(kubernetesenv.NewProvider(pc, n.secretInformer, n.configMapInformer))
+
+	return nil
+}
+
+// setDefaultNamespaces sets the namespace of the credential and trust bundle references of the
+// Prism Central endpoint to the CCM namespace if not set.
+func setDefaultNamespaces(pc credentialtypes.NutanixPrismEndpoint, ccmNamespace string) {
 	if pc.CredentialRef != nil {
 		if pc.CredentialRef.Namespace == "" {
 			pc.CredentialRef.Namespace = ccmNamespace
@@ -115,10 +163,19 @@ func (n *nutanixClientEnvironment) setupEnvironment() error {
 		additionalTrustBundleRef.Namespace == "" {
 		additionalTrustBundleRef.Namespace = ccmNamespace
 	}
+}
 
-	n.env = environment.NewEnvironment(kubernetesenv.NewProvider(pc, n.secretInformer, n.configMapInformer))
-
-	return nil
+// getPrismCentralEndpoint returns the endpoint of the Prism Central the client connects to.
+func (n *nutanixClientEnvironment) getPrismCentralEndpoint() (credentialtypes.NutanixPrismEndpoint, error) {
+	if n.prismCentral == "" {
+		return n.config.PrismCentral, nil
+	}
+	for _, pc := range n.config.PrismCentrals {
+		if pc.Name == n.prismCentral {
+			return pc.NutanixPrismEndpoint, nil
+		}
+	}
+	return credentialtypes.NutanixPrismEndpoint{}, fmt.Errorf("prism central %s is not configured", n.prismCentral)
 }
 
 func (n *nutanixClientEnvironment) SetInformers(sharedInformers informers.SharedInformerFactory) {
@@ -129,6 +186,17 @@ func (n *nutanixClientEnvironment) SetInformers(sharedInformers informers.Shared
 	n.syncCache(n.configMapInformer.Informer())
 }
 
+// Invalidate drops the cached clients, e.g. when the credentials or the trust bundle are
+// rotated.
+func (n *nutanixClientEnvironment) Invalidate() {
+	if n.clientCache != nil {
+		n.clientCache.Delete(n)
+	}
+	if n.v4ClientCache != nil {
+		n.v4ClientCache.Delete(n)
+	}
+}
+
 func (n *nutanixClientEnvironment) syncCache(informer cache.SharedInformer) {
 	hasSynced := informer.HasSynced
 	if !hasSynced() {
@@ -142,24 +210,291 @@ func (n *nutanixClientEnvironment) syncCache(informer cache.SharedInformer) {
 
 type nutanixClient struct {
 	convergedClient *convergedV4.Client
+	floatingIPsApi  *networkingApi.FloatingIpsApi
+	vpcsApi         *networkingApi.VpcsApi
+	routeTablesApi  *networkingApi.RouteTablesApi
+	routesApi       *networkingApi.RoutesApi
 }
 
-func (client *nutanixClient) GetVM(ctx context.Context, vmUUID string) (*vmmModels.Vm, error) {
+func (client *nutanixClient) GetVM(ctx context.Context, vmUUID string) (_ *vmmModels.Vm, err error) {
+	defer observePrismAPIRequest("GetVM", time.Now(), &err)
 	return client.convergedClient.VMs.Get(ctx, vmUUID)
 }
 
-func (client *nutanixClient) GetCluster(ctx context.Context, clusterUUID string) (*clusterModels.Cluster, error) {
+func (client *nutanixClient) ListVMs(ctx context.Context, filter string) (_ []vmmModels.Vm, err error) {
+	defer observePrismAPIRequest("ListVMs", time.Now(), &err)
+	return client.convergedClient.VMs.List(ctx, converged.WithFilter(filter))
+}
+
+func (client *nutanixClient) GetCluster(ctx context.Context, clusterUUID string) (_ *clusterModels.Cluster, err error) {
+	defer observePrismAPIRequest("GetCluster", time.Now(), &err)
 	return client.convergedClient.Clusters.Get(ctx, clusterUUID)
 }
 
-func (client *nutanixClient) ListAllCluster(ctx context.Context) ([]clusterModels.Cluster, error) {
+func (client *nutanixClient) ListAllCluster(ctx context.Context) (_ []clusterModels.Cluster, err error) {
+	defer observePrismAPIRequest("ListAllCluster", time.Now(), &err)
 	return client.convergedClient.Clusters.List(ctx)
 }
 
-func (client *nutanixClient) GetCategory(ctx context.Context, categoryUUID string) (*prismModels.Category, error) {
+func (client *nutanixClient) GetCategory(ctx context.Context, categoryUUID string) (_ *prismModels.Category, err error) {
+	defer observePrismAPIRequest("GetCategory", time.Now(), &err)
 	return client.convergedClient.Categories.Get(ctx, categoryUUID)
 }
 
-func (client *nutanixClient) GetClusterHost(ctx context.Context, clusterUuid string, hostUUID string) (*clusterModels.Host, error) {
+func (client *nutanixClient) ListCategories(ctx context.Context, filter string) (_ []prismModels.Category, err error) {
+	defer observePrismAPIRequest("ListCategories", time.Now(), &err)
+	return client.convergedClient.Categories.List(ctx, converged.WithFilter(filter), converged.WithExpand("detailedAssociations"))
+}
+
+func (client *nutanixClient) GetClusterHost(ctx context.Context, clusterUuid string, hostUUID string) (_ *clusterModels.Host, err error) {
+	defer observePrismAPIRequest("GetClusterHost", time.Now(), &err)
 	return client.convergedClient.Clusters.GetClusterHost(ctx, clusterUuid, hostUUID)
 }
+
+func (client *nutanixClient) ReserveSubnetIPs(ctx context.Context, subnetUUID string, spec *networkingModels.IpReserveSpec) (err error) {
+	defer observePrismAPIRequest("ReserveSubnetIPs", time.Now(), &err)
+	taskRef, err := client.convergedClient.Subnets.ReserveIpsBySubnetId(ctx, subnetUUID, spec)
+	if err != nil {
+		return err
+	}
+	return client.waitForTask(ctx, taskRef)
+}
+
+func (client *nutanixClient) UnreserveSubnetIPs(ctx context.Context, subnetUUID string, spec *networkingModels.IpUnreserveSpec) (err error) {
+	defer observePrismAPIRequest("UnreserveSubnetIPs", time.Now(), &err)
+	taskRef, err := client.convergedClient.Subnets.UnreserveIpsBySubnetId(ctx, subnetUUID, spec)
+	if err != nil {
+		return err
+	}
+	return client.waitForTask(ctx, taskRef)
+}
+
+func (client *nutanixClient) ListReservedSubnetIPs(ctx context.Context, subnetUUID string) (_ []networkingModels.ReservedIp, err error) {
+	defer observePrismAPIRequest("ListReservedSubnetIPs", time.Now(), &err)
+	resp, err := client.convergedClient.Subnets.ListReservedIpsBySubnetId(ctx, subnetUUID)
+	if err != nil {
+		return nil, err
+	}
+	listResp, ok := resp.(*networkingModels.ListSubnetReservedIpsApiResponse)
+	if !ok {
+		return nil, fmt.Errorf("unexpected response type %T when listing reserved IPs of subnet %s", resp, subnetUUID)
+	}
+	switch data := listResp.GetData().(type) {
+	case nil:
+		return []networkingModels.ReservedIp{}, nil
+	case []networkingModels.ReservedIp:
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unexpected data type %T when listing reserved IPs of subnet %s", data, subnetUUID)
+	}
+}
+
+func (client *nutanixClient) GetSubnet(ctx context.Context, subnetUUID string) (_ *networkingModels.Subnet, err error) {
+	defer observePrismAPIRequest("GetSubnet", time.Now(), &err)
+	return client.convergedClient.Subnets.Get(ctx, subnetUUID)
+}
+
+func (client *nutanixClient) GetVPC(ctx context.Context, vpcUUID string) (_ *networkingModels.Vpc, err error) {
+	defer observePrismAPIRequest("GetVPC", time.Now(), &err)
+	if client.vpcsApi == nil {
+		return nil, fmt.Errorf("%s: VPCs API not initialized", errFlowNetworkingNotAvailable)
+	}
+	resp, err := client.vpcsApi.GetVpcById(&vpcUUID)
+	if err != nil {
+		return nil, convergedV4.CategoriseFromOpenAPI(err)
+	}
+	if resp == nil {
+		return nil, fmt.Errorf("empty response when getting VPC %s", vpcUUID)
+	}
+	vpc, ok := resp.GetData().(networkingModels.Vpc)
+	if !ok {
+		return nil, fmt.Errorf("unexpected data type %T when getting VPC %s", resp.GetData(), vpcUUID)
+	}
+	return &vpc, nil
+}
+
+func (client *nutanixClient) ListVMNics(ctx context.Context, vmUUID string) (_ []vmmModels.Nic, err error) {
+	defer observePrismAPIRequest("ListVMNics", time.Now(), &err)
+	return client.convergedClient.ListNicsByVmId(ctx, vmUUID)
+}
+
+func (client *nutanixClient) ListFloatingIPs(ctx context.Context, filter string) (_ []networkingModels.FloatingIp, err error) {
+	defer observePrismAPIRequest("ListFloatingIPs", time.Now(), &err)
+	if client.floatingIPsApi == nil {
+		return nil, fmt.Errorf("%s: floating IPs API not initialized", errFlowNetworkingNotAvailable)
+	}
+	return listAllPages[networkingModels.FloatingIp]("floating IPs", func(page, limit int) (interface{}, error) {
+		resp, err := client.floatingIPsApi.ListFloatingIps(&page, &limit, &filter, nil, nil)
+		if err != nil || resp == nil {
+			return nil, err
+		}
+		return resp.GetData(), nil
+	})
+}
+
+func (client *nutanixClient) CreateFloatingIP(ctx context.Context, floatingIP *networkingModels.FloatingIp) (err error) {
+	defer observePrismAPIRequest("CreateFloatingIP", time.Now(), &err)
+	if client.floatingIPsApi == nil {
+		return fmt.Errorf("%s: floating IPs API not initialized", errFlowNetworkingNotAvailable)
+	}
+	resp, err := client.floatingIPsApi.CreateFloatingIp(floatingIP)
+	if err != nil {
+		return convergedV4.CategoriseFromOpenAPI(err)
+	}
+	return client.waitForTaskResponse(ctx, resp)
+}
+
+func (client *nutanixClient) UpdateFloatingIP(ctx context.Context, floatingIP *networkingModels.FloatingIp) (err error) {
+	defer observePrismAPIRequest("UpdateFloatingIP", time.Now(), &err)
+	if client.floatingIPsApi == nil {
+		return fmt.Errorf("%s: floating IPs API not initialized", errFlowNetworkingNotAvailable)
+	}
+	if floatingIP == nil || floatingIP.ExtId == nil {
+		return fmt.Errorf("floating IP to update must have an ExtId")
+	}
+	// Updates are guarded by the ETag of the current floating IP
+	current, err := client.floatingIPsApi.GetFloatingIpById(floatingIP.ExtId)
+	if err != nil {
+		return convergedV4.CategoriseFromOpenAPI(err)
+	}
+	if current == nil {
+		return fmt.Errorf("empty response when getting floating IP %s", *floatingIP.ExtId)
+	}
+	etag := convergedV4.GetEtag(current.GetData())
+	if etag == "" {
+		return fmt.Errorf("no ETag found for floating IP %s", *floatingIP.ExtId)
+	}
+	resp, err := client.floatingIPsApi.UpdateFloatingIpById(floatingIP.ExtId, floatingIP, map[string]interface{}{"If-Match": &etag})
+	if err != nil {
+		return convergedV4.CategoriseFromOpenAPI(err)
+	}
+	return client.waitForTaskResponse(ctx, resp)
+}
+
+func (client *nutanixClient) DeleteFloatingIP(ctx context.Context, floatingIPUUID string) (err error) {
+	defer observePrismAPIRequest("DeleteFloatingIP", time.Now(), &err)
+	if client.floatingIPsApi == nil {
+		return fmt.Errorf("%s: floating IPs API not initialized", errFlowNetworkingNotAvailable)
+	}
+	resp, err := client.floatingIPsApi.DeleteFloatingIpById(&floatingIPUUID)
+	if err != nil {
+		return convergedV4.CategoriseFromOpenAPI(err)
+	}
+	return client.waitForTaskResponse(ctx, resp)
+}
+
+func (client *nutanixClient) ListRouteTables(ctx context.Context, filter string) (_ []networkingModels.RouteTable, err error) {
+	defer observePrismAPIRequest("ListRouteTables", time.Now(), &err)
+	if client.routeTablesApi == nil {
+		return nil, fmt.Errorf("%s: route tables API not initialized", errFlowNetworkingNotAvailable)
+	}
+	return listAllPages[networkingModels.RouteTable]("route tables", func(page, limit int) (interface{}, error) {
+		resp, err := client.routeTablesApi.ListRouteTables(&page, &limit, &filter, nil)
+		if err != nil || resp == nil {
+			return nil, err
+		}
+		return resp.GetData(), nil
+	})
+}
+
+func (client *nutanixClient) ListRoutes(ctx context.Context, routeTableUUID string, filter string) (_ []networkingModels.Route, err error) {
+	defer observePrismAPIRequest("ListRoutes", time.Now(), &err)
+	if client.routesApi == nil {
+		return nil, fmt.Errorf("%s: routes API not initialized", errFlowNetworkingNotAvailable)
+	}
+	return listAllPages[networkingModels.Route]("routes", func(page, limit int) (interface{}, error) {
+		resp, err := client.routesApi.ListRoutesByRouteTableId(&routeTableUUID, &page, &limit, &filter, nil)
+		if err != nil || resp == nil {
+			return nil, err
+		}
+		return resp.GetData(), nil
+	})
+}
+
+func (client *nutanixClient) CreateRoute(ctx context.Context, routeTableUUID string, route *networkingModels.Route) (err error) {
+	defer observePrismAPIRequest("CreateRoute", time.Now(), &err)
+	if client.routesApi == nil {
+		return fmt.Errorf("%s: routes API not initialized", errFlowNetworkingNotAvailable)
+	}
+	resp, err := client.routesApi.CreateRouteForRouteTable(&routeTableUUID, route)
+	if err != nil {
+		return convergedV4.CategoriseFromOpenAPI(err)
+	}
+	return client.waitForTaskResponse(ctx, resp)
+}
+
+func (client *nutanixClient) DeleteRoute(ctx context.Context, routeTableUUID string, routeUUID string) (err error) {
+	defer observePrismAPIRequest("DeleteRoute", time.Now(), &err)
+	if client.routesApi == nil {
+		return fmt.Errorf("%s: routes API not initialized", errFlowNetworkingNotAvailable)
+	}
+	resp, err := client.routesApi.DeleteRouteForRouteTableById(&routeUUID, &routeTableUUID)
+	if err != nil {
+		return convergedV4.CategoriseFromOpenAPI(err)
+	}
+	return client.waitForTaskResponse(ctx, resp)
+}
+
+// listAllPages calls list for each page until a page is not full and returns the concatenated entities.
+func listAllPages[T any](kind string, list func(page, limit int) (interface{}, error)) ([]T, error) {
+	entities := make([]T, 0)
+	for page := 0; ; page++ {
+		data, err := list(page, networkingPageLimit)
+		if err != nil {
+			return nil, convergedV4.CategoriseFromOpenAPI(err)
+		}
+		switch data := data.(type) {
+		case nil:
+			return entities, nil
+		case []T:
+			entities = append(entities, data...)
+			if len(data) < networkingPageLimit {
+				return entities, nil
+			}
+		default:
+			return nil, fmt.Errorf("unexpected data type %T when listing %s", data, kind)
+		}
+	}
+}
+
+func (client *nutanixClient) waitForTaskResponse(ctx context.Context, resp *networkingModels.TaskReferenceApiResponse) error {
+	if resp == nil {
+		return fmt.Errorf("task response cannot be empty")
+	}
+	taskRef, ok := resp.GetData().(networkingPrismModels.TaskReference)
+	if !ok {
+		return fmt.Errorf("unexpected data type %T in task response", resp.GetData())
+	}
+	return client.waitForTask(ctx, &taskRef)
+}
+
+// waitForTask polls the Prism Central task until it succeeds, fails or times out.
+func (client *nutanixClient) waitForTask(ctx context.Context, taskRef *networkingPrismModels.TaskReference) error {
+	if taskRef == nil || taskRef.ExtId == nil {
+		return fmt.Errorf("task reference cannot be empty")
+	}
+	taskUUID := *taskRef.ExtId
+
+	return wait.PollUntilContextTimeout(ctx, taskPollInterval, taskPollTimeout, true, func(ctx context.Context) (bool, error) {
+		task, err := client.convergedClient.Tasks.Get(ctx, taskUUID)
+		if err != nil {
+			return false, err
+		}
+		if task.Status == nil {
+			return false, nil
+		}
+		switch *task.Status {
+		case prismModels.TASKSTATUS_SUCCEEDED:
+			return true, nil
+		case prismModels.TASKSTATUS_FAILED, prismModels.TASKSTATUS_CANCELED:
+			messages := make([]string, 0, len(task.ErrorMessages))
+			for _, msg := range task.ErrorMessages {
+				if msg.Message != nil {
+					messages = append(messages, *msg.Message)
+				}
+			}
+			return false, fmt.Errorf("task %s did not succeed (%s): %v", taskUUID, task.Status.GetName(), messages)
+		}
+		return false, nil
+	})
+}
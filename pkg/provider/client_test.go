@@ -89,6 +89,11 @@ var _ = Describe("Test Client", func() { // nolint:typecheck
 		It("should return the client name", func() { //nolint:typecheck
 			Expect(nClient.Key()).To(Equal(constants.ClientName)) //nolint:typecheck
 		})
+
+		It("should suffix the client name with the Prism Central name", func() { //nolint:typecheck
+			nClient.prismCentral = "pc1"
+			Expect(nClient.Key()).To(Equal(constants.ClientName + "-pc1")) //nolint:typecheck
+		})
 	})
 
 	Context("Test ManagementEndpoint", func() { //nolint:typecheck
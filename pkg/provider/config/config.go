@@ -17,19 +17,394 @@ limitations under the License.
 package config
 
 import (
-	"encoding/json"
-	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	credentialTypes "github.com/nutanix-cloud-native/prism-go-client/environment/credentials"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	klog "k8s.io/klog/v2"
 )
 
 // Config of Nutanix provider
 type Config struct {
+	metav1.TypeMeta `json:",inline"`
+
 	PrismCentral         credentialTypes.NutanixPrismEndpoint `json:"prismCentral"`
 	TopologyDiscovery    TopologyDiscovery                    `json:"topologyDiscovery"`
 	EnableCustomLabeling bool                                 `json:"enableCustomLabeling"`
 	IgnoredNodeIPs       []string                             `json:"ignoredNodeIPs,omitempty"`
+	AddressFamily        AddressFamilyType                    `json:"addressFamily,omitempty"`
+	NodeAddressRules     []NodeAddressRule                    `json:"nodeAddressRules,omitempty"`
+	LoadBalancer         *LoadBalancerConfig                  `json:"loadBalancer,omitempty"`
+	Routes               *RoutesConfig                        `json:"routes,omitempty"`
+	Cache                *CacheConfig                         `json:"cache,omitempty"`
+	Retry                *RetryConfig                         `json:"retry,omitempty"`
+	CircuitBreaker       *CircuitBreakerConfig                `json:"circuitBreaker,omitempty"`
+	RateLimit            *RateLimitConfig                     `json:"rateLimit,omitempty"`
+	NodeLabelSync        *NodeLabelSyncConfig                 `json:"nodeLabelSync,omitempty"`
+	CategoryLabels       *CategoryLabelsConfig                `json:"categoryLabels,omitempty"`
+	// TaintRules taint the nodes whose VM matches the rule. The taints are removed when the VM
+	// no longer matches.
+	TaintRules []TaintRule `json:"taintRules,omitempty"`
+	// HostMaintenance taints the nodes whose VM runs on an AHV host in maintenance
+	HostMaintenance *HostMaintenanceConfig `json:"hostMaintenance,omitempty"`
+	// InstanceTypes is a catalog of named instance types evaluated in order; the first one
+	// matching the VM is reported for the node
+	InstanceTypes []InstanceTypeConfig `json:"instanceTypes,omitempty"`
+	// InstanceTypeNames maps instance types derived from the VM resources, such as
+	// ahv-8c-32g, to the instance type reported for the node instead
+	InstanceTypeNames map[string]string `json:"instanceTypeNames,omitempty"`
+	// ShutdownPowerStates lists the VM power states reported as shutdown, for which the node
+	// lifecycle controller taints the nodes: OFF, PAUSED or UNDETERMINED. Defaults to OFF and
+	// PAUSED.
+	ShutdownPowerStates []string `json:"shutdownPowerStates,omitempty"`
+	// NodeDiscovery configures how the VM of a node is found
+	NodeDiscovery *NodeDiscoveryConfig `json:"nodeDiscovery,omitempty"`
+	// PrismCentrals lists the Prism Centrals the nodes are resolved against instead of
+	// PrismCentral, e.g. for nodes on AHV clusters managed by different Prism Centrals. The
+	// first one serves the load balancer, IPAM and route APIs.
+	PrismCentrals []PrismCentralConfig `json:"prismCentrals,omitempty"`
+}
+
+// PrismCentralConfig is a named Prism Central endpoint, with its own credentialRef and
+// additionalTrustBundle.
+type PrismCentralConfig struct {
+	// Name identifies the Prism Central in the annotation of the nodes it manages
+	Name string `json:"name"`
+	credentialTypes.NutanixPrismEndpoint
+}
+
+// IsSecretCredentialKind returns whether the kind of a credentialRef is Secret. The kind is
+// matched case-insensitively since the manifests set it in lowercase, and Prism Go Client does
+// not check it.
+func IsSecretCredentialKind(kind credentialTypes.NutanixCredentialKind) bool {
+	return strings.EqualFold(string(kind), string(credentialTypes.SecretKind))
+}
+
+// DefaultShutdownPowerStates are the VM power states reported as shutdown by default.
+var DefaultShutdownPowerStates = []string{"OFF", "PAUSED"}
+
+// AddressFamilyType selects which IP families are reported as node addresses, and in which order.
+type AddressFamilyType string
+
+const (
+	// IPv4FirstAddressFamilyType reports IPv4 addresses before IPv6 addresses (default)
+	IPv4FirstAddressFamilyType = AddressFamilyType("IPv4First")
+	// IPv6FirstAddressFamilyType reports IPv6 addresses before IPv4 addresses
+	IPv6FirstAddressFamilyType = AddressFamilyType("IPv6First")
+	// IPv4OnlyAddressFamilyType only reports IPv4 addresses
+	IPv4OnlyAddressFamilyType = AddressFamilyType("IPv4Only")
+	// IPv6OnlyAddressFamilyType only reports IPv6 addresses
+	IPv6OnlyAddressFamilyType = AddressFamilyType("IPv6Only")
+)
+
+// NodeAddressRule assigns a type to the node addresses it selects. Rules are evaluated in
+// order and the first matching rule wins; addresses matching no rule are reported as InternalIP.
+// A rule selects the addresses matching all of its set selectors.
+type NodeAddressRule struct {
+	// SubnetName selects the addresses of the NICs attached to the subnet with this name
+	SubnetName string `json:"subnetName,omitempty"`
+	// SubnetUUID selects the addresses of the NICs attached to the subnet with this UUID
+	SubnetUUID string `json:"subnetUUID,omitempty"`
+	// CIDRs selects the addresses within any of these IP addresses, CIDR prefixes or IP ranges
+	CIDRs []string `json:"cidrs,omitempty"`
+	// Type is the type assigned to the selected addresses
+	Type NodeAddressType `json:"type"`
+}
+
+type NodeAddressType string
+
+const (
+	// InternalIPNodeAddressType reports the address as node InternalIP
+	InternalIPNodeAddressType = NodeAddressType("InternalIP")
+	// ExternalIPNodeAddressType reports the address as node ExternalIP
+	ExternalIPNodeAddressType = NodeAddressType("ExternalIP")
+	// ExcludedNodeAddressType does not report the address
+	ExcludedNodeAddressType = NodeAddressType("Excluded")
+)
+
+// InstanceTypeConfig names the instance type of the VMs matching all of its set rules. An
+// instance type without rules matches all VMs.
+type InstanceTypeConfig struct {
+	// Name is the instance type reported for the matching VMs
+	Name string `json:"name"`
+	// MinVCPUs and MaxVCPUs bound the vCPUs of the VM, i.e. the sockets times the cores per
+	// socket times the threads per core
+	MinVCPUs int `json:"minVCPUs,omitempty"`
+	MaxVCPUs int `json:"maxVCPUs,omitempty"`
+	// MinMemory and MaxMemory bound the memory of the VM, e.g. 32Gi
+	MinMemory *resource.Quantity `json:"minMemory,omitempty"`
+	MaxMemory *resource.Quantity `json:"maxMemory,omitempty"`
+	// GPU selects the VMs with GPUs attached if true, and the VMs without GPUs if false
+	GPU *bool `json:"gpu,omitempty"`
+	// Categories selects the VMs assigned all of these category keys and values
+	Categories map[string]string `json:"categories,omitempty"`
+}
+
+// TaintRule taints the nodes whose VM matches all of its selectors. Rules are evaluated in
+// order; the first matching rule wins for a taint key and effect.
+type TaintRule struct {
+	// Categories selects the VMs assigned all of these category keys and values
+	Categories map[string]string `json:"categories,omitempty"`
+	// CustomAttributes selects the VMs with all of these key:value custom attributes. An empty
+	// value selects the VMs with the custom attribute set to any value.
+	CustomAttributes map[string]string `json:"customAttributes,omitempty"`
+	// Key, Value and Effect form the taint of the nodes of the selected VMs, e.g.
+	// nvidia.com/gpu=true:NoSchedule. The taints with the key and effect of a rule are owned
+	// by the CCM.
+	Key    string         `json:"key"`
+	Value  string         `json:"value,omitempty"`
+	Effect v1.TaintEffect `json:"effect"`
+}
+
+// LoadBalancerConfig enables Services of type LoadBalancer. Each Service is assigned a
+// virtual IP; announcing the VIP on the network is left to an in-cluster component
+// such as kube-vip.
+type LoadBalancerConfig struct {
+	// IPAM selects where VIPs are allocated from. Defaults to Pool.
+	IPAM LoadBalancerIPAMType `json:"ipam,omitempty"`
+	// IPPools lists the IP addresses, CIDR prefixes and IP ranges VIPs are allocated from
+	// when using the Pool IPAM
+	IPPools []string `json:"ipPools,omitempty"`
+	// SubnetUUID is the Nutanix managed subnet VIPs are reserved in when using the Prism IPAM
+	SubnetUUID string `json:"subnetUUID,omitempty"`
+	// FloatingIP exposes Services through Flow Virtual Networking floating IPs when the
+	// nodes are attached to a VPC
+	FloatingIP *FloatingIPConfig `json:"floatingIP,omitempty"`
+}
+
+// FloatingIPConfig allocates a floating IP from an external subnet for each Service and
+// reports it as the external ingress IP of the Service.
+type FloatingIPConfig struct {
+	// ExternalSubnetUUID is the external subnet floating IPs are allocated from
+	ExternalSubnetUUID string `json:"externalSubnetUUID"`
+	// VPCUUID is the VPC the node VMs are attached to
+	VPCUUID string `json:"vpcUUID"`
+	// Association selects what the floating IP is associated with. Defaults to VIP.
+	Association FloatingIPAssociationType `json:"association,omitempty"`
+}
+
+type FloatingIPAssociationType string
+
+const (
+	// VIPFloatingIPAssociationType associates the floating IP with the VIP allocated for the Service
+	VIPFloatingIPAssociationType = FloatingIPAssociationType("VIP")
+	// NodeNICFloatingIPAssociationType associates the floating IP with the VPC NIC of a node;
+	// the Service is then reachable on its node ports
+	NodeNICFloatingIPAssociationType = FloatingIPAssociationType("NodeNIC")
+)
+
+type LoadBalancerIPAMType string
+
+const (
+	// PoolLoadBalancerIPAMType allocates VIPs from loadBalancer.ipPools and records them in a ConfigMap
+	PoolLoadBalancerIPAMType = LoadBalancerIPAMType("Pool")
+	// PrismLoadBalancerIPAMType reserves VIPs in the IPAM of a Nutanix managed subnet
+	PrismLoadBalancerIPAMType = LoadBalancerIPAMType("Prism")
+)
+
+// RoutesConfig enables the route controller. Pod CIDR routes are programmed into the route
+// table of a Flow VPC with the VPC NIC of the node VM as next hop.
+type RoutesConfig struct {
+	// VPCUUID is the VPC the node VMs are attached to
+	VPCUUID string `json:"vpcUUID"`
+}
+
+// CacheConfig configures the cache of Prism Central responses. Entries expire after the TTL of
+// their entity kind; not found responses are cached for NotFoundTTL.
+type CacheConfig struct {
+	// Disabled turns off caching of Prism Central responses
+	Disabled bool `json:"disabled,omitempty"`
+	// MaxEntries bounds the number of cached responses. Defaults to 4096.
+	MaxEntries int `json:"maxEntries,omitempty"`
+	// VMTTL defaults to 10s
+	VMTTL metav1.Duration `json:"vmTTL,omitempty"`
+	// ClusterTTL applies to clusters and to the list of clusters. Defaults to 5m.
+	ClusterTTL metav1.Duration `json:"clusterTTL,omitempty"`
+	// HostTTL defaults to 1m
+	HostTTL metav1.Duration `json:"hostTTL,omitempty"`
+	// CategoryTTL defaults to 5m
+	CategoryTTL metav1.Duration `json:"categoryTTL,omitempty"`
+	// NetworkTTL applies to subnets and VPCs. Defaults to 5m.
+	NetworkTTL metav1.Duration `json:"networkTTL,omitempty"`
+	// NotFoundTTL defaults to 10s
+	NotFoundTTL metav1.Duration `json:"notFoundTTL,omitempty"`
+}
+
+const (
+	DefaultCacheMaxEntries  = 4096
+	DefaultCacheVMTTL       = 10 * time.Second
+	DefaultCacheClusterTTL  = 5 * time.Minute
+	DefaultCacheHostTTL     = time.Minute
+	DefaultCacheCategoryTTL = 5 * time.Minute
+	DefaultCacheNetworkTTL  = 5 * time.Minute
+	DefaultCacheNotFoundTTL = 10 * time.Second
+)
+
+// RetryConfig configures the retries of Prism Central read requests failing with transient
+// errors such as 5xx responses, rate limiting, timeouts and connection resets. The backoff
+// between attempts doubles after each attempt and is jittered.
+type RetryConfig struct {
+	// MaxAttempts bounds the number of attempts of a request; 1 disables retries. Defaults to 3.
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+	// InitialBackoff defaults to 200ms
+	InitialBackoff metav1.Duration `json:"initialBackoff,omitempty"`
+	// MaxBackoff defaults to 2s
+	MaxBackoff metav1.Duration `json:"maxBackoff,omitempty"`
+}
+
+const (
+	DefaultRetryMaxAttempts    = 3
+	DefaultRetryInitialBackoff = 200 * time.Millisecond
+	DefaultRetryMaxBackoff     = 2 * time.Second
+)
+
+// CircuitBreakerConfig configures the circuit breaker of Prism Central requests. The circuit
+// breaker opens after FailureThreshold consecutive transient errors and fails requests without
+// calling Prism Central for OpenDuration, after which a single probe request is let through.
+type CircuitBreakerConfig struct {
+	// Disabled turns off the circuit breaker
+	Disabled bool `json:"disabled,omitempty"`
+	// FailureThreshold defaults to 5
+	FailureThreshold int `json:"failureThreshold,omitempty"`
+	// OpenDuration defaults to 30s
+	OpenDuration metav1.Duration `json:"openDuration,omitempty"`
+}
+
+const (
+	DefaultCircuitBreakerFailureThreshold = 5
+	DefaultCircuitBreakerOpenDuration     = 30 * time.Second
+)
+
+// RateLimitConfig configures the client-side token bucket rate limiter of Prism Central requests.
+// Requests throttled by Prism Central hold off all requests for the Retry-After delay.
+type RateLimitConfig struct {
+	// Disabled turns off the client-side rate limiter. Throttled requests are still honored.
+	Disabled bool `json:"disabled,omitempty"`
+	// QPS is the sustained rate of requests. Defaults to 10.
+	QPS float32 `json:"qps,omitempty"`
+	// Burst is the maximum number of requests sent at once. Defaults to 20.
+	Burst int `json:"burst,omitempty"`
+	// DefaultRetryAfter is the delay after a throttled request whose response has no Retry-After
+	// header. Defaults to 1s.
+	DefaultRetryAfter metav1.Duration `json:"defaultRetryAfter,omitempty"`
+}
+
+const (
+	DefaultRateLimitQPS        = 10
+	DefaultRateLimitBurst      = 20
+	DefaultRateLimitRetryAfter = time.Second
+)
+
+// NodeLabelSyncConfig configures the periodic reconciliation of the node labels owned by the
+// CCM with Prism Central. Labels are updated when they changed, e.g. after a VM live migrated
+// to another AHV host, and removed when they no longer apply.
+type NodeLabelSyncConfig struct {
+	// Disabled turns off the reconciliation; labels are then only set when nodes are initialized
+	Disabled bool `json:"disabled,omitempty"`
+	// Period defaults to 5m
+	Period metav1.Duration `json:"period,omitempty"`
+}
+
+const (
+	DefaultNodeLabelSyncPeriod = 5 * time.Minute
+)
+
+// CategoryLabelsConfig syncs the Prism categories assigned to the VM, its AHV host or its
+// cluster onto node labels. Category values are sanitized into label values; keys assigned
+// multiple values by the same entity are skipped.
+type CategoryLabelsConfig struct {
+	// AllowedKeys lists the category keys synced onto node labels
+	AllowedKeys []string `json:"allowedKeys"`
+	// Prefix is prepended to the sanitized category keys to form the label keys, e.g.
+	// categories.nutanix.com/. The labels of the allowed keys are owned by the CCM and removed
+	// when they no longer apply; the other labels with this prefix are left untouched.
+	Prefix string `json:"prefix,omitempty"`
+	// LabelKeys maps category keys to the label keys used instead of the prefixed category keys
+	LabelKeys map[string]string `json:"labelKeys,omitempty"`
+	// Sources lists the entities the categories are read from, by order of precedence.
+	// Defaults to VM, Host and Cluster.
+	Sources []CategorySourceType `json:"sources,omitempty"`
+}
+
+type CategorySourceType string
+
+const (
+	// VMCategorySourceType reads the categories assigned to the VM
+	VMCategorySourceType = CategorySourceType("VM")
+	// HostCategorySourceType reads the categories attached to the AHV host of the VM
+	HostCategorySourceType = CategorySourceType("Host")
+	// ClusterCategorySourceType reads the categories assigned to the cluster of the VM
+	ClusterCategorySourceType = CategorySourceType("Cluster")
+)
+
+// NodeDiscoveryConfig configures the strategies finding the VM of a node. The first strategy
+// finding a VM wins; the node is reported as not existing if none does.
+type NodeDiscoveryConfig struct {
+	// Strategies lists the strategies in the order they are tried. Defaults to SystemUUID and
+	// ProviderID.
+	Strategies []NodeDiscoveryStrategy `json:"strategies,omitempty"`
+	// CustomAttributeKey is the key of the key:value VM custom attribute set to the node name,
+	// used by the CustomAttribute strategy. Defaults to nodeName.
+	CustomAttributeKey string `json:"customAttributeKey,omitempty"`
+}
+
+type NodeDiscoveryStrategy string
+
+const (
+	// SystemUUIDNodeDiscoveryStrategy finds the VM whose UUID is the SMBIOS system UUID reported
+	// by the kubelet
+	SystemUUIDNodeDiscoveryStrategy = NodeDiscoveryStrategy("SystemUUID")
+	// ProviderIDNodeDiscoveryStrategy finds the VM whose UUID is the providerID of the node
+	ProviderIDNodeDiscoveryStrategy = NodeDiscoveryStrategy("ProviderID")
+	// VMNameNodeDiscoveryStrategy finds the VM named after the node
+	VMNameNodeDiscoveryStrategy = NodeDiscoveryStrategy("VMName")
+	// CustomAttributeNodeDiscoveryStrategy finds the VM with the custom attribute
+	// <customAttributeKey>:<node name>
+	CustomAttributeNodeDiscoveryStrategy = NodeDiscoveryStrategy("CustomAttribute")
+)
+
+const (
+	DefaultNodeDiscoveryCustomAttributeKey = "nodeName"
+)
+
+// DefaultNodeDiscoveryStrategies only match the VM by UUID, as several VMs may share a name or
+// a custom attribute.
+var DefaultNodeDiscoveryStrategies = []NodeDiscoveryStrategy{SystemUUIDNodeDiscoveryStrategy, ProviderIDNodeDiscoveryStrategy}
+
+// HostMaintenanceConfig enables the host maintenance controller. The nodes whose VM runs on an
+// AHV host in maintenance are tainted and report a node condition, so that they can be drained
+// before their VMs are migrated or powered off.
+type HostMaintenanceConfig struct {
+	// States lists the hypervisor or maintenance states of the AHV hosts in maintenance.
+	// Defaults to ENTERING_MAINTENANCE_MODE, ENTERED_MAINTENANCE_MODE and
+	// ENTERING_MAINTENANCE_MODE_FROM_HA_FAILOVER.
+	States []string `json:"states,omitempty"`
+	// Taint is the taint of the nodes on hosts in maintenance. Defaults to
+	// node.nutanix.com/host-maintenance:NoSchedule.
+	Taint *v1.Taint `json:"taint,omitempty"`
+	// ConditionType is the type of the node condition reporting whether the host is in
+	// maintenance. Defaults to HostMaintenance.
+	ConditionType v1.NodeConditionType `json:"conditionType,omitempty"`
+	// Period defaults to 1m. The AHV hosts are read through the cache, see cache.hostTTL.
+	Period metav1.Duration `json:"period,omitempty"`
+}
+
+const (
+	DefaultHostMaintenanceTaintKey      = "node.nutanix.com/host-maintenance"
+	DefaultHostMaintenanceConditionType = v1.NodeConditionType("HostMaintenance")
+	DefaultHostMaintenancePeriod        = time.Minute
+)
+
+// DefaultHostMaintenanceStates are the hypervisor states of the AHV hosts entering or in
+// maintenance.
+var DefaultHostMaintenanceStates = []string{
+	"ENTERING_MAINTENANCE_MODE",
+	"ENTERED_MAINTENANCE_MODE",
+	"ENTERING_MAINTENANCE_MODE_FROM_HA_FAILOVER",
 }
 
 type TopologyDiscovery struct {
@@ -55,23 +430,177 @@ type TopologyCategories struct {
 	RegionCategory string `json:"regionCategory"`
 }
 
+// NewConfigFromBytes decodes the config, in YAML or JSON, sets the defaults of the unset fields
+// and validates it. The returned error aggregates the errors of all the unknown or invalid
+// fields.
 func NewConfigFromBytes(bytes []byte) (Config, error) {
-	nutanixConfig := Config{}
-	if err := json.Unmarshal(bytes, &nutanixConfig); err != nil {
+	nutanixConfig, err := decode(bytes)
+	if err != nil {
 		return nutanixConfig, err
 	}
-	switch nutanixConfig.TopologyDiscovery.Type {
-	case PrismTopologyDiscoveryType:
-		return nutanixConfig, nil
-	case "":
+	nutanixConfig.setDefaults()
+	if errs := nutanixConfig.Validate(); len(errs) > 0 {
+		return nutanixConfig, errs.ToAggregate()
+	}
+	return nutanixConfig, nil
+}
+
+// GetPrismCentrals returns the Prism Centrals, or PrismCentral without a name if prismCentrals
+// is not set.
+func (c *Config) GetPrismCentrals() []PrismCentralConfig {
+	if len(c.PrismCentrals) > 0 {
+		return c.PrismCentrals
+	}
+	return []PrismCentralConfig{{NutanixPrismEndpoint: c.PrismCentral}}
+}
+
+func (c *Config) setDefaults() {
+	if c.AddressFamily == "" {
+		c.AddressFamily = IPv4FirstAddressFamilyType
+	}
+	if c.TopologyDiscovery.Type == "" {
 		klog.Warningf("topology discovery type was not set. Defaulting to %s", PrismTopologyDiscoveryType)
-		nutanixConfig.TopologyDiscovery.Type = PrismTopologyDiscoveryType
-		return nutanixConfig, nil
-	case CategoriesTopologyDiscoveryType:
-		if nutanixConfig.TopologyDiscovery.TopologyCategories == nil {
-			return nutanixConfig, fmt.Errorf("topologyCategories must be set when using topology discovery type: %s", CategoriesTopologyDiscoveryType)
+		c.TopologyDiscovery.Type = PrismTopologyDiscoveryType
+	}
+	if c.Cache == nil {
+		c.Cache = &CacheConfig{}
+	}
+	c.Cache.setDefaults()
+	if c.Retry == nil {
+		c.Retry = &RetryConfig{}
+	}
+	c.Retry.setDefaults()
+	if c.CircuitBreaker == nil {
+		c.CircuitBreaker = &CircuitBreakerConfig{}
+	}
+	c.CircuitBreaker.setDefaults()
+	if c.RateLimit == nil {
+		c.RateLimit = &RateLimitConfig{}
+	}
+	c.RateLimit.setDefaults()
+	if c.NodeLabelSync == nil {
+		c.NodeLabelSync = &NodeLabelSyncConfig{}
+	}
+	c.NodeLabelSync.setDefaults()
+	if c.NodeDiscovery == nil {
+		c.NodeDiscovery = &NodeDiscoveryConfig{}
+	}
+	c.NodeDiscovery.setDefaults()
+	if c.CategoryLabels != nil {
+		c.CategoryLabels.setDefaults()
+	}
+	if c.HostMaintenance != nil {
+		c.HostMaintenance.setDefaults()
+	}
+	if c.LoadBalancer != nil {
+		c.LoadBalancer.setDefaults()
+	}
+}
+
+func (c *CacheConfig) setDefaults() {
+	if c.MaxEntries == 0 {
+		c.MaxEntries = DefaultCacheMaxEntries
+	}
+	for _, ttl := range []struct {
+		value        *metav1.Duration
+		defaultValue time.Duration
+	}{
+		{&c.VMTTL, DefaultCacheVMTTL},
+		{&c.ClusterTTL, DefaultCacheClusterTTL},
+		{&c.HostTTL, DefaultCacheHostTTL},
+		{&c.CategoryTTL, DefaultCacheCategoryTTL},
+		{&c.NetworkTTL, DefaultCacheNetworkTTL},
+		{&c.NotFoundTTL, DefaultCacheNotFoundTTL},
+	} {
+		if ttl.value.Duration == 0 {
+			ttl.value.Duration = ttl.defaultValue
 		}
-		return nutanixConfig, nil
 	}
-	return nutanixConfig, fmt.Errorf("unsupported topology discovery type: %s", nutanixConfig.TopologyDiscovery.Type)
+}
+
+func (r *RetryConfig) setDefaults() {
+	if r.MaxAttempts == 0 {
+		r.MaxAttempts = DefaultRetryMaxAttempts
+	}
+	if r.InitialBackoff.Duration == 0 {
+		r.InitialBackoff.Duration = DefaultRetryInitialBackoff
+	}
+	if r.MaxBackoff.Duration == 0 {
+		r.MaxBackoff.Duration = max(DefaultRetryMaxBackoff, r.InitialBackoff.Duration)
+	}
+}
+
+func (cb *CircuitBreakerConfig) setDefaults() {
+	if cb.FailureThreshold == 0 {
+		cb.FailureThreshold = DefaultCircuitBreakerFailureThreshold
+	}
+	if cb.OpenDuration.Duration == 0 {
+		cb.OpenDuration.Duration = DefaultCircuitBreakerOpenDuration
+	}
+}
+
+func (rl *RateLimitConfig) setDefaults() {
+	if rl.QPS == 0 {
+		rl.QPS = DefaultRateLimitQPS
+	}
+	if rl.Burst == 0 {
+		rl.Burst = DefaultRateLimitBurst
+	}
+	if rl.DefaultRetryAfter.Duration == 0 {
+		rl.DefaultRetryAfter.Duration = DefaultRateLimitRetryAfter
+	}
+}
+
+func (ls *NodeLabelSyncConfig) setDefaults() {
+	if ls.Period.Duration == 0 {
+		ls.Period.Duration = DefaultNodeLabelSyncPeriod
+	}
+}
+
+func (nd *NodeDiscoveryConfig) setDefaults() {
+	if len(nd.Strategies) == 0 {
+		nd.Strategies = slices.Clone(DefaultNodeDiscoveryStrategies)
+	}
+	if nd.CustomAttributeKey == "" {
+		nd.CustomAttributeKey = DefaultNodeDiscoveryCustomAttributeKey
+	}
+}
+
+func (cl *CategoryLabelsConfig) setDefaults() {
+	if len(cl.Sources) == 0 {
+		cl.Sources = []CategorySourceType{VMCategorySourceType, HostCategorySourceType, ClusterCategorySourceType}
+	}
+}
+
+func (hm *HostMaintenanceConfig) setDefaults() {
+	if len(hm.States) == 0 {
+		hm.States = slices.Clone(DefaultHostMaintenanceStates)
+	}
+	if hm.Taint == nil {
+		hm.Taint = &v1.Taint{Key: DefaultHostMaintenanceTaintKey, Effect: v1.TaintEffectNoSchedule}
+	}
+	if hm.Taint.Effect == "" {
+		hm.Taint.Effect = v1.TaintEffectNoSchedule
+	}
+	if hm.ConditionType == "" {
+		hm.ConditionType = DefaultHostMaintenanceConditionType
+	}
+	if hm.Period.Duration == 0 {
+		hm.Period.Duration = DefaultHostMaintenancePeriod
+	}
+}
+
+func (lb *LoadBalancerConfig) setDefaults() {
+	if lb.FloatingIP != nil {
+		if lb.FloatingIP.Association == "" {
+			lb.FloatingIP.Association = VIPFloatingIPAssociationType
+		}
+		// No VIP is allocated when the floating IP is associated with a node NIC
+		if lb.FloatingIP.Association == NodeNICFloatingIPAssociationType {
+			return
+		}
+	}
+	if lb.IPAM == "" {
+		lb.IPAM = PoolLoadBalancerIPAMType
+	}
 }
@@ -0,0 +1,90 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	klog "k8s.io/klog/v2"
+	sigsjson "sigs.k8s.io/json"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// APIVersion is the version of the config schema
+	APIVersion = "cloudprovider.nutanix.com/v1alpha1"
+	// Kind is the kind of the config
+	Kind = "NutanixCloudConfig"
+)
+
+// decode decodes the config in YAML or JSON. A versioned config is decoded strictly: unknown and
+// duplicate fields are errors. A config without apiVersion and kind is the unversioned format of
+// the earlier releases, and is converted to the current version.
+func decode(data []byte) (Config, error) {
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return Config{}, err
+	}
+	c := Config{}
+	if err := json.Unmarshal(jsonData, &c.TypeMeta); err != nil {
+		return Config{}, err
+	}
+
+	switch {
+	case c.APIVersion == "" && c.Kind == "":
+		return convertUnversioned(jsonData)
+	case c.APIVersion != APIVersion || c.Kind != Kind:
+		return Config{}, fmt.Errorf("unsupported config apiVersion %q and kind %q: expected apiVersion %q and kind %q", c.APIVersion, c.Kind, APIVersion, Kind)
+	}
+
+	// The JSON decoder reports the duplicate fields along with the unknown ones.
+	if json.Valid(data) {
+		jsonData = data
+	} else if jsonData, err = yaml.YAMLToJSONStrict(data); err != nil {
+		return Config{}, err
+	}
+	strictErrs, err := sigsjson.UnmarshalStrict(jsonData, &c)
+	if err != nil {
+		return Config{}, err
+	}
+	if len(strictErrs) > 0 {
+		return Config{}, utilerrors.NewAggregate(strictErrs)
+	}
+	return c, nil
+}
+
+// convertUnversioned converts the unversioned config. It keeps being decoded leniently so that
+// existing deployments keep working, but its unknown and duplicate fields are logged. Its field
+// names are matched case-sensitively like in the versioned config, so that the fields logged as
+// unknown are the fields ignored.
+func convertUnversioned(jsonData []byte) (Config, error) {
+	c := Config{}
+	if err := sigsjson.UnmarshalCaseSensitivePreserveInts(jsonData, &c); err != nil {
+		return Config{}, err
+	}
+	if strictErrs, err := sigsjson.UnmarshalStrict(jsonData, &Config{}); err == nil {
+		for _, strictErr := range strictErrs {
+			klog.Warningf("ignoring the field of the unversioned cloud config: %v", strictErr)
+		}
+	}
+	klog.Infof("converting the unversioned cloud config; set apiVersion %s and kind %s to decode it strictly", APIVersion, Kind)
+	c.APIVersion = APIVersion
+	c.Kind = Kind
+	return c, nil
+}
@@ -0,0 +1,411 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strings"
+
+	credentialTypes "github.com/nutanix-cloud-native/prism-go-client/environment/credentials"
+	"go4.org/netipx"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// SupportedShutdownPowerStates are the VM power states that can be reported as shutdown. ON is
+// not one of them, since running nodes would be tainted as shut down.
+var SupportedShutdownPowerStates = []string{"OFF", "PAUSED", "UNDETERMINED"}
+
+var (
+	supportedAddressFamilies = []AddressFamilyType{
+		IPv4FirstAddressFamilyType, IPv6FirstAddressFamilyType, IPv4OnlyAddressFamilyType, IPv6OnlyAddressFamilyType,
+	}
+	supportedNodeAddressTypes       = []NodeAddressType{InternalIPNodeAddressType, ExternalIPNodeAddressType, ExcludedNodeAddressType}
+	supportedTopologyDiscoveries    = []TopologyDiscoveryType{PrismTopologyDiscoveryType, CategoriesTopologyDiscoveryType}
+	supportedLoadBalancerIPAMs      = []LoadBalancerIPAMType{PoolLoadBalancerIPAMType, PrismLoadBalancerIPAMType}
+	supportedFloatingIPAssociations = []FloatingIPAssociationType{VIPFloatingIPAssociationType, NodeNICFloatingIPAssociationType}
+	supportedCategorySources        = []CategorySourceType{VMCategorySourceType, HostCategorySourceType, ClusterCategorySourceType}
+	supportedNodeDiscoveries        = []NodeDiscoveryStrategy{
+		SystemUUIDNodeDiscoveryStrategy, ProviderIDNodeDiscoveryStrategy, VMNameNodeDiscoveryStrategy, CustomAttributeNodeDiscoveryStrategy,
+	}
+	supportedTaintEffects     = []v1.TaintEffect{v1.TaintEffectNoSchedule, v1.TaintEffectPreferNoSchedule, v1.TaintEffectNoExecute}
+	supportedTrustBundleKinds = []credentialTypes.NutanixTrustBundleKind{credentialTypes.NutanixTrustBundleKindString, credentialTypes.NutanixTrustBundleKindConfigMap}
+	supportedCredentialKinds  = []credentialTypes.NutanixCredentialKind{credentialTypes.SecretKind}
+)
+
+// Validate returns the errors of all the invalid fields of the config, with their JSON path.
+// The defaults of the config are expected to be set.
+func (c *Config) Validate() field.ErrorList {
+	var errs field.ErrorList
+
+	if len(c.PrismCentrals) == 0 {
+		errs = append(errs, validatePrismEndpoint(field.NewPath("prismCentral"), c.PrismCentral)...)
+	} else if c.PrismCentral.Address != "" {
+		errs = append(errs, field.Forbidden(field.NewPath("prismCentral"), "cannot be set together with prismCentrals"))
+	}
+	for i, pc := range c.PrismCentrals {
+		path := field.NewPath("prismCentrals").Index(i)
+		if msgs := validation.IsValidLabelValue(pc.Name); pc.Name == "" {
+			errs = append(errs, field.Required(path.Child("name"), ""))
+		} else if len(msgs) > 0 {
+			errs = append(errs, field.Invalid(path.Child("name"), pc.Name, strings.Join(msgs, ", ")))
+		} else if idx := slices.IndexFunc(c.PrismCentrals, func(other PrismCentralConfig) bool {
+			return other.Name == pc.Name
+		}); idx != i {
+			errs = append(errs, field.Duplicate(path.Child("name"), pc.Name))
+		}
+		errs = append(errs, validatePrismEndpoint(path, pc.NutanixPrismEndpoint)...)
+	}
+
+	topologyPath := field.NewPath("topologyDiscovery")
+	errs = append(errs, validateSupported(topologyPath.Child("type"), c.TopologyDiscovery.Type, supportedTopologyDiscoveries)...)
+	if c.TopologyDiscovery.Type == CategoriesTopologyDiscoveryType && c.TopologyDiscovery.TopologyCategories == nil {
+		errs = append(errs, field.Required(topologyPath.Child("topologyCategories"),
+			fmt.Sprintf("must be set when using topology discovery type: %s", CategoriesTopologyDiscoveryType)))
+	}
+
+	errs = append(errs, validateIPs(field.NewPath("ignoredNodeIPs"), c.IgnoredNodeIPs)...)
+	errs = append(errs, validateSupported(field.NewPath("addressFamily"), c.AddressFamily, supportedAddressFamilies)...)
+	for i, rule := range c.NodeAddressRules {
+		path := field.NewPath("nodeAddressRules").Index(i)
+		if rule.SubnetName == "" && rule.SubnetUUID == "" && len(rule.CIDRs) == 0 {
+			errs = append(errs, field.Required(path, "must set at least one of subnetName, subnetUUID or cidrs"))
+		}
+		errs = append(errs, validateIPs(path.Child("cidrs"), rule.CIDRs)...)
+		errs = append(errs, validateSupported(path.Child("type"), rule.Type, supportedNodeAddressTypes)...)
+	}
+
+	if c.LoadBalancer != nil {
+		errs = append(errs, c.LoadBalancer.validate(field.NewPath("loadBalancer"))...)
+	}
+	if c.Routes != nil && c.Routes.VPCUUID == "" {
+		errs = append(errs, field.Required(field.NewPath("routes", "vpcUUID"), "must be set when routes are configured"))
+	}
+
+	if c.Cache != nil {
+		path := field.NewPath("cache")
+		errs = append(errs, validateNonNegative(path.Child("maxEntries"), c.Cache.MaxEntries)...)
+		for _, ttl := range []struct {
+			field string
+			value metav1.Duration
+		}{
+			{"vmTTL", c.Cache.VMTTL},
+			{"clusterTTL", c.Cache.ClusterTTL},
+			{"hostTTL", c.Cache.HostTTL},
+			{"categoryTTL", c.Cache.CategoryTTL},
+			{"networkTTL", c.Cache.NetworkTTL},
+			{"notFoundTTL", c.Cache.NotFoundTTL},
+		} {
+			errs = append(errs, validateNonNegativeDuration(path.Child(ttl.field), ttl.value)...)
+		}
+	}
+	if c.Retry != nil {
+		path := field.NewPath("retry")
+		errs = append(errs, validateNonNegative(path.Child("maxAttempts"), c.Retry.MaxAttempts)...)
+		errs = append(errs, validateNonNegativeDuration(path.Child("initialBackoff"), c.Retry.InitialBackoff)...)
+		errs = append(errs, validateNonNegativeDuration(path.Child("maxBackoff"), c.Retry.MaxBackoff)...)
+		if c.Retry.MaxBackoff.Duration < c.Retry.InitialBackoff.Duration {
+			errs = append(errs, field.Invalid(path.Child("maxBackoff"), c.Retry.MaxBackoff.Duration.String(), "cannot be less than retry.initialBackoff"))
+		}
+	}
+	if c.CircuitBreaker != nil {
+		path := field.NewPath("circuitBreaker")
+		errs = append(errs, validateNonNegative(path.Child("failureThreshold"), c.CircuitBreaker.FailureThreshold)...)
+		errs = append(errs, validateNonNegativeDuration(path.Child("openDuration"), c.CircuitBreaker.OpenDuration)...)
+	}
+	if c.RateLimit != nil {
+		path := field.NewPath("rateLimit")
+		errs = append(errs, validateNonNegative(path.Child("qps"), c.RateLimit.QPS)...)
+		errs = append(errs, validateNonNegative(path.Child("burst"), c.RateLimit.Burst)...)
+		errs = append(errs, validateNonNegativeDuration(path.Child("defaultRetryAfter"), c.RateLimit.DefaultRetryAfter)...)
+	}
+	if c.NodeLabelSync != nil {
+		errs = append(errs, validateNonNegativeDuration(field.NewPath("nodeLabelSync", "period"), c.NodeLabelSync.Period)...)
+	}
+
+	if c.CategoryLabels != nil {
+		errs = append(errs, c.CategoryLabels.validate(field.NewPath("categoryLabels"))...)
+	}
+	for i := range c.TaintRules {
+		errs = append(errs, c.TaintRules[i].validate(field.NewPath("taintRules").Index(i))...)
+	}
+	if c.HostMaintenance != nil {
+		errs = append(errs, c.HostMaintenance.validate(field.NewPath("hostMaintenance"))...)
+	}
+	for i := range c.InstanceTypes {
+		errs = append(errs, c.InstanceTypes[i].validate(field.NewPath("instanceTypes").Index(i))...)
+	}
+	for _, instanceType := range sortedKeys(c.InstanceTypeNames) {
+		name := c.InstanceTypeNames[instanceType]
+		path := field.NewPath("instanceTypeNames").Key(instanceType)
+		if msgs := validation.IsValidLabelValue(name); name == "" {
+			errs = append(errs, field.Required(path, ""))
+		} else if len(msgs) > 0 {
+			errs = append(errs, field.Invalid(path, name, strings.Join(msgs, ", ")))
+		}
+	}
+	for i, powerState := range c.ShutdownPowerStates {
+		if !slices.ContainsFunc(SupportedShutdownPowerStates, func(supported string) bool {
+			return strings.EqualFold(supported, powerState)
+		}) {
+			errs = append(errs, field.NotSupported(field.NewPath("shutdownPowerStates").Index(i), powerState, SupportedShutdownPowerStates))
+		}
+	}
+	if c.NodeDiscovery != nil {
+		path := field.NewPath("nodeDiscovery", "strategies")
+		for i, strategy := range c.NodeDiscovery.Strategies {
+			errs = append(errs, validateSupported(path.Index(i), strategy, supportedNodeDiscoveries)...)
+			if slices.Index(c.NodeDiscovery.Strategies, strategy) != i {
+				errs = append(errs, field.Duplicate(path.Index(i), strategy))
+			}
+		}
+	}
+	return errs
+}
+
+// validatePrismEndpoint validates the address, port, credentials and trust bundle of a Prism
+// Central endpoint.
+func validatePrismEndpoint(path *field.Path, pc credentialTypes.NutanixPrismEndpoint) field.ErrorList {
+	var errs field.ErrorList
+	if pc.Address == "" {
+		errs = append(errs, field.Required(path.Child("address"), ""))
+	}
+	if pc.Port < 0 || pc.Port > 65535 {
+		errs = append(errs, field.Invalid(path.Child("port"), pc.Port, "must be between 0 and 65535"))
+	}
+	if ref := pc.CredentialRef; ref != nil {
+		refPath := path.Child("credentialRef")
+		if !IsSecretCredentialKind(ref.Kind) {
+			errs = append(errs, field.NotSupported(refPath.Child("kind"), ref.Kind, supportedCredentialKinds))
+		}
+		if ref.Name == "" {
+			errs = append(errs, field.Required(refPath.Child("name"), ""))
+		}
+	}
+	if ref := pc.AdditionalTrustBundle; ref != nil {
+		refPath := path.Child("additionalTrustBundle")
+		errs = append(errs, validateSupported(refPath.Child("kind"), ref.Kind, supportedTrustBundleKinds)...)
+		if ref.Kind == credentialTypes.NutanixTrustBundleKindString && ref.Data == "" {
+			errs = append(errs, field.Required(refPath.Child("data"), fmt.Sprintf("must be set when using trust bundle kind: %s", ref.Kind)))
+		}
+		if ref.Kind == credentialTypes.NutanixTrustBundleKindConfigMap && ref.Name == "" {
+			errs = append(errs, field.Required(refPath.Child("name"), fmt.Sprintf("must be set when using trust bundle kind: %s", ref.Kind)))
+		}
+	}
+	return errs
+}
+
+func (lb *LoadBalancerConfig) validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if fip := lb.FloatingIP; fip != nil {
+		fipPath := path.Child("floatingIP")
+		if fip.ExternalSubnetUUID == "" {
+			errs = append(errs, field.Required(fipPath.Child("externalSubnetUUID"), ""))
+		}
+		if fip.VPCUUID == "" {
+			errs = append(errs, field.Required(fipPath.Child("vpcUUID"), ""))
+		}
+		errs = append(errs, validateSupported(fipPath.Child("association"), fip.Association, supportedFloatingIPAssociations)...)
+		if fip.Association == NodeNICFloatingIPAssociationType {
+			// No VIP is allocated when the floating IP is associated with a node NIC
+			if lb.IPAM != "" || len(lb.IPPools) > 0 || lb.SubnetUUID != "" {
+				errs = append(errs, field.Forbidden(path, fmt.Sprintf("IPAM cannot be configured when using floating IP association: %s", NodeNICFloatingIPAssociationType)))
+			}
+			return errs
+		}
+	}
+
+	errs = append(errs, validateSupported(path.Child("ipam"), lb.IPAM, supportedLoadBalancerIPAMs)...)
+	switch lb.IPAM {
+	case PoolLoadBalancerIPAMType:
+		if len(lb.IPPools) == 0 {
+			errs = append(errs, field.Required(path.Child("ipPools"), fmt.Sprintf("must be set when using load balancer IPAM: %s", lb.IPAM)))
+		}
+	case PrismLoadBalancerIPAMType:
+		if lb.SubnetUUID == "" {
+			errs = append(errs, field.Required(path.Child("subnetUUID"), fmt.Sprintf("must be set when using load balancer IPAM: %s", lb.IPAM)))
+		}
+	}
+	errs = append(errs, validateIPs(path.Child("ipPools"), lb.IPPools)...)
+	return errs
+}
+
+func (cl *CategoryLabelsConfig) validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if len(cl.AllowedKeys) == 0 {
+		errs = append(errs, field.Required(path.Child("allowedKeys"), "must be set when category labels are configured"))
+	}
+	for i, key := range cl.AllowedKeys {
+		if key == "" {
+			errs = append(errs, field.Required(path.Child("allowedKeys").Index(i), ""))
+		}
+	}
+	if cl.Prefix != "" {
+		// The prefix is followed by at least one character of the sanitized category key
+		if msgs := validation.IsQualifiedName(cl.Prefix + "x"); len(msgs) > 0 {
+			errs = append(errs, field.Invalid(path.Child("prefix"), cl.Prefix, strings.Join(msgs, ", ")))
+		}
+	}
+	for _, key := range sortedKeys(cl.LabelKeys) {
+		keyPath := path.Child("labelKeys").Key(key)
+		if !slices.Contains(cl.AllowedKeys, key) {
+			errs = append(errs, field.Invalid(keyPath, key, "must map an allowed key"))
+		}
+		if msgs := validation.IsQualifiedName(cl.LabelKeys[key]); len(msgs) > 0 {
+			errs = append(errs, field.Invalid(keyPath, cl.LabelKeys[key], strings.Join(msgs, ", ")))
+		}
+	}
+	for i, source := range cl.Sources {
+		errs = append(errs, validateSupported(path.Child("sources").Index(i), source, supportedCategorySources)...)
+	}
+	return errs
+}
+
+func (r *TaintRule) validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if len(r.Categories) == 0 && len(r.CustomAttributes) == 0 {
+		errs = append(errs, field.Required(path, "must set at least one of categories or customAttributes"))
+	}
+	return append(errs, validateTaint(path, r.Key, r.Value, r.Effect)...)
+}
+
+func (hm *HostMaintenanceConfig) validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, state := range hm.States {
+		if state == "" {
+			errs = append(errs, field.Required(path.Child("states").Index(i), ""))
+		}
+	}
+	if hm.Taint != nil {
+		errs = append(errs, validateTaint(path.Child("taint"), hm.Taint.Key, hm.Taint.Value, hm.Taint.Effect)...)
+	}
+	return append(errs, validateNonNegativeDuration(path.Child("period"), hm.Period)...)
+}
+
+func (c *InstanceTypeConfig) validate(path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if msgs := validation.IsValidLabelValue(c.Name); c.Name == "" {
+		errs = append(errs, field.Required(path.Child("name"), ""))
+	} else if len(msgs) > 0 {
+		errs = append(errs, field.Invalid(path.Child("name"), c.Name, strings.Join(msgs, ", ")))
+	}
+	errs = append(errs, validateNonNegative(path.Child("minVCPUs"), c.MinVCPUs)...)
+	errs = append(errs, validateNonNegative(path.Child("maxVCPUs"), c.MaxVCPUs)...)
+	if c.MaxVCPUs > 0 && c.MinVCPUs > c.MaxVCPUs {
+		errs = append(errs, field.Invalid(path.Child("minVCPUs"), c.MinVCPUs, "cannot be greater than maxVCPUs"))
+	}
+	if c.MinMemory != nil && c.MinMemory.Sign() < 0 {
+		errs = append(errs, field.Invalid(path.Child("minMemory"), c.MinMemory.String(), "cannot be negative"))
+	}
+	if c.MaxMemory != nil && c.MaxMemory.Sign() < 0 {
+		errs = append(errs, field.Invalid(path.Child("maxMemory"), c.MaxMemory.String(), "cannot be negative"))
+	}
+	if c.MinMemory != nil && c.MaxMemory != nil && c.MinMemory.Cmp(*c.MaxMemory) > 0 {
+		errs = append(errs, field.Invalid(path.Child("minMemory"), c.MinMemory.String(), "cannot be greater than maxMemory"))
+	}
+	return errs
+}
+
+// validateTaint validates the key, value and effect of a taint.
+func validateTaint(path *field.Path, key, value string, effect v1.TaintEffect) field.ErrorList {
+	var errs field.ErrorList
+	if msgs := validation.IsQualifiedName(key); len(msgs) > 0 {
+		errs = append(errs, field.Invalid(path.Child("key"), key, strings.Join(msgs, ", ")))
+	}
+	if msgs := validation.IsValidLabelValue(value); len(msgs) > 0 {
+		errs = append(errs, field.Invalid(path.Child("value"), value, strings.Join(msgs, ", ")))
+	}
+	return append(errs, validateSupported(path.Child("effect"), effect, supportedTaintEffects)...)
+}
+
+// validateIPs validates a list of IP addresses, CIDR prefixes and IP ranges.
+func validateIPs(path *field.Path, entries []string) field.ErrorList {
+	var errs field.ErrorList
+	for i, entry := range entries {
+		if _, err := ParseIPRange(entry); err != nil {
+			errs = append(errs, field.Invalid(path.Index(i), entry, err.Error()))
+		}
+	}
+	return errs
+}
+
+// ParseIPRange parses an IPv4 or IPv6 address, CIDR prefix ("10.0.0.0/24", "fd00::/64") or IP
+// range ("10.0.0.1-10.0.0.10", "fd00::1-fd00::10") into an IP range. IPv4-mapped IPv6 addresses
+// are converted to IPv4 addresses.
+func ParseIPRange(entry string) (netipx.IPRange, error) {
+	switch {
+	case strings.Contains(entry, "-"):
+		ipRange, err := netipx.ParseIPRange(entry)
+		if err != nil {
+			return netipx.IPRange{}, fmt.Errorf("invalid IP range: %v", err)
+		}
+		from, to := ipRange.From(), ipRange.To()
+		if from.Is4In6() && to.Is4In6() {
+			ipRange = netipx.IPRangeFrom(from.Unmap(), to.Unmap())
+		}
+		return ipRange, nil
+	case strings.Contains(entry, "/"):
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return netipx.IPRange{}, fmt.Errorf("invalid IP prefix: %v", err)
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return netipx.RangeOfPrefix(prefix), nil
+	}
+	ip, err := netip.ParseAddr(entry)
+	if err != nil {
+		return netipx.IPRange{}, fmt.Errorf("invalid IP: %v", err)
+	}
+	if ip.Zone() != "" {
+		return netipx.IPRange{}, fmt.Errorf("invalid IP: IPv6 zones are not supported")
+	}
+	return netipx.IPRangeFrom(ip.Unmap(), ip.Unmap()), nil
+}
+
+func validateSupported[T ~string](path *field.Path, value T, supported []T) field.ErrorList {
+	if slices.Contains(supported, value) {
+		return nil
+	}
+	return field.ErrorList{field.NotSupported(path, value, supported)}
+}
+
+func validateNonNegative[T int | float32](path *field.Path, value T) field.ErrorList {
+	if value < 0 {
+		return field.ErrorList{field.Invalid(path, value, "cannot be negative")}
+	}
+	return nil
+}
+
+func validateNonNegativeDuration(path *field.Path, value metav1.Duration) field.ErrorList {
+	if value.Duration < 0 {
+		return field.ErrorList{field.Invalid(path, value.Duration.String(), "cannot be negative")}
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of the map in order, so that the errors are reported in a stable
+// order.
+func sortedKeys(m map[string]string) []string {
+	return slices.Sorted(maps.Keys(m))
+}
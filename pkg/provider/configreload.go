@@ -0,0 +1,139 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"os"
+	"reflect"
+
+	v1 "k8s.io/api/core/v1"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+)
+
+// current returns the manager with the last reloaded config. Callers use the returned manager
+// for a whole request or reconcile, so that a reload does not change the config in flight.
+func (n *nutanixManager) current() *nutanixManager {
+	if n.reloaded == nil {
+		return n
+	}
+	if m := n.reloaded.Load(); m != nil {
+		return m
+	}
+	return n
+}
+
+// setConfigMapInformer reloads the config when the ConfigMap of the cloud config changes.
+func (n *nutanixManager) setConfigMapInformer(configMapInformer coreinformers.ConfigMapInformer) {
+	name := getConfigMapName()
+	reload := func(obj interface{}) {
+		configMap, ok := obj.(*v1.ConfigMap)
+		if !ok || configMap.Name != name {
+			return
+		}
+		data, ok := configMap.Data[constants.ConfigMapKey]
+		if !ok {
+			klog.Warningf("ConfigMap %s/%s has no %s key: not reloading the config", configMap.Namespace, name, constants.ConfigMapKey) //nolint:typecheck
+			return
+		}
+		if err := n.reloadConfig([]byte(data)); err != nil {
+			configReloadsTotal.WithLabelValues(configReloadFailure).Inc()
+			klog.Errorf("ignoring invalid config of ConfigMap %s/%s: %v", configMap.Namespace, name, err) //nolint:typecheck
+		}
+	}
+	if _, err := configMapInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    reload,
+		UpdateFunc: func(_, newObj interface{}) { reload(newObj) },
+	}); err != nil {
+		klog.Errorf("failed to add config map event handler: %v", err) //nolint:typecheck
+	}
+}
+
+// reloadConfig validates the config and swaps the current manager for one with the config. The
+// settings only taken into account when the CCM starts are kept.
+func (n *nutanixManager) reloadConfig(data []byte) error {
+	nutanixConfig, err := config.NewConfigFromBytes(data)
+	if err != nil {
+		return err
+	}
+
+	// The clients default the namespace of the references of the current config.
+	if ccmNamespace, err := GetCCMNamespace(); err == nil {
+		setDefaultNamespaces(nutanixConfig.PrismCentral, ccmNamespace)
+		for _, pc := range nutanixConfig.PrismCentrals {
+			setDefaultNamespaces(pc.NutanixPrismEndpoint, ccmNamespace)
+		}
+	}
+
+	current := n.current()
+	if changed := keepStartupSettings(&nutanixConfig, current.config); len(changed) > 0 {
+		klog.Warningf("the CCM must be restarted to apply the changed settings %v", changed) //nolint:typecheck
+	}
+	if reflect.DeepEqual(nutanixConfig, current.config) {
+		return nil
+	}
+
+	next := *current
+	if err := next.applyConfig(nutanixConfig); err != nil {
+		return err
+	}
+	n.reloaded.Store(&next)
+	configReloadsTotal.WithLabelValues(configReloadSuccess).Inc()
+	klog.Info("reloaded the config") //nolint:typecheck
+	return nil
+}
+
+// keepStartupSettings copies the settings of the clients and controllers created when the CCM
+// starts from the current config, and returns the names of the ones that changed.
+func keepStartupSettings(nutanixConfig *config.Config, current config.Config) []string {
+	settings := []struct {
+		name          string
+		value, actual any
+	}{
+		{"prismCentral", &nutanixConfig.PrismCentral, &current.PrismCentral},
+		{"prismCentrals", &nutanixConfig.PrismCentrals, &current.PrismCentrals},
+		{"loadBalancer", &nutanixConfig.LoadBalancer, &current.LoadBalancer},
+		{"routes", &nutanixConfig.Routes, &current.Routes},
+		{"cache", &nutanixConfig.Cache, &current.Cache},
+		{"retry", &nutanixConfig.Retry, &current.Retry},
+		{"circuitBreaker", &nutanixConfig.CircuitBreaker, &current.CircuitBreaker},
+		{"rateLimit", &nutanixConfig.RateLimit, &current.RateLimit},
+		{"nodeLabelSync", &nutanixConfig.NodeLabelSync, &current.NodeLabelSync},
+		{"hostMaintenance", &nutanixConfig.HostMaintenance, &current.HostMaintenance},
+	}
+	var changed []string
+	for _, setting := range settings {
+		value, actual := reflect.ValueOf(setting.value).Elem(), reflect.ValueOf(setting.actual).Elem()
+		if !reflect.DeepEqual(value.Interface(), actual.Interface()) {
+			changed = append(changed, setting.name)
+		}
+		value.Set(actual)
+	}
+	return changed
+}
+
+// getConfigMapName returns the name of the ConfigMap of the cloud config.
+func getConfigMapName() string {
+	if name := os.Getenv(constants.ConfigMapNameKey); name != "" {
+		return name
+	}
+	return constants.DefaultConfigMapName
+}
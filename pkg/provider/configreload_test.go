@@ -0,0 +1,139 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:typecheck // Test file uses ginkgo/gomega which typecheck doesn't understand well
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/netip"
+
+	credentialTypes "github.com/nutanix-cloud-native/prism-go-client/environment/credentials"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+)
+
+var _ = Describe("Test Config Reload", func() { // nolint:typecheck
+	var (
+		ctx context.Context
+		c   config.Config
+		m   *nutanixManager
+	)
+
+	marshal := func(c config.Config) []byte {
+		cBytes, err := json.Marshal(c)
+		Expect(err).ToNot(HaveOccurred())
+		return cBytes
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = config.Config{
+			PrismCentral: credentialTypes.NutanixPrismEndpoint{Address: "pc.example.com", Port: 9440},
+			TopologyDiscovery: config.TopologyDiscovery{
+				Type: config.PrismTopologyDiscoveryType,
+			},
+		}
+		nutanixConfig, err := config.NewConfigFromBytes(marshal(c))
+		Expect(err).ToNot(HaveOccurred())
+		m, err = newNutanixManager(nutanixConfig)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should return the manager itself if the config was not reloaded", func() {
+		Expect(m.current()).To(BeIdenticalTo(m))
+	})
+
+	It("should swap the config and the state derived from it", func() {
+		snapshot := m.current()
+		c.IgnoredNodeIPs = []string{"10.0.0.1"}
+		c.EnableCustomLabeling = true
+		Expect(m.reloadConfig(marshal(c))).To(Succeed())
+
+		current := m.current()
+		Expect(current).ToNot(BeIdenticalTo(m))
+		Expect(current.config.EnableCustomLabeling).To(BeTrue())
+		Expect(current.ignoredNodeIPs.Contains(netip.MustParseAddr("10.0.0.1"))).To(BeTrue())
+		Expect(current.nutanixClient).To(BeIdenticalTo(m.nutanixClient))
+
+		// A reconcile in flight keeps the config it started with.
+		Expect(snapshot.config.EnableCustomLabeling).To(BeFalse())
+		Expect(snapshot.ignoredNodeIPs.Contains(netip.MustParseAddr("10.0.0.1"))).To(BeFalse())
+	})
+
+	It("should keep the current config if the config is invalid", func() {
+		c.IgnoredNodeIPs = []string{"not-an-ip"}
+		Expect(m.reloadConfig(marshal(c))).ToNot(Succeed())
+		Expect(m.reloadConfig([]byte("{"))).ToNot(Succeed())
+		Expect(m.current()).To(BeIdenticalTo(m))
+	})
+
+	It("should not swap the manager if the config did not change", func() {
+		Expect(m.reloadConfig(marshal(c))).To(Succeed())
+		Expect(m.current()).To(BeIdenticalTo(m))
+	})
+
+	It("should keep the settings applied when the CCM starts", func() {
+		c.PrismCentral.Address = "other-pc.example.com"
+		c.Routes = &config.RoutesConfig{VPCUUID: "00000000-0000-0000-0000-000000000001"}
+		c.EnableCustomLabeling = true
+		Expect(m.reloadConfig(marshal(c))).To(Succeed())
+
+		current := m.current()
+		Expect(current.config.EnableCustomLabeling).To(BeTrue())
+		Expect(current.config.PrismCentral.Address).To(Equal("pc.example.com"))
+		Expect(current.config.Routes).To(Equal(m.config.Routes))
+	})
+
+	It("should reload the config when its ConfigMap changes", func() {
+		kClient := fake.NewSimpleClientset()
+		informerFactory := informers.NewSharedInformerFactory(kClient, 0)
+		m.setConfigMapInformer(informerFactory.Core().V1().ConfigMaps())
+		stopCh := make(chan struct{})
+		defer close(stopCh)
+		informerFactory.Start(stopCh)
+		informerFactory.WaitForCacheSync(stopCh)
+
+		createConfigMap := func(name string) {
+			_, err := kClient.CoreV1().ConfigMaps("kube-system").Create(ctx, &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system"},
+				Data:       map[string]string{constants.ConfigMapKey: string(marshal(c))},
+			}, metav1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		c.EnableCustomLabeling = true
+		createConfigMap("other-config")
+		Consistently(func() bool { return m.current().config.EnableCustomLabeling }).Should(BeFalse())
+		createConfigMap(constants.DefaultConfigMapName)
+		Eventually(func() bool { return m.current().config.EnableCustomLabeling }).Should(BeTrue())
+
+		configMap, err := kClient.CoreV1().ConfigMaps("kube-system").Get(ctx, constants.DefaultConfigMapName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		configMap.Data[constants.ConfigMapKey] = "{"
+		_, err = kClient.CoreV1().ConfigMaps("kube-system").Update(ctx, configMap, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Consistently(func() bool { return m.current().config.EnableCustomLabeling }).Should(BeTrue())
+	})
+})
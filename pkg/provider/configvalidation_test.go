@@ -0,0 +1,143 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:typecheck // Test file uses ginkgo/gomega which typecheck doesn't understand well
+package provider
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+)
+
+var _ = Describe("Test Config Validation", func() { // nolint:typecheck
+	fieldErrors := func(data string) []string {
+		_, err := config.NewConfigFromBytes([]byte(data))
+		Expect(err).To(HaveOccurred())
+		var aggregate utilerrors.Aggregate
+		Expect(errors.As(err, &aggregate)).To(BeTrue())
+		msgs := []string{}
+		for _, fieldErr := range aggregate.Errors() {
+			msgs = append(msgs, fieldErr.Error())
+		}
+		return msgs
+	}
+
+	It("should decode a versioned config in YAML", func() {
+		c, err := config.NewConfigFromBytes([]byte(`
+apiVersion: cloudprovider.nutanix.com/v1alpha1
+kind: NutanixCloudConfig
+prismCentral:
+  address: pc.example.com
+enableCustomLabeling: true
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.PrismCentral.Address).To(Equal("pc.example.com"))
+		Expect(c.EnableCustomLabeling).To(BeTrue())
+		Expect(c.Retry.MaxAttempts).To(Equal(config.DefaultRetryMaxAttempts))
+	})
+
+	It("should report the unknown and duplicate fields of a versioned config", func() {
+		Expect(fieldErrors(`{
+			"apiVersion": "cloudprovider.nutanix.com/v1alpha1",
+			"kind": "NutanixCloudConfig",
+			"prismCentral": {"address": "pc.example.com"},
+			"enableCustomLabelling": true,
+			"retry": {"maxAttempt": 2},
+			"ignoredNodeIPs": [],
+			"ignoredNodeIPs": []
+		}`)).To(ConsistOf(
+			ContainSubstring(`unknown field "enableCustomLabelling"`),
+			ContainSubstring(`unknown field "retry.maxAttempt"`),
+			ContainSubstring(`duplicate field "ignoredNodeIPs"`),
+		))
+	})
+
+	It("should reject an unsupported version", func() {
+		_, err := config.NewConfigFromBytes([]byte(`{"apiVersion": "cloudprovider.nutanix.com/v1", "kind": "NutanixCloudConfig"}`))
+		Expect(err).To(MatchError(ContainSubstring("unsupported config")))
+	})
+
+	It("should convert an unversioned config and ignore its unknown fields", func() {
+		c, err := config.NewConfigFromBytes([]byte(`{"prismCentral": {"address": "pc.example.com"}, "enableCustomLabelling": true, "enablecustomlabeling": true}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.APIVersion).To(Equal(config.APIVersion))
+		Expect(c.Kind).To(Equal(config.Kind))
+		Expect(c.EnableCustomLabeling).To(BeFalse())
+	})
+
+	It("should accept a minimal config", func() {
+		_, err := config.NewConfigFromBytes([]byte(`{"prismCentral": {"address": "pc.example.com"}}`))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should report all invalid fields with their paths", func() {
+		Expect(fieldErrors(`{
+			"prismCentral": {"port": 70000},
+			"ignoredNodeIPs": ["10.0.0.1", "not-an-ip"],
+			"retry": {"maxAttempts": -1},
+			"shutdownPowerStates": ["OFF", "ASLEEP"]
+		}`)).To(ConsistOf(
+			HavePrefix("prismCentral.address: Required value"),
+			HavePrefix("prismCentral.port: Invalid value"),
+			HavePrefix("ignoredNodeIPs[1]: Invalid value"),
+			HavePrefix("retry.maxAttempts: Invalid value"),
+			HavePrefix("shutdownPowerStates[1]: Unsupported value"),
+		))
+	})
+
+	It("should accept the secret kind of the credentialRef in lowercase", func() {
+		_, err := config.NewConfigFromBytes([]byte(`{"prismCentral": {"address": "pc.example.com", "credentialRef": {"kind": "secret", "name": "nutanix-creds"}}}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fieldErrors(`{"prismCentral": {"address": "pc.example.com", "credentialRef": {"kind": "ConfigMap", "name": "nutanix-creds"}}}`)).To(ConsistOf(
+			HavePrefix("prismCentral.credentialRef.kind: Unsupported value"),
+		))
+	})
+
+	It("should reject the running power state as a shutdown power state", func() {
+		Expect(fieldErrors(`{
+			"prismCentral": {"address": "pc.example.com"},
+			"shutdownPowerStates": ["OFF", "ON"]
+		}`)).To(ConsistOf(
+			HavePrefix("shutdownPowerStates[1]: Unsupported value"),
+		))
+	})
+
+	It("should report the fields of each Prism Central", func() {
+		Expect(fieldErrors(`{
+			"prismCentrals": [
+				{"name": "pc-1", "address": "pc-1.example.com"},
+				{"name": "invalid name"}
+			]
+		}`)).To(ConsistOf(
+			HavePrefix("prismCentrals[1].name: Invalid value"),
+			HavePrefix("prismCentrals[1].address: Required value"),
+		))
+	})
+
+	It("should report the dependent fields of the load balancer", func() {
+		Expect(fieldErrors(`{
+			"prismCentral": {"address": "pc.example.com"},
+			"loadBalancer": {"ipam": "Prism"}
+		}`)).To(ConsistOf(
+			HavePrefix("loadBalancer.subnetUUID: Required value"),
+		))
+	})
+})
@@ -0,0 +1,138 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"reflect"
+
+	credentialtypes "github.com/nutanix-cloud-native/prism-go-client/environment/credentials"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+)
+
+// Kinds of rotated Prism Central references
+const (
+	credentialsRotationKind = "credentials"
+	trustBundleRotationKind = "trust_bundle"
+)
+
+const (
+	eventComponent = "cloud-provider-nutanix"
+
+	credentialsRotatedReason = "PrismCredentialsRotated"
+	trustBundleRotatedReason = "PrismTrustBundleRotated"
+)
+
+// newEventRecorder returns a recorder of the events of the CCM.
+func newEventRecorder(client clientset.Interface) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: eventComponent})
+}
+
+// setCredentialInformers invalidates the client of a Prism Central when the Secret of its
+// credentials or the ConfigMap of its trust bundle changes, so that the next requests use the
+// rotated ones instead of failing until the CCM restarts.
+func (n *nutanixManager) setCredentialInformers(ccmNamespace string, secretInformer coreinformers.SecretInformer, configMapInformer coreinformers.ConfigMapInformer) {
+	isCredentialRef := func(pc credentialtypes.NutanixPrismEndpoint, obj runtime.Object) bool {
+		secret, ok := obj.(*v1.Secret)
+		ref := pc.CredentialRef
+		return ok && ref != nil && config.IsSecretCredentialKind(ref.Kind) &&
+			ref.Name == secret.Name && namespaceOrDefault(ref.Namespace, ccmNamespace) == secret.Namespace
+	}
+	isTrustBundleRef := func(pc credentialtypes.NutanixPrismEndpoint, obj runtime.Object) bool {
+		configMap, ok := obj.(*v1.ConfigMap)
+		ref := pc.AdditionalTrustBundle
+		return ok && ref != nil && ref.Kind == credentialtypes.NutanixTrustBundleKindConfigMap &&
+			ref.Name == configMap.Name && namespaceOrDefault(ref.Namespace, ccmNamespace) == configMap.Namespace
+	}
+
+	if _, err := secretInformer.Informer().AddEventHandler(n.rotationEventHandler(credentialsRotationKind, isCredentialRef, func(obj interface{}) interface{} {
+		return obj.(*v1.Secret).Data
+	})); err != nil {
+		klog.Errorf("failed to add secret event handler: %v", err) //nolint:typecheck
+	}
+	if _, err := configMapInformer.Informer().AddEventHandler(n.rotationEventHandler(trustBundleRotationKind, isTrustBundleRef, func(obj interface{}) interface{} {
+		return obj.(*v1.ConfigMap).Data
+	})); err != nil {
+		klog.Errorf("failed to add config map event handler: %v", err) //nolint:typecheck
+	}
+}
+
+// rotationEventHandler returns the handler rotating the references of the kind when their data
+// changes or when they are recreated. The objects listed when the informer starts are not
+// rotations.
+func (n *nutanixManager) rotationEventHandler(kind string, isRef func(credentialtypes.NutanixPrismEndpoint, runtime.Object) bool, data func(obj interface{}) interface{}) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			if !isInInitialList {
+				n.rotate(kind, obj, isRef)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if !reflect.DeepEqual(data(oldObj), data(newObj)) {
+				n.rotate(kind, newObj, isRef)
+			}
+		},
+	}
+}
+
+// rotate invalidates the clients of the Prism Centrals referencing the object, and records the
+// rotation with an event on the object and a metric.
+func (n *nutanixManager) rotate(kind string, obj interface{}, isRef func(credentialtypes.NutanixPrismEndpoint, runtime.Object) bool) {
+	object, ok := obj.(runtime.Object)
+	if !ok {
+		return
+	}
+	prismCentralConfigs := n.config.GetPrismCentrals()
+	for i, pc := range n.getPrismCentrals() {
+		if !isRef(prismCentralConfigs[i].NutanixPrismEndpoint, object) {
+			continue
+		}
+		pc.client.Invalidate()
+		credentialRotationsTotal.WithLabelValues(pc.name, kind).Inc()
+
+		reason, message := credentialsRotatedReason, "Rotated the credentials of Prism Central"
+		if kind == trustBundleRotationKind {
+			reason, message = trustBundleRotatedReason, "Rotated the trust bundle of Prism Central"
+		}
+		if pc.name != "" {
+			message += " " + pc.name
+		}
+		klog.Info(message) //nolint:typecheck
+		if n.eventRecorder != nil {
+			n.eventRecorder.Event(object, v1.EventTypeNormal, reason, message)
+		}
+	}
+}
+
+// namespaceOrDefault returns the namespace, or the default namespace if it is empty.
+func namespaceOrDefault(namespace, defaultNamespace string) string {
+	if namespace == "" {
+		return defaultNamespace
+	}
+	return namespace
+}
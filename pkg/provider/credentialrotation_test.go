@@ -0,0 +1,171 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:typecheck // Test file uses ginkgo/gomega which typecheck doesn't understand well
+package provider
+
+import (
+	"context"
+
+	credentialTypes "github.com/nutanix-cloud-native/prism-go-client/environment/credentials"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+)
+
+var _ = Describe("Test Credential Rotation", func() { // nolint:typecheck
+	const (
+		ccmNamespace    = "kube-system"
+		credentialsName = "nutanix-creds"
+		trustBundleName = "nutanix-trust-bundle"
+	)
+
+	var (
+		ctx            context.Context
+		kClient        *fake.Clientset
+		mockClient     *mock.MockClient
+		eventRecorder  *record.FakeRecorder
+		stopCh         chan struct{}
+		credentialKind credentialTypes.NutanixCredentialKind
+	)
+
+	BeforeEach(func() {
+		credentialKind = credentialTypes.SecretKind
+	})
+
+	JustBeforeEach(func() {
+		ctx = context.Background()
+		kClient = fake.NewSimpleClientset(
+			&v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: credentialsName, Namespace: ccmNamespace},
+				Data:       map[string][]byte{"credentials": []byte("old")},
+			},
+			&v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: trustBundleName, Namespace: ccmNamespace},
+				Data:       map[string]string{"ca.crt": "old"},
+			},
+		)
+		mockEnvironment, err := mock.CreateMockEnvironment(ctx, kClient)
+		Expect(err).ToNot(HaveOccurred())
+
+		m, err := newNutanixManager(config.Config{
+			PrismCentral: credentialTypes.NutanixPrismEndpoint{
+				Address:       "pc.example.com",
+				Port:          9440,
+				CredentialRef: &credentialTypes.NutanixCredentialReference{Kind: credentialKind, Name: credentialsName},
+				AdditionalTrustBundle: &credentialTypes.NutanixTrustBundleReference{
+					Kind:      credentialTypes.NutanixTrustBundleKindConfigMap,
+					Name:      trustBundleName,
+					Namespace: ccmNamespace,
+				},
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		mockClient = mock.CreateMockClient(*mockEnvironment)
+		m.nutanixClient = mockClient
+		eventRecorder = record.NewFakeRecorder(10)
+		m.eventRecorder = eventRecorder
+
+		informerFactory := informers.NewSharedInformerFactory(kClient, 0)
+		m.setCredentialInformers(ccmNamespace, informerFactory.Core().V1().Secrets(), informerFactory.Core().V1().ConfigMaps())
+		stopCh = make(chan struct{})
+		informerFactory.Start(stopCh)
+		informerFactory.WaitForCacheSync(stopCh)
+	})
+
+	AfterEach(func() {
+		close(stopCh)
+	})
+
+	updateSecret := func(name string, update func(secret *v1.Secret)) {
+		secret, err := kClient.CoreV1().Secrets(ccmNamespace).Get(ctx, name, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		update(secret)
+		_, err = kClient.CoreV1().Secrets(ccmNamespace).Update(ctx, secret, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	It("should not invalidate the client when the informer starts", func() {
+		Consistently(mockClient.Invalidations).Should(BeZero())
+		Expect(eventRecorder.Events).To(BeEmpty())
+	})
+
+	It("should invalidate the client when the credentials are rotated", func() {
+		updateSecret(credentialsName, func(secret *v1.Secret) {
+			secret.Data["credentials"] = []byte("new")
+		})
+		Eventually(mockClient.Invalidations).Should(Equal(1))
+		Expect(<-eventRecorder.Events).To(Equal("Normal PrismCredentialsRotated Rotated the credentials of Prism Central"))
+	})
+
+	It("should invalidate the client when the credentials are recreated", func() {
+		Expect(kClient.CoreV1().Secrets(ccmNamespace).Delete(ctx, credentialsName, metav1.DeleteOptions{})).To(Succeed())
+		_, err := kClient.CoreV1().Secrets(ccmNamespace).Create(ctx, &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: credentialsName, Namespace: ccmNamespace},
+			Data:       map[string][]byte{"credentials": []byte("new")},
+		}, metav1.CreateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(mockClient.Invalidations).Should(Equal(1))
+	})
+
+	It("should invalidate the client when the trust bundle is rotated", func() {
+		configMap, err := kClient.CoreV1().ConfigMaps(ccmNamespace).Get(ctx, trustBundleName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		configMap.Data["ca.crt"] = "new"
+		_, err = kClient.CoreV1().ConfigMaps(ccmNamespace).Update(ctx, configMap, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(mockClient.Invalidations).Should(Equal(1))
+		Expect(<-eventRecorder.Events).To(Equal("Normal PrismTrustBundleRotated Rotated the trust bundle of Prism Central"))
+	})
+
+	It("should not invalidate the client if the data of the credentials did not change", func() {
+		updateSecret(credentialsName, func(secret *v1.Secret) {
+			secret.Labels = map[string]string{"foo": "bar"}
+		})
+		Consistently(mockClient.Invalidations).Should(BeZero())
+	})
+
+	It("should not invalidate the client when other secrets change", func() {
+		_, err := kClient.CoreV1().Secrets(ccmNamespace).Create(ctx, &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-creds", Namespace: ccmNamespace},
+		}, metav1.CreateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		updateSecret("other-creds", func(secret *v1.Secret) {
+			secret.Data = map[string][]byte{"credentials": []byte("new")}
+		})
+		Consistently(mockClient.Invalidations).Should(BeZero())
+	})
+
+	Context("with the secret kind of the credentialRef in lowercase", func() {
+		BeforeEach(func() {
+			credentialKind = "secret"
+		})
+
+		It("should invalidate the client when the credentials are rotated", func() {
+			updateSecret(credentialsName, func(secret *v1.Secret) {
+				secret.Data["credentials"] = []byte("new")
+			})
+			Eventually(mockClient.Invalidations).Should(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,288 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+
+	networkingCommonModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/common/v1/config"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+// floatingIPManager exposes Services through Flow Virtual Networking floating IPs.
+// Floating IPs are named after the Service UID so they can be found again after restarts.
+type floatingIPManager struct {
+	nutanixManager *nutanixManager
+	config         config.FloatingIPConfig
+
+	mu sync.Mutex
+}
+
+func newFloatingIPManager(nutanixManager *nutanixManager, fipConfig config.FloatingIPConfig) *floatingIPManager {
+	return &floatingIPManager{
+		nutanixManager: nutanixManager,
+		config:         fipConfig,
+	}
+}
+
+// Get returns the floating IP of the service, if any.
+func (f *floatingIPManager) Get(ctx context.Context, service *v1.Service) (netip.Addr, bool, error) {
+	if err := validateServiceForAllocation(service); err != nil {
+		return netip.Addr{}, false, err
+	}
+	nClient, err := f.nutanixManager.nutanixClient.Get()
+	if err != nil {
+		return netip.Addr{}, false, err
+	}
+	fip, err := f.get(ctx, nClient, service)
+	if err != nil || fip == nil {
+		return netip.Addr{}, false, err
+	}
+	address, err := floatingIPAddress(fip)
+	if err != nil {
+		return netip.Addr{}, false, err
+	}
+	return address, true, nil
+}
+
+// Ensure creates the floating IP of the service if needed and associates it with the
+// VIP of the service, or with the VPC NIC of one of the nodes when no VIP is used.
+func (f *floatingIPManager) Ensure(ctx context.Context, service *v1.Service, vip netip.Addr, nodes []*v1.Node) (netip.Addr, error) {
+	if err := validateServiceForAllocation(service); err != nil {
+		return netip.Addr{}, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	nClient, err := f.nutanixManager.nutanixClient.Get()
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	fip, err := f.get(ctx, nClient, service)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	association, err := f.association(ctx, fip, vip, nodes)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("failed to determine floating IP association for service %s/%s: %w", service.Namespace, service.Name, err)
+	}
+
+	switch {
+	case fip == nil:
+		if err := f.validateVPC(ctx, nClient); err != nil {
+			return netip.Addr{}, err
+		}
+		fip = networkingModels.NewFloatingIp()
+		fip.Name = ptr.To(vipClientContext(service))
+		fip.Description = ptr.To(fmt.Sprintf("Kubernetes Service %s/%s", service.Namespace, service.Name))
+		fip.ExternalSubnetReference = ptr.To(f.config.ExternalSubnetUUID)
+		if err := fip.SetAssociation(association); err != nil {
+			return netip.Addr{}, err
+		}
+		if err := nClient.CreateFloatingIP(ctx, fip); err != nil {
+			return netip.Addr{}, fmt.Errorf("failed to create floating IP in subnet %s for service %s/%s: %w", f.config.ExternalSubnetUUID, service.Namespace, service.Name, err)
+		}
+		fip, err = f.get(ctx, nClient, service)
+		if err != nil {
+			return netip.Addr{}, err
+		}
+		if fip == nil {
+			return netip.Addr{}, fmt.Errorf("created floating IP for service %s/%s not found", service.Namespace, service.Name)
+		}
+		klog.Infof("created floating IP %s for service %s/%s", ptr.Deref(fip.ExtId, ""), service.Namespace, service.Name) //nolint:typecheck
+	case !associationMatches(fip.GetAssociation(), association):
+		if err := fip.SetAssociation(association); err != nil {
+			return netip.Addr{}, err
+		}
+		if err := nClient.UpdateFloatingIP(ctx, fip); err != nil {
+			return netip.Addr{}, fmt.Errorf("failed to update association of floating IP %s for service %s/%s: %w", ptr.Deref(fip.ExtId, ""), service.Namespace, service.Name, err)
+		}
+		klog.Infof("updated association of floating IP %s for service %s/%s", ptr.Deref(fip.ExtId, ""), service.Namespace, service.Name) //nolint:typecheck
+	}
+	return floatingIPAddress(fip)
+}
+
+// Release deletes the floating IP of the service.
+func (f *floatingIPManager) Release(ctx context.Context, service *v1.Service) error {
+	if err := validateServiceForAllocation(service); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	nClient, err := f.nutanixManager.nutanixClient.Get()
+	if err != nil {
+		return err
+	}
+	fips, err := nClient.ListFloatingIPs(ctx, floatingIPFilter(service))
+	if err != nil {
+		return err
+	}
+	for _, fip := range fips {
+		if fip.ExtId == nil {
+			continue
+		}
+		if err := nClient.DeleteFloatingIP(ctx, *fip.ExtId); err != nil {
+			return fmt.Errorf("failed to delete floating IP %s for service %s/%s: %w", *fip.ExtId, service.Namespace, service.Name, err)
+		}
+		klog.Infof("deleted floating IP %s for service %s/%s", *fip.ExtId, service.Namespace, service.Name) //nolint:typecheck
+	}
+	return nil
+}
+
+func (f *floatingIPManager) get(ctx context.Context, nClient interfaces.Prism, service *v1.Service) (*networkingModels.FloatingIp, error) {
+	fips, err := nClient.ListFloatingIPs(ctx, floatingIPFilter(service))
+	if err != nil {
+		return nil, err
+	}
+	if len(fips) == 0 {
+		return nil, nil
+	}
+	if len(fips) > 1 {
+		klog.Warningf("found %d floating IPs for service %s/%s, using %s", len(fips), service.Namespace, service.Name, ptr.Deref(fips[0].ExtId, "")) //nolint:typecheck
+	}
+	return &fips[0], nil
+}
+
+// validateVPC checks that the VPC is connected to the external subnet floating IPs are allocated from.
+func (f *floatingIPManager) validateVPC(ctx context.Context, nClient interfaces.Prism) error {
+	vpc, err := nClient.GetVPC(ctx, f.config.VPCUUID)
+	if err != nil {
+		return fmt.Errorf("failed to get VPC %s: %w", f.config.VPCUUID, err)
+	}
+	for _, externalSubnet := range vpc.ExternalSubnets {
+		if ptr.Deref(externalSubnet.SubnetReference, "") == f.config.ExternalSubnetUUID {
+			return nil
+		}
+	}
+	return fmt.Errorf("VPC %s is not connected to external subnet %s", f.config.VPCUUID, f.config.ExternalSubnetUUID)
+}
+
+func (f *floatingIPManager) association(ctx context.Context, current *networkingModels.FloatingIp, vip netip.Addr, nodes []*v1.Node) (interface{}, error) {
+	if f.config.Association == config.NodeNICFloatingIPAssociationType {
+		return f.nodeNICAssociation(ctx, current, nodes)
+	}
+	if !vip.Is4() {
+		return nil, fmt.Errorf("VIP %s is not an IPv4 address", vip)
+	}
+	association := networkingModels.NewPrivateIpAssociation()
+	association.PrivateIp = ptr.To(ipv4Address(vip))
+	association.VpcReference = ptr.To(f.config.VPCUUID)
+	return *association, nil
+}
+
+// nodeNICAssociation keeps the current NIC association while its node is still a load balancer
+// member and otherwise associates the floating IP with the first VPC NIC of the nodes, ordered by name.
+// The NICs of each node are read from the Prism Central of its VM.
+func (f *floatingIPManager) nodeNICAssociation(ctx context.Context, current *networkingModels.FloatingIp, nodes []*v1.Node) (interface{}, error) {
+	currentNIC := ""
+	if current != nil {
+		if association, ok := current.GetAssociation().(networkingModels.VmNicAssociation); ok {
+			currentNIC = ptr.Deref(association.VmNicReference, "")
+		}
+	}
+
+	sortedNodes := slices.Clone(nodes)
+	slices.SortFunc(sortedNodes, func(a, b *v1.Node) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	subnetVPCs := make(map[string]string)
+	selectedNIC := ""
+	for _, node := range sortedNodes {
+		nics, err := f.nutanixManager.getNodeVPCNics(ctx, node, f.config.VPCUUID, subnetVPCs)
+		if err != nil {
+			klog.Warningf("skipping node %s for floating IP association: %v", node.Name, err) //nolint:typecheck
+			continue
+		}
+		for _, nic := range nics {
+			nicUUID := ptr.Deref(nic.ExtId, "")
+			if nicUUID == "" {
+				continue
+			}
+			if nicUUID == currentNIC {
+				return f.vmNICAssociation(nicUUID), nil
+			}
+			if selectedNIC == "" {
+				selectedNIC = nicUUID
+			}
+		}
+		if selectedNIC != "" && currentNIC == "" {
+			break
+		}
+	}
+	if selectedNIC == "" {
+		return nil, fmt.Errorf("no node has a NIC in VPC %s", f.config.VPCUUID)
+	}
+	return f.vmNICAssociation(selectedNIC), nil
+}
+
+func (f *floatingIPManager) vmNICAssociation(nicUUID string) networkingModels.VmNicAssociation {
+	association := networkingModels.NewVmNicAssociation()
+	association.VmNicReference = ptr.To(nicUUID)
+	association.VpcReference = ptr.To(f.config.VPCUUID)
+	return *association
+}
+
+func floatingIPFilter(service *v1.Service) string {
+	return fmt.Sprintf("name eq '%s'", vipClientContext(service))
+}
+
+func floatingIPAddress(fip *networkingModels.FloatingIp) (netip.Addr, error) {
+	if fip.FloatingIp == nil || fip.FloatingIp.Ipv4 == nil || fip.FloatingIp.Ipv4.Value == nil {
+		return netip.Addr{}, fmt.Errorf("floating IP %s has no IPv4 address", ptr.Deref(fip.ExtId, ""))
+	}
+	address, err := netip.ParseAddr(*fip.FloatingIp.Ipv4.Value)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("failed to parse address of floating IP %s: %w", ptr.Deref(fip.ExtId, ""), err)
+	}
+	return address, nil
+}
+
+func associationMatches(current, desired interface{}) bool {
+	switch desired := desired.(type) {
+	case networkingModels.PrivateIpAssociation:
+		current, ok := current.(networkingModels.PrivateIpAssociation)
+		return ok &&
+			ptr.Deref(current.VpcReference, "") == ptr.Deref(desired.VpcReference, "") &&
+			ipv4AddressValue(current.PrivateIp) == ipv4AddressValue(desired.PrivateIp)
+	case networkingModels.VmNicAssociation:
+		current, ok := current.(networkingModels.VmNicAssociation)
+		return ok && ptr.Deref(current.VmNicReference, "") == ptr.Deref(desired.VmNicReference, "")
+	}
+	return false
+}
+
+func ipv4AddressValue(address *networkingCommonModels.IPAddress) string {
+	if address == nil || address.Ipv4 == nil {
+		return ""
+	}
+	return ptr.Deref(address.Ipv4.Value, "")
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nutanix-cloud-native/prism-go-client/converged"
+	clusterModels "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/models/clustermgmt/v4/config"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/cloud-provider/node/helpers"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+)
+
+const (
+	hostInMaintenanceReason    = "HostInMaintenance"
+	hostNotInMaintenanceReason = "HostNotInMaintenance"
+)
+
+// hostMaintenanceController periodically taints the initialized nodes whose VM runs on an AHV
+// host in maintenance and sets their maintenance condition. The taint is removed and the
+// condition cleared once the host left maintenance, or the VM migrated to another host.
+type hostMaintenanceController struct {
+	manager     *nutanixManager
+	nodeLister  corelisters.NodeLister
+	nodesSynced cache.InformerSynced
+	config      *config.HostMaintenanceConfig
+}
+
+func newHostMaintenanceController(manager *nutanixManager, nodeInformer coreinformers.NodeInformer, hostMaintenance *config.HostMaintenanceConfig) *hostMaintenanceController {
+	return &hostMaintenanceController{
+		manager:     manager,
+		nodeLister:  nodeInformer.Lister(),
+		nodesSynced: nodeInformer.Informer().HasSynced,
+		config:      hostMaintenance,
+	}
+}
+
+// run reconciles the nodes every period until stopCh is closed.
+func (c *hostMaintenanceController) run(stopCh <-chan struct{}) {
+	if !cache.WaitForCacheSync(stopCh, c.nodesSynced) {
+		klog.Error("failed to sync the node informer cache of the host maintenance controller") //nolint:typecheck
+		return
+	}
+	klog.Infof("Starting the host maintenance controller with period %s", c.config.Period.Duration) //nolint:typecheck
+	ctx := wait.ContextForChannel(stopCh)
+	wait.Until(func() { c.reconcileNodes(ctx) }, c.config.Period.Duration, stopCh)
+}
+
+func (c *hostMaintenanceController) reconcileNodes(ctx context.Context) {
+	nodes, err := c.nodeLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list nodes: %v", err) //nolint:typecheck
+		return
+	}
+	for _, node := range nodes {
+		if err := c.reconcileNode(ctx, node); err != nil {
+			klog.Errorf("failed to reconcile host maintenance of node %s: %v", node.Name, err) //nolint:typecheck
+		}
+	}
+}
+
+// reconcileNode taints the node and sets its maintenance condition if the AHV host of its VM is
+// in maintenance, and reverts both otherwise. Nodes that are not initialized yet and nodes whose
+// VM does not exist are skipped.
+func (c *hostMaintenanceController) reconcileNode(ctx context.Context, node *v1.Node) error {
+	if hasCloudTaint(node) {
+		return nil
+	}
+
+	nodeVM, err := c.manager.current().getNodeVM(ctx, node)
+	if err != nil {
+		if converged.IsNotFound(err) {
+			klog.V(1).Infof("skipping node %s: VM not found", node.Name) //nolint:typecheck
+			return nil
+		}
+		return err
+	}
+	nClient, vm := nodeVM.nClient, nodeVM.vm
+
+	var host *clusterModels.Host
+	if vm.Cluster != nil && vm.Cluster.ExtId != nil && vm.Host != nil && vm.Host.ExtId != nil {
+		if host, err = nClient.GetClusterHost(ctx, *vm.Cluster.ExtId, *vm.Host.ExtId); err != nil {
+			return err
+		}
+	}
+
+	state, inMaintenance := hostMaintenanceState(host, c.config.States)
+	if inMaintenance {
+		return c.setHostInMaintenance(ctx, node, host, state)
+	}
+	return c.setHostNotInMaintenance(ctx, node)
+}
+
+func (c *hostMaintenanceController) setHostInMaintenance(ctx context.Context, node *v1.Node, host *clusterModels.Host, state string) error {
+	message := fmt.Sprintf("AHV host %s is in maintenance state %s", hostDisplayName(host), state)
+	taint := *c.config.Taint
+	if !slices.ContainsFunc(node.Spec.Taints, func(t v1.Taint) bool { return t.MatchTaint(&taint) && t.Value == taint.Value }) {
+		if taint.Effect == v1.TaintEffectNoExecute {
+			now := metav1.Now()
+			taint.TimeAdded = &now
+		}
+		klog.Infof("tainting node %s: %s", node.Name, message) //nolint:typecheck
+		if err := helpers.AddOrUpdateTaintOnNode(c.manager.client, node.Name, &taint); err != nil {
+			return err
+		}
+	}
+	return c.setCondition(ctx, node, v1.ConditionTrue, hostInMaintenanceReason, message)
+}
+
+func (c *hostMaintenanceController) setHostNotInMaintenance(ctx context.Context, node *v1.Node) error {
+	if err := helpers.RemoveTaintOffNode(c.manager.client, node.Name, node, c.config.Taint); err != nil {
+		return err
+	}
+
+	// The condition is only reported by the nodes that have been on a host in maintenance
+	if _, ok := getNodeCondition(node, c.config.ConditionType); !ok {
+		return nil
+	}
+	return c.setCondition(ctx, node, v1.ConditionFalse, hostNotInMaintenanceReason, "AHV host is not in maintenance")
+}
+
+// setCondition sets the maintenance condition of the node, unless it is already up to date.
+func (c *hostMaintenanceController) setCondition(ctx context.Context, node *v1.Node, status v1.ConditionStatus, reason string, message string) error {
+	now := metav1.Now()
+	condition := v1.NodeCondition{
+		Type:               c.config.ConditionType,
+		Status:             status,
+		LastHeartbeatTime:  now,
+		LastTransitionTime: now,
+		Reason:             reason,
+		Message:            message,
+	}
+	if current, ok := getNodeCondition(node, c.config.ConditionType); ok {
+		if current.Status == status && current.Reason == reason && current.Message == message {
+			return nil
+		}
+		if current.Status == status {
+			condition.LastTransitionTime = current.LastTransitionTime
+		}
+	}
+
+	patch, err := json.Marshal(map[string]any{
+		"status": map[string]any{
+			"conditions": []v1.NodeCondition{condition},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := c.manager.client.CoreV1().Nodes().PatchStatus(ctx, node.Name, patch); err != nil {
+		return fmt.Errorf("failed to set condition %s of node %s: %w", c.config.ConditionType, node.Name, err)
+	}
+	return nil
+}
+
+// hostMaintenanceState returns the hypervisor or maintenance state of the AHV host matching one
+// of the states, if any.
+func hostMaintenanceState(host *clusterModels.Host, states []string) (string, bool) {
+	if host == nil {
+		return "", false
+	}
+	var hostStates []string
+	if host.Hypervisor != nil && host.Hypervisor.State != nil {
+		hostStates = append(hostStates, host.Hypervisor.State.GetName())
+	}
+	if host.MaintenanceState != nil {
+		hostStates = append(hostStates, *host.MaintenanceState)
+	}
+	for _, hostState := range hostStates {
+		for _, state := range states {
+			if strings.EqualFold(hostState, state) {
+				return hostState, true
+			}
+		}
+	}
+	return "", false
+}
+
+// hostDisplayName returns the name of the AHV host, or its UUID if it has no name.
+func hostDisplayName(host *clusterModels.Host) string {
+	if host.HostName != nil && *host.HostName != "" {
+		return *host.HostName
+	}
+	return ptr.Deref(host.ExtId, "")
+}
+
+func getNodeCondition(node *v1.Node, conditionType v1.NodeConditionType) (v1.NodeCondition, bool) {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == conditionType {
+			return condition, true
+		}
+	}
+	return v1.NodeCondition{}, false
+}
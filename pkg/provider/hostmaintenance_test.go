@@ -0,0 +1,173 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:typecheck // Test file uses ginkgo/gomega which typecheck doesn't understand well
+package provider
+
+import (
+	"context"
+	"encoding/json"
+
+	credentialTypes "github.com/nutanix-cloud-native/prism-go-client/environment/credentials"
+	clusterModels "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/models/clustermgmt/v4/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	cloudproviderapi "k8s.io/cloud-provider/api"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+)
+
+var _ = Describe("Test Host Maintenance Controller", func() { // nolint:typecheck
+	var (
+		ctx             context.Context
+		kClient         *fake.Clientset
+		mockEnvironment *mock.MockEnvironment
+		hostMaintenance *config.HostMaintenanceConfig
+		c               *hostMaintenanceController
+	)
+
+	getNode := func(nodeName string) *v1.Node {
+		node, err := kClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		return node
+	}
+
+	BeforeEach(func() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		kClient = fake.NewSimpleClientset()
+		var err error
+		mockEnvironment, err = mock.CreateMockEnvironment(ctx, kClient)
+		Expect(err).ToNot(HaveOccurred())
+		hostMaintenance = &config.HostMaintenanceConfig{}
+	})
+
+	JustBeforeEach(func() {
+		cBytes, err := json.Marshal(config.Config{
+			PrismCentral:    credentialTypes.NutanixPrismEndpoint{Address: "pc.example.com"},
+			HostMaintenance: hostMaintenance,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		cfg, err := config.NewConfigFromBytes(cBytes)
+		Expect(err).ToNot(HaveOccurred())
+		m, err := newNutanixManager(cfg)
+		Expect(err).ToNot(HaveOccurred())
+		m.client = kClient
+		m.nutanixClient = mock.CreateMockClient(*mockEnvironment)
+
+		informerFactory := informers.NewSharedInformerFactory(kClient, 0)
+		c = newHostMaintenanceController(m, informerFactory.Core().V1().Nodes(), cfg.HostMaintenance)
+		informerFactory.Start(ctx.Done())
+		informerFactory.WaitForCacheSync(ctx.Done())
+	})
+
+	It("should taint the nodes on a host in maintenance", func() {
+		mockEnvironment.SetHostMaintenanceState(mock.MockHostUUID, clusterModels.HYPERVISORSTATE_ENTERING_MAINTENANCE_MODE)
+		Expect(c.reconcileNode(ctx, getNode(mock.MockVMNamePoweredOn))).To(Succeed())
+
+		node := getNode(mock.MockVMNamePoweredOn)
+		Expect(node.Spec.Taints).To(ConsistOf(v1.Taint{Key: config.DefaultHostMaintenanceTaintKey, Effect: v1.TaintEffectNoSchedule}))
+		condition, ok := getNodeCondition(node, config.DefaultHostMaintenanceConditionType)
+		Expect(ok).To(BeTrue())
+		Expect(condition.Status).To(Equal(v1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(hostInMaintenanceReason))
+		Expect(condition.Message).To(ContainSubstring("ENTERING_MAINTENANCE_MODE"))
+	})
+
+	It("should not taint the nodes on a host that is not in maintenance", func() {
+		mockEnvironment.SetHostMaintenanceState(mock.MockHostUUID, clusterModels.HYPERVISORSTATE_ENTERED_MAINTENANCE_MODE)
+		Expect(c.reconcileNode(ctx, getNode(mock.MockVMNameHostCategories))).To(Succeed())
+		Expect(c.reconcileNode(ctx, getNode(mock.MockVMNamePoweredOff))).To(Succeed())
+
+		for _, nodeName := range []string{mock.MockVMNameHostCategories, mock.MockVMNamePoweredOff} {
+			node := getNode(nodeName)
+			Expect(node.Spec.Taints).To(BeEmpty())
+			Expect(node.Status.Conditions).To(BeEmpty())
+		}
+	})
+
+	It("should remove the taint once the host left maintenance", func() {
+		mockEnvironment.SetHostMaintenanceState(mock.MockHostUUID, clusterModels.HYPERVISORSTATE_ENTERED_MAINTENANCE_MODE)
+		Expect(c.reconcileNode(ctx, getNode(mock.MockVMNamePoweredOn))).To(Succeed())
+
+		mockEnvironment.SetHostMaintenanceState(mock.MockHostUUID, clusterModels.HYPERVISORSTATE_ACROPOLIS_NORMAL)
+		Expect(c.reconcileNode(ctx, getNode(mock.MockVMNamePoweredOn))).To(Succeed())
+
+		node := getNode(mock.MockVMNamePoweredOn)
+		Expect(node.Spec.Taints).To(BeEmpty())
+		condition, ok := getNodeCondition(node, config.DefaultHostMaintenanceConditionType)
+		Expect(ok).To(BeTrue())
+		Expect(condition.Status).To(Equal(v1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(hostNotInMaintenanceReason))
+	})
+
+	It("should skip nodes that are not initialized", func() {
+		mockEnvironment.SetHostMaintenanceState(mock.MockHostUUID, clusterModels.HYPERVISORSTATE_ENTERED_MAINTENANCE_MODE)
+		node := getNode(mock.MockVMNamePoweredOn)
+		node.Spec.Taints = []v1.Taint{{Key: cloudproviderapi.TaintExternalCloudProvider, Effect: v1.TaintEffectNoSchedule}}
+		Expect(c.reconcileNode(ctx, node)).To(Succeed())
+		Expect(getNode(mock.MockVMNamePoweredOn).Spec.Taints).To(BeEmpty())
+	})
+
+	It("should skip nodes whose VM does not exist", func() {
+		Expect(c.reconcileNode(ctx, mockEnvironment.GetNode(mock.MockNodeNameVMNotExisting))).To(Succeed())
+	})
+
+	Context("Test configured maintenance", func() {
+		BeforeEach(func() {
+			hostMaintenance.States = []string{"reserved_for_ha_failover"}
+			hostMaintenance.Taint = &v1.Taint{Key: "example.com/maintenance", Value: "true", Effect: v1.TaintEffectNoExecute}
+			hostMaintenance.ConditionType = "NutanixHostMaintenance"
+		})
+
+		It("should apply the configured taint and condition", func() {
+			mockEnvironment.SetHostMaintenanceState(mock.MockHostUUID, clusterModels.HYPERVISORSTATE_RESERVED_FOR_HA_FAILOVER)
+			Expect(c.reconcileNode(ctx, getNode(mock.MockVMNamePoweredOn))).To(Succeed())
+
+			node := getNode(mock.MockVMNamePoweredOn)
+			Expect(node.Spec.Taints).To(HaveLen(1))
+			Expect(node.Spec.Taints[0].MatchTaint(hostMaintenance.Taint)).To(BeTrue())
+			Expect(node.Spec.Taints[0].Value).To(Equal("true"))
+			Expect(node.Spec.Taints[0].TimeAdded).ToNot(BeNil())
+			_, ok := getNodeCondition(node, "NutanixHostMaintenance")
+			Expect(ok).To(BeTrue())
+		})
+
+		It("should only consider the configured states", func() {
+			mockEnvironment.SetHostMaintenanceState(mock.MockHostUUID, clusterModels.HYPERVISORSTATE_ENTERED_MAINTENANCE_MODE)
+			Expect(c.reconcileNode(ctx, getNode(mock.MockVMNamePoweredOn))).To(Succeed())
+			Expect(getNode(mock.MockVMNamePoweredOn).Spec.Taints).To(BeEmpty())
+		})
+	})
+
+	It("should reconcile all nodes", func() {
+		mockEnvironment.SetHostMaintenanceState(mock.MockHostUUID, clusterModels.HYPERVISORSTATE_ENTERED_MAINTENANCE_MODE)
+		Eventually(func() ([]*v1.Node, error) {
+			return c.nodeLister.List(labels.Everything())
+		}).ShouldNot(BeEmpty())
+		c.reconcileNodes(ctx)
+		Expect(getNode(mock.MockVMNamePoweredOn).Spec.Taints).To(HaveLen(1))
+		Expect(getNode(mock.MockVMNameCategories).Spec.Taints).To(HaveLen(1))
+		Expect(getNode(mock.MockVMNameHostCategories).Spec.Taints).To(BeEmpty())
+	})
+})
@@ -35,7 +35,7 @@ func newInstancesV2(nutanixManager *nutanixManager) cloudprovider.InstancesV2 {
 }
 
 func (i *instancesV2) InstanceExists(ctx context.Context, node *v1.Node) (bool, error) {
-	ok, err := i.nutanixManager.nodeExists(ctx, node)
+	ok, err := i.nutanixManager.current().nodeExists(ctx, node)
 	if err != nil {
 		return ok, err
 	}
@@ -44,7 +44,7 @@ func (i *instancesV2) InstanceExists(ctx context.Context, node *v1.Node) (bool,
 }
 
 func (i *instancesV2) InstanceShutdown(ctx context.Context, node *v1.Node) (bool, error) {
-	ok, err := i.nutanixManager.isNodeShutdown(ctx, node)
+	ok, err := i.nutanixManager.current().isNodeShutdown(ctx, node)
 	if err != nil {
 		return ok, err
 	}
@@ -53,7 +53,7 @@ func (i *instancesV2) InstanceShutdown(ctx context.Context, node *v1.Node) (bool
 }
 
 func (i *instancesV2) InstanceMetadata(ctx context.Context, node *v1.Node) (*cloudprovider.InstanceMetadata, error) {
-	md, err := i.nutanixManager.getInstanceMetadata(ctx, node)
+	md, err := i.nutanixManager.current().getInstanceMetadata(ctx, node)
 	if err != nil {
 		return md, err
 	}
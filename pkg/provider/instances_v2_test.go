@@ -21,13 +21,17 @@ import (
 	"context"
 
 	clusterModels "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/models/clustermgmt/v4/config"
+	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"go4.org/netipx"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
 
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
@@ -75,6 +79,10 @@ var _ = Describe("Test InstancesV2", func() { // nolint:typecheck
 				client:         kClient,
 				nutanixClient:  mock.CreateMockClient(*mockEnvironment),
 				ignoredNodeIPs: &netipx.IPSet{},
+				shutdownPowerStates: []vmmModels.PowerState{
+					vmmModels.POWERSTATE_OFF,
+					vmmModels.POWERSTATE_PAUSED,
+				},
 			},
 		}
 	})
@@ -121,6 +129,14 @@ var _ = Describe("Test InstancesV2", func() { // nolint:typecheck
 			Expect(s).To(BeFalse())
 		})
 
+		It("should detect if VM is paused", func() {
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+			vm.PowerState = vmmModels.POWERSTATE_PAUSED.Ref()
+			s, err := i.InstanceShutdown(ctx, mockEnvironment.GetNode(mock.MockVMNamePoweredOn))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(s).To(BeTrue())
+		})
+
 		It("should error when system UUID is not set for node", func() {
 			node := mockEnvironment.GetNode(mock.MockNodeNameNoSystemUUID)
 			Expect(node).ToNot(BeNil())
@@ -287,6 +303,58 @@ var _ = Describe("Test InstancesV2", func() { // nolint:typecheck
 			Expect(err).ToNot(HaveOccurred())
 			Expect(updatedNode.Labels).ToNot(HaveKey(constants.MetroNodeGroupLabel))
 		})
+
+		It("should report the instance type mapped in instanceTypeNames", func() {
+			node := mockEnvironment.GetNode(mock.MockVMNamePoweredOn)
+			i.nutanixManager.config.InstanceTypeNames = map[string]string{mock.MockInstanceType: "general-purpose-medium"}
+			metadata, err := i.InstanceMetadata(ctx, node)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(metadata.InstanceType).To(Equal("general-purpose-medium"))
+		})
+
+		It("should report the first instance type of the catalog matching the VM", func() {
+			node := mockEnvironment.GetNode(mock.MockVMNameCategories)
+			i.nutanixManager.config.InstanceTypes = []config.InstanceTypeConfig{
+				{Name: "gpu", GPU: ptr.To(true)},
+				{Name: "regional", Categories: map[string]string{mock.MockDefaultRegion: mock.MockRegion}},
+				{Name: "medium", MinVCPUs: 4},
+			}
+			i.nutanixManager.config.InstanceTypeNames = map[string]string{mock.MockInstanceType: "general-purpose-medium"}
+			metadata, err := i.InstanceMetadata(ctx, node)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(metadata.InstanceType).To(Equal("regional"))
+		})
+
+		It("should report the derived instance type if no instance type of the catalog matches the VM", func() {
+			node := mockEnvironment.GetNode(mock.MockVMNamePoweredOn)
+			i.nutanixManager.config.InstanceTypes = []config.InstanceTypeConfig{
+				{Name: "regional", Categories: map[string]string{mock.MockDefaultRegion: mock.MockRegion}},
+				{Name: "large", MinMemory: ptr.To(resource.MustParse("32Gi"))},
+			}
+			metadata, err := i.InstanceMetadata(ctx, node)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(metadata.InstanceType).To(Equal(mock.MockInstanceType))
+		})
+
+		It("should update the instance type labels of initialized nodes when the VM is resized", func() {
+			node := mockEnvironment.GetNode(mock.MockVMNamePoweredOn)
+			node.Labels = map[string]string{
+				v1.LabelInstanceType:       mock.MockInstanceType,
+				v1.LabelInstanceTypeStable: mock.MockInstanceType,
+			}
+			node, err = kClient.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+			vm.MemorySizeBytes = ptr.To(int64(16 << 30))
+			metadata, err := i.InstanceMetadata(ctx, node)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(metadata.InstanceType).To(Equal("ahv-4c-16g"))
+			updatedNode, err := kClient.CoreV1().Nodes().Get(ctx, node.Name, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updatedNode.Labels).To(HaveKeyWithValue(v1.LabelInstanceType, "ahv-4c-16g"))
+			Expect(updatedNode.Labels).To(HaveKeyWithValue(v1.LabelInstanceTypeStable, "ahv-4c-16g"))
+		})
 	})
 
 	Context("Test NewInstancesV2", func() {
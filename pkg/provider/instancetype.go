@@ -0,0 +1,160 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/cloud-provider/node/helpers"
+	"k8s.io/klog/v2"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+const (
+	mebibyte = 1 << 20
+	gibibyte = 1 << 30
+)
+
+// getInstanceType returns the first instance type of the instanceTypes catalog matching the VM.
+// Otherwise, it returns the instance type derived from the VM resources, or the name it is
+// mapped to in instanceTypeNames.
+func (n *nutanixManager) getInstanceType(ctx context.Context, nClient interfaces.Prism, vm *vmmModels.Vm) (string, error) {
+	var vmCategories map[string][]string
+	for _, instanceType := range n.config.InstanceTypes {
+		if len(instanceType.Categories) > 0 && vmCategories == nil {
+			var err error
+			if vmCategories, err = n.getVMCategories(ctx, nClient, vm); err != nil {
+				return "", err
+			}
+		}
+		if matchesInstanceType(instanceType, vm, vmCategories) {
+			return instanceType.Name, nil
+		}
+	}
+
+	derived := instanceTypeFromVM(vm)
+	if name, ok := n.config.InstanceTypeNames[derived]; ok {
+		return name, nil
+	}
+	return derived, nil
+}
+
+// matchesInstanceType returns true if the VM matches all of the set rules of the instance type.
+// Resource rules do not match VMs whose resources are not known.
+func matchesInstanceType(instanceType config.InstanceTypeConfig, vm *vmmModels.Vm, vmCategories map[string][]string) bool {
+	if vm == nil {
+		return false
+	}
+	if instanceType.MinVCPUs > 0 || instanceType.MaxVCPUs > 0 {
+		vCPUs := vmVCPUs(vm)
+		if vCPUs == 0 || vCPUs < instanceType.MinVCPUs || (instanceType.MaxVCPUs > 0 && vCPUs > instanceType.MaxVCPUs) {
+			return false
+		}
+	}
+	if instanceType.MinMemory != nil || instanceType.MaxMemory != nil {
+		if vm.MemorySizeBytes == nil || *vm.MemorySizeBytes <= 0 {
+			return false
+		}
+		if instanceType.MinMemory != nil && *vm.MemorySizeBytes < instanceType.MinMemory.Value() {
+			return false
+		}
+		if instanceType.MaxMemory != nil && *vm.MemorySizeBytes > instanceType.MaxMemory.Value() {
+			return false
+		}
+	}
+	if instanceType.GPU != nil && *instanceType.GPU != (len(vm.Gpus) > 0) {
+		return false
+	}
+	for key, value := range instanceType.Categories {
+		if !slices.Contains(vmCategories[key], value) {
+			return false
+		}
+	}
+	return true
+}
+
+// vmVCPUs returns the sockets times the cores per socket times the threads per core of the VM,
+// or 0 if the number of sockets is not known.
+func vmVCPUs(vm *vmmModels.Vm) int {
+	if vm.NumSockets == nil || *vm.NumSockets <= 0 {
+		return 0
+	}
+	vCPUs := *vm.NumSockets
+	if vm.NumCoresPerSocket != nil && *vm.NumCoresPerSocket > 0 {
+		vCPUs *= *vm.NumCoresPerSocket
+	}
+	if vm.NumThreadsPerCore != nil && *vm.NumThreadsPerCore > 0 {
+		vCPUs *= *vm.NumThreadsPerCore
+	}
+	return vCPUs
+}
+
+// instanceTypeFromVM derives the instance type from the VM resources as
+// ahv-<vCPUs>c-<memory>g, with the memory in MiB (ahv-<vCPUs>c-<memory>m) if it is not a whole
+// number of GiB, and suffixed with -<GPUs>gpu if GPUs are attached. It returns
+// constants.InstanceType if the VM resources are not known.
+func instanceTypeFromVM(vm *vmmModels.Vm) string {
+	if vm == nil || vm.MemorySizeBytes == nil || *vm.MemorySizeBytes <= 0 {
+		return constants.InstanceType
+	}
+	vCPUs := vmVCPUs(vm)
+	if vCPUs == 0 {
+		return constants.InstanceType
+	}
+
+	memory := fmt.Sprintf("%dm", *vm.MemorySizeBytes/mebibyte)
+	if *vm.MemorySizeBytes%gibibyte == 0 {
+		memory = fmt.Sprintf("%dg", *vm.MemorySizeBytes/gibibyte)
+	}
+
+	instanceType := fmt.Sprintf("ahv-%dc-%s", vCPUs, memory)
+	if len(vm.Gpus) > 0 {
+		instanceType = fmt.Sprintf("%s-%dgpu", instanceType, len(vm.Gpus))
+	}
+	return instanceType
+}
+
+// reconcileInstanceTypeLabels updates the instance type labels of an initialized node when the
+// instance type changed, e.g. after the VM was resized. The cloud node controller only sets
+// them when initializing the node.
+func (n *nutanixManager) reconcileInstanceTypeLabels(node *v1.Node, instanceType string) error {
+	if node == nil {
+		return fmt.Errorf("node cannot be nil when reconciling instance type labels")
+	}
+
+	current, ok := node.Labels[v1.LabelInstanceTypeStable]
+	if !ok || current == instanceType {
+		return nil
+	}
+
+	labels := map[string]string{
+		v1.LabelInstanceType:       instanceType,
+		v1.LabelInstanceTypeStable: instanceType,
+	}
+	if ok := helpers.AddOrUpdateLabelsOnNode(n.client, labels, node); !ok {
+		return fmt.Errorf("error occurred while updating instance type labels on node %s", node.Name)
+	}
+	klog.V(1).Infof("updated instance type of node %s from %s to %s", node.Name, current, instanceType) //nolint:typecheck
+	return nil
+}
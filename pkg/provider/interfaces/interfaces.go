@@ -20,6 +20,7 @@ import (
 	"context"
 
 	clusterModels "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/models/clustermgmt/v4/config"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
 	prismModels "github.com/nutanix/ntnx-api-golang-clients/prism-go-client/v4/models/prism/v4/config"
 	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
 	"k8s.io/client-go/informers"
@@ -28,12 +29,43 @@ import (
 type Client interface {
 	Get() (Prism, error)
 	SetInformers(sharedInformers informers.SharedInformerFactory)
+	// Invalidate drops the cached Prism client, so that the next Get creates one with the
+	// current credentials and trust bundle
+	Invalidate()
 }
 
 type Prism interface {
 	GetVM(ctx context.Context, vmUUID string) (*vmmModels.Vm, error)
+	// ListVMs lists the VMs matching the OData filter
+	ListVMs(ctx context.Context, filter string) ([]vmmModels.Vm, error)
 	GetCluster(ctx context.Context, clusterUUID string) (*clusterModels.Cluster, error)
 	ListAllCluster(ctx context.Context) ([]clusterModels.Cluster, error)
 	GetCategory(ctx context.Context, categoryUUID string) (*prismModels.Category, error)
+	// ListCategories lists the categories matching the OData filter with their detailed associations
+	ListCategories(ctx context.Context, filter string) ([]prismModels.Category, error)
 	GetClusterHost(ctx context.Context, clusterUuid string, hostUUID string) (*clusterModels.Host, error)
+	// ReserveSubnetIPs reserves IPs in the IPAM of a managed subnet and waits for the reservation to complete.
+	ReserveSubnetIPs(ctx context.Context, subnetUUID string, spec *networkingModels.IpReserveSpec) error
+	// UnreserveSubnetIPs releases IPs reserved in the IPAM of a managed subnet and waits for the release to complete.
+	UnreserveSubnetIPs(ctx context.Context, subnetUUID string, spec *networkingModels.IpUnreserveSpec) error
+	ListReservedSubnetIPs(ctx context.Context, subnetUUID string) ([]networkingModels.ReservedIp, error)
+	GetSubnet(ctx context.Context, subnetUUID string) (*networkingModels.Subnet, error)
+	GetVPC(ctx context.Context, vpcUUID string) (*networkingModels.Vpc, error)
+	ListVMNics(ctx context.Context, vmUUID string) ([]vmmModels.Nic, error)
+	// ListFloatingIPs lists the floating IPs matching the OData filter
+	ListFloatingIPs(ctx context.Context, filter string) ([]networkingModels.FloatingIp, error)
+	// CreateFloatingIP creates a floating IP and waits for the creation to complete.
+	CreateFloatingIP(ctx context.Context, floatingIP *networkingModels.FloatingIp) error
+	// UpdateFloatingIP updates the floating IP identified by its ExtId and waits for the update to complete.
+	UpdateFloatingIP(ctx context.Context, floatingIP *networkingModels.FloatingIp) error
+	// DeleteFloatingIP deletes a floating IP and waits for the deletion to complete.
+	DeleteFloatingIP(ctx context.Context, floatingIPUUID string) error
+	// ListRouteTables lists the route tables matching the OData filter
+	ListRouteTables(ctx context.Context, filter string) ([]networkingModels.RouteTable, error)
+	// ListRoutes lists the routes of a route table matching the OData filter
+	ListRoutes(ctx context.Context, routeTableUUID string, filter string) ([]networkingModels.Route, error)
+	// CreateRoute creates a route in a route table and waits for the creation to complete.
+	CreateRoute(ctx context.Context, routeTableUUID string, route *networkingModels.Route) error
+	// DeleteRoute deletes a route from a route table and waits for the deletion to complete.
+	DeleteRoute(ctx context.Context, routeTableUUID string, routeUUID string) error
 }
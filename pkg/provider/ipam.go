@@ -0,0 +1,464 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+
+	networkingCommonModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/common/v1/config"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
+	"go4.org/netipx"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+)
+
+// vipAllocator hands out virtual IPs for Services of type LoadBalancer.
+// All methods are keyed on the Service UID and are idempotent.
+type vipAllocator interface {
+	// Allocate returns the VIP assigned to the service, assigning a new one if needed.
+	Allocate(ctx context.Context, service *v1.Service) (netip.Addr, error)
+	// Get returns the VIP assigned to the service, if any.
+	Get(ctx context.Context, service *v1.Service) (netip.Addr, bool, error)
+	// Release frees the VIP assigned to the service.
+	Release(ctx context.Context, service *v1.Service) error
+}
+
+// poolAllocator allocates VIPs from a static IP pool. Allocations are persisted in a
+// ConfigMap in the CCM namespace, keyed by Service UID, so they survive restarts.
+type poolAllocator struct {
+	nutanixManager *nutanixManager
+	pool           *netipx.IPSet
+
+	// mu serializes allocations made by this process; the ConfigMap resourceVersion
+	// guards against concurrent writers.
+	mu sync.Mutex
+}
+
+func newPoolAllocator(nutanixManager *nutanixManager, ipPools []string) (*poolAllocator, error) {
+	pool, err := parseVIPPool(ipPools)
+	if err != nil {
+		return nil, err
+	}
+	return &poolAllocator{
+		nutanixManager: nutanixManager,
+		pool:           pool,
+	}, nil
+}
+
+// parseVIPPool parses the configured IP pools. The network and broadcast addresses of
+// IPv4 prefixes are excluded since they cannot be used as VIPs.
+func parseVIPPool(ipPools []string) (*netipx.IPSet, error) {
+	pool, err := parseIPSet("loadBalancer.ipPools", ipPools)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := netipx.IPSetBuilder{}
+	builder.AddSet(pool)
+	for _, entry := range ipPools {
+		if !strings.Contains(entry, "/") {
+			continue
+		}
+		// The prefix of the parsed range is masked, and unmapped if it is an IPv4-mapped prefix.
+		ipRange, err := config.ParseIPRange(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse loadBalancer.ipPools %q: %v", entry, err)
+		}
+		prefix, ok := ipRange.Prefix()
+		if ok && prefix.Addr().Is4() && prefix.Bits() < 31 {
+			builder.Remove(prefix.Addr())
+			builder.Remove(netipx.PrefixLastIP(prefix))
+		}
+	}
+	return builder.IPSet()
+}
+
+func (p *poolAllocator) Allocate(ctx context.Context, service *v1.Service) (netip.Addr, error) {
+	if err := validateServiceForAllocation(service); err != nil {
+		return netip.Addr{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var vip netip.Addr
+	err := p.updateAllocations(ctx, func(allocations map[string]string) (bool, error) {
+		key := string(service.UID)
+		requested, err := requestedVIP(service)
+		if err != nil {
+			return false, err
+		}
+
+		if current, ok := allocations[key]; ok {
+			currentIP, err := netip.ParseAddr(current)
+			if err != nil {
+				return false, fmt.Errorf("invalid VIP %q recorded for service %s/%s: %v", current, service.Namespace, service.Name, err)
+			}
+			if !requested.IsValid() || requested == currentIP {
+				vip = currentIP
+				return false, nil
+			}
+			klog.Infof("service %s/%s requested VIP %s, releasing %s", service.Namespace, service.Name, requested, currentIP) //nolint:typecheck
+			delete(allocations, key)
+		}
+
+		inUse := netipx.IPSetBuilder{}
+		for _, ip := range allocations {
+			if addr, err := netip.ParseAddr(ip); err == nil {
+				inUse.Add(addr)
+			}
+		}
+		used, err := inUse.IPSet()
+		if err != nil {
+			return false, err
+		}
+
+		if requested.IsValid() {
+			if !p.pool.Contains(requested) {
+				return false, fmt.Errorf("requested VIP %s for service %s/%s is not part of the load balancer IP pools", requested, service.Namespace, service.Name)
+			}
+			if used.Contains(requested) {
+				return false, fmt.Errorf("requested VIP %s for service %s/%s is already allocated", requested, service.Namespace, service.Name)
+			}
+			vip = requested
+		} else {
+			free, ok := firstFreeIP(p.pool, used, serviceIPFamily(service))
+			if !ok {
+				return false, fmt.Errorf("no free VIP left in the load balancer IP pools for service %s/%s", service.Namespace, service.Name)
+			}
+			vip = free
+		}
+
+		allocations[key] = vip.String()
+		klog.Infof("allocated VIP %s to service %s/%s", vip, service.Namespace, service.Name) //nolint:typecheck
+		return true, nil
+	})
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return vip, nil
+}
+
+func (p *poolAllocator) Get(ctx context.Context, service *v1.Service) (netip.Addr, bool, error) {
+	if err := validateServiceForAllocation(service); err != nil {
+		return netip.Addr{}, false, err
+	}
+
+	cm, err := p.getAllocations(ctx)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return netip.Addr{}, false, nil
+		}
+		return netip.Addr{}, false, err
+	}
+
+	current, ok := cm.Data[string(service.UID)]
+	if !ok {
+		return netip.Addr{}, false, nil
+	}
+	vip, err := netip.ParseAddr(current)
+	if err != nil {
+		return netip.Addr{}, false, fmt.Errorf("invalid VIP %q recorded for service %s/%s: %v", current, service.Namespace, service.Name, err)
+	}
+	return vip, true, nil
+}
+
+func (p *poolAllocator) Release(ctx context.Context, service *v1.Service) error {
+	if err := validateServiceForAllocation(service); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.updateAllocations(ctx, func(allocations map[string]string) (bool, error) {
+		key := string(service.UID)
+		vip, ok := allocations[key]
+		if !ok {
+			return false, nil
+		}
+		delete(allocations, key)
+		klog.Infof("released VIP %s of service %s/%s", vip, service.Namespace, service.Name) //nolint:typecheck
+		return true, nil
+	})
+}
+
+func (p *poolAllocator) getAllocations(ctx context.Context) (*v1.ConfigMap, error) {
+	if p.nutanixManager.client == nil {
+		return nil, fmt.Errorf("kubernetes client is not initialized")
+	}
+	ccmNamespace, err := GetCCMNamespace()
+	if err != nil {
+		return nil, err
+	}
+	return p.nutanixManager.client.CoreV1().ConfigMaps(ccmNamespace).Get(ctx, constants.LoadBalancerAllocationsConfigMapName, metav1.GetOptions{})
+}
+
+// updateAllocations applies mutate to the persisted allocations and writes them back
+// if mutate reports a change. Conflicting writes are retried.
+func (p *poolAllocator) updateAllocations(ctx context.Context, mutate func(allocations map[string]string) (bool, error)) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := p.getAllocations(ctx)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		create := apierrors.IsNotFound(err)
+		if create {
+			ccmNamespace, err := GetCCMNamespace()
+			if err != nil {
+				return err
+			}
+			cm = &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      constants.LoadBalancerAllocationsConfigMapName,
+					Namespace: ccmNamespace,
+				},
+			}
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+
+		changed, err := mutate(cm.Data)
+		if err != nil || !changed {
+			return err
+		}
+
+		configMaps := p.nutanixManager.client.CoreV1().ConfigMaps(cm.Namespace)
+		if create {
+			_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				// Another writer created the ConfigMap first; retry against its contents.
+				return apierrors.NewConflict(v1.Resource("configmaps"), cm.Name, err)
+			}
+			return err
+		}
+		_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// prismAllocator reserves VIPs in the IPAM of a Nutanix managed subnet, so AHV does not
+// hand out the same address to a VM. Each reservation carries the Service UID as its
+// client context, which makes Prism Central the source of truth for allocations.
+type prismAllocator struct {
+	nutanixManager *nutanixManager
+	subnetUUID     string
+
+	// mu prevents concurrent reservations for the same service by this process.
+	mu sync.Mutex
+}
+
+func newPrismAllocator(nutanixManager *nutanixManager, subnetUUID string) *prismAllocator {
+	return &prismAllocator{
+		nutanixManager: nutanixManager,
+		subnetUUID:     subnetUUID,
+	}
+}
+
+func (p *prismAllocator) Allocate(ctx context.Context, service *v1.Service) (netip.Addr, error) {
+	if err := validateServiceForAllocation(service); err != nil {
+		return netip.Addr{}, err
+	}
+	requested, err := requestedVIP(service)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	current, found, err := p.get(ctx, service)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	if found {
+		if !requested.IsValid() || requested == current {
+			return current, nil
+		}
+		klog.Infof("service %s/%s requested VIP %s, releasing %s", service.Namespace, service.Name, requested, current) //nolint:typecheck
+		if err := p.release(ctx, service); err != nil {
+			return netip.Addr{}, err
+		}
+	}
+
+	nClient, err := p.nutanixManager.nutanixClient.Get()
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	spec := networkingModels.NewIpReserveSpec()
+	spec.ClientContext = ptr.To(vipClientContext(service))
+	if requested.IsValid() {
+		if !requested.Is4() {
+			return netip.Addr{}, fmt.Errorf("requested VIP %s for service %s/%s is not an IPv4 address", requested, service.Namespace, service.Name)
+		}
+		spec.ReserveType = networkingModels.RESERVETYPE_IP_ADDRESS_LIST.Ref()
+		spec.IpAddresses = []networkingCommonModels.IPAddress{ipv4Address(requested)}
+	} else {
+		spec.ReserveType = networkingModels.RESERVETYPE_IP_ADDRESS_COUNT.Ref()
+		spec.Count = ptr.To(int64(1))
+	}
+	if err := nClient.ReserveSubnetIPs(ctx, p.subnetUUID, spec); err != nil {
+		return netip.Addr{}, fmt.Errorf("failed to reserve VIP in subnet %s for service %s/%s: %w", p.subnetUUID, service.Namespace, service.Name, err)
+	}
+
+	vip, found, err := p.get(ctx, service)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	if !found {
+		return netip.Addr{}, fmt.Errorf("reserved VIP for service %s/%s not found in subnet %s", service.Namespace, service.Name, p.subnetUUID)
+	}
+	klog.Infof("reserved VIP %s in subnet %s for service %s/%s", vip, p.subnetUUID, service.Namespace, service.Name) //nolint:typecheck
+	return vip, nil
+}
+
+func (p *prismAllocator) Get(ctx context.Context, service *v1.Service) (netip.Addr, bool, error) {
+	if err := validateServiceForAllocation(service); err != nil {
+		return netip.Addr{}, false, err
+	}
+	return p.get(ctx, service)
+}
+
+func (p *prismAllocator) Release(ctx context.Context, service *v1.Service) error {
+	if err := validateServiceForAllocation(service); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, found, err := p.get(ctx, service)
+	if err != nil || !found {
+		return err
+	}
+	return p.release(ctx, service)
+}
+
+func (p *prismAllocator) get(ctx context.Context, service *v1.Service) (netip.Addr, bool, error) {
+	nClient, err := p.nutanixManager.nutanixClient.Get()
+	if err != nil {
+		return netip.Addr{}, false, err
+	}
+	reservedIPs, err := nClient.ListReservedSubnetIPs(ctx, p.subnetUUID)
+	if err != nil {
+		return netip.Addr{}, false, fmt.Errorf("failed to list reserved IPs of subnet %s: %w", p.subnetUUID, err)
+	}
+
+	clientContext := vipClientContext(service)
+	for _, reservedIP := range reservedIPs {
+		if reservedIP.ClientContext == nil || *reservedIP.ClientContext != clientContext || reservedIP.Ipv4Address == nil {
+			continue
+		}
+		vip, err := netip.ParseAddr(*reservedIP.Ipv4Address)
+		if err != nil {
+			return netip.Addr{}, false, fmt.Errorf("invalid VIP %q reserved for service %s/%s: %v", *reservedIP.Ipv4Address, service.Namespace, service.Name, err)
+		}
+		return vip, true, nil
+	}
+	return netip.Addr{}, false, nil
+}
+
+func (p *prismAllocator) release(ctx context.Context, service *v1.Service) error {
+	nClient, err := p.nutanixManager.nutanixClient.Get()
+	if err != nil {
+		return err
+	}
+
+	spec := networkingModels.NewIpUnreserveSpec()
+	spec.UnreserveType = networkingModels.UNRESERVETYPE_CONTEXT.Ref()
+	spec.ClientContext = ptr.To(vipClientContext(service))
+	if err := nClient.UnreserveSubnetIPs(ctx, p.subnetUUID, spec); err != nil {
+		return fmt.Errorf("failed to release VIP in subnet %s for service %s/%s: %w", p.subnetUUID, service.Namespace, service.Name, err)
+	}
+	klog.Infof("released VIP in subnet %s of service %s/%s", p.subnetUUID, service.Namespace, service.Name) //nolint:typecheck
+	return nil
+}
+
+// vipClientContext returns the client context that identifies the VIP reservation of the service.
+func vipClientContext(service *v1.Service) string {
+	return constants.LoadBalancerClientContextPrefix + string(service.UID)
+}
+
+func ipv4Address(ip netip.Addr) networkingCommonModels.IPAddress {
+	address := networkingCommonModels.NewIPAddress()
+	address.Ipv4 = networkingCommonModels.NewIPv4Address()
+	address.Ipv4.Value = ptr.To(ip.String())
+	return *address
+}
+
+func validateServiceForAllocation(service *v1.Service) error {
+	if service == nil {
+		return fmt.Errorf("service cannot be nil when allocating a VIP")
+	}
+	if service.UID == "" {
+		return fmt.Errorf("service %s/%s has no UID", service.Namespace, service.Name)
+	}
+	return nil
+}
+
+// requestedVIP returns the VIP explicitly requested through spec.loadBalancerIP, if any.
+func requestedVIP(service *v1.Service) (netip.Addr, error) {
+	if service.Spec.LoadBalancerIP == "" {
+		return netip.Addr{}, nil
+	}
+	addr, err := netip.ParseAddr(service.Spec.LoadBalancerIP)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("invalid loadBalancerIP %q for service %s/%s: %v", service.Spec.LoadBalancerIP, service.Namespace, service.Name, err)
+	}
+	return addr, nil
+}
+
+// serviceIPFamily returns the primary IP family of the service, or an empty string if
+// it is not set.
+func serviceIPFamily(service *v1.Service) v1.IPFamily {
+	if len(service.Spec.IPFamilies) == 0 {
+		return ""
+	}
+	return service.Spec.IPFamilies[0]
+}
+
+// firstFreeIP returns the lowest address of the pool, matching the IP family if set,
+// that is not part of used.
+func firstFreeIP(pool, used *netipx.IPSet, family v1.IPFamily) (netip.Addr, bool) {
+	for _, ipRange := range pool.Ranges() {
+		if family == v1.IPv4Protocol && !ipRange.From().Is4() {
+			continue
+		}
+		if family == v1.IPv6Protocol && !ipRange.From().Is6() {
+			continue
+		}
+		for ip := ipRange.From(); ip.IsValid() && ip.Compare(ipRange.To()) <= 0; ip = ip.Next() {
+			if !used.Contains(ip) {
+				return ip, true
+			}
+		}
+	}
+	return netip.Addr{}, false
+}
@@ -18,43 +18,150 @@ package provider
 
 import (
 	"context"
+	"fmt"
+	"net/netip"
 
 	v1 "k8s.io/api/core/v1"
+	cloudprovider "k8s.io/cloud-provider"
+	"k8s.io/klog/v2"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
 )
 
-// get the status from the service Info.
-// returning an error - keeps the intent alive
-// bool indicates if an lb was found or not
-func (nc *NtnxCloud) GetLoadBalancer(ct context.Context, clusterName string, service *v1.Service) (
+type loadBalancer struct {
+	nutanixManager *nutanixManager
+	// allocator is nil when floating IPs are associated with node NICs
+	allocator vipAllocator
+	// floatingIPs is nil unless Flow floating IPs are configured
+	floatingIPs *floatingIPManager
+}
+
+func newLoadBalancer(nutanixManager *nutanixManager) (cloudprovider.LoadBalancer, error) {
+	lbConfig := nutanixManager.config.LoadBalancer
+	if lbConfig == nil {
+		return nil, fmt.Errorf("load balancer config cannot be nil when creating the load balancer")
+	}
+
+	lb := &loadBalancer{
+		nutanixManager: nutanixManager,
+	}
+	if lbConfig.FloatingIP != nil {
+		lb.floatingIPs = newFloatingIPManager(nutanixManager, *lbConfig.FloatingIP)
+		if lbConfig.FloatingIP.Association == config.NodeNICFloatingIPAssociationType {
+			return lb, nil
+		}
+	}
+
+	switch lbConfig.IPAM {
+	case config.PrismLoadBalancerIPAMType:
+		lb.allocator = newPrismAllocator(nutanixManager, lbConfig.SubnetUUID)
+	case config.PoolLoadBalancerIPAMType, "":
+		poolAllocator, err := newPoolAllocator(nutanixManager, lbConfig.IPPools)
+		if err != nil {
+			return nil, err
+		}
+		lb.allocator = poolAllocator
+	default:
+		return nil, fmt.Errorf("unsupported load balancer IPAM: %s", lbConfig.IPAM)
+	}
+	return lb, nil
+}
+
+// GetLoadBalancer returns the status of the load balancer of the service.
+// The bool indicates if a VIP, or a floating IP when configured, was allocated for the service.
+func (l *loadBalancer) GetLoadBalancer(ctx context.Context, clusterName string, service *v1.Service) (
 	*v1.LoadBalancerStatus, bool, error,
 ) {
-	return nil, false, nil
+	var vip netip.Addr
+	if l.allocator != nil {
+		var found bool
+		var err error
+		vip, found, err = l.allocator.Get(ctx, service)
+		if err != nil || !found {
+			return nil, false, err
+		}
+	}
+	if l.floatingIPs == nil {
+		return loadBalancerStatus(vip), true, nil
+	}
+
+	fip, found, err := l.floatingIPs.Get(ctx, service)
+	if err != nil || !found {
+		return nil, false, err
+	}
+	return loadBalancerStatus(fip, vip), true, nil
 }
 
 // GetLoadBalancerName returns the name of the load balancer. Implementations must treat the
 // *v1.Service parameter as read-only and not modify it.
-func (nc *NtnxCloud) GetLoadBalancerName(ct context.Context, clusterName string, service *v1.Service) string {
-	return ""
+func (l *loadBalancer) GetLoadBalancerName(ctx context.Context, clusterName string, service *v1.Service) string {
+	return cloudprovider.DefaultLoadBalancerName(service)
 }
 
-// It adds an entry "create" into the internal method call record.
-func (nc *NtnxCloud) EnsureLoadBalancer(ct context.Context,
+// EnsureLoadBalancer allocates a VIP for the service if it does not have one yet
+// and returns it as the load balancer ingress. When floating IPs are configured, the
+// floating IP is reported as the first ingress IP.
+func (l *loadBalancer) EnsureLoadBalancer(ctx context.Context,
 	clusterName string, service *v1.Service, nodes []*v1.Node) (
 	*v1.LoadBalancerStatus, error,
 ) {
-	return nil, nil
+	var vip netip.Addr
+	if l.allocator != nil {
+		var err error
+		vip, err = l.allocator.Allocate(ctx, service)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if l.floatingIPs == nil {
+		klog.V(1).InfoS("EnsureLoadBalancer", "service", klog.KObj(service), "vip", vip) //nolint:typecheck
+		return loadBalancerStatus(vip), nil
+	}
+
+	fip, err := l.floatingIPs.Ensure(ctx, service, vip, nodes)
+	if err != nil {
+		return nil, err
+	}
+	klog.V(1).InfoS("EnsureLoadBalancer", "service", klog.KObj(service), "vip", vip, "floatingIP", fip) //nolint:typecheck
+	return loadBalancerStatus(fip, vip), nil
 }
 
-func (nc *NtnxCloud) UpdateLoadBalancer(ct context.Context,
+// UpdateLoadBalancer moves a floating IP associated with a node NIC to another node when
+// its node left the load balancer. It is a no-op otherwise: the VIP does not depend on the set of nodes.
+func (l *loadBalancer) UpdateLoadBalancer(ctx context.Context,
 	clusterName string, service *v1.Service, nodes []*v1.Node,
 ) error {
-	return nil
+	if l.floatingIPs == nil || l.allocator != nil {
+		return nil
+	}
+	_, err := l.floatingIPs.Ensure(ctx, service, netip.Addr{}, nodes)
+	return err
 }
 
-// EnsureLoadBalancerDeleted is a test-spy implementation of LoadBalancer.EnsureLoadBalancerDeleted.
-// It adds an entry "delete" into the internal method call record.
-func (nc *NtnxCloud) EnsureLoadBalancerDeleted(ct context.Context, clusterName string,
+// EnsureLoadBalancerDeleted releases the floating IP and the VIP allocated for the service.
+func (l *loadBalancer) EnsureLoadBalancerDeleted(ctx context.Context, clusterName string,
 	service *v1.Service,
 ) error {
+	if l.floatingIPs != nil {
+		if err := l.floatingIPs.Release(ctx, service); err != nil {
+			return err
+		}
+	}
+	if l.allocator != nil {
+		if err := l.allocator.Release(ctx, service); err != nil {
+			return err
+		}
+	}
+	klog.V(1).InfoS("EnsureLoadBalancerDeleted", "service", klog.KObj(service)) //nolint:typecheck
 	return nil
 }
+
+func loadBalancerStatus(addresses ...netip.Addr) *v1.LoadBalancerStatus {
+	status := &v1.LoadBalancerStatus{}
+	for _, address := range addresses {
+		if address.IsValid() {
+			status.Ingress = append(status.Ingress, v1.LoadBalancerIngress{IP: address.String()})
+		}
+	}
+	return status
+}
@@ -19,67 +19,414 @@ package provider
 
 import (
 	"context"
+	"os"
 
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	cloudprovider "k8s.io/cloud-provider"
 
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
 )
 
+func newMockService(name string, uid string) *v1.Service {
+	return &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       types.UID(uid),
+		},
+		Spec: v1.ServiceSpec{
+			Type: v1.ServiceTypeLoadBalancer,
+		},
+	}
+}
+
 var _ = Describe("Test Loadbalancer", func() { // nolint:typecheck
+	const ccmNamespace = "ccm-namespace"
+
 	var (
-		ctx       context.Context
-		ntnxCloud NtnxCloud
+		ctx     context.Context
+		kClient *fake.Clientset
+		m       *nutanixManager
+		lb      cloudprovider.LoadBalancer
 	)
 
 	BeforeEach(func() {
 		ctx = context.Background()
-		ntnxCloud = NtnxCloud{}
+		kClient = fake.NewSimpleClientset()
+		Expect(os.Setenv(constants.CCMNamespaceKey, ccmNamespace)).To(Succeed())
+
+		c := mock.GenerateMockConfig()
+		c.LoadBalancer = &config.LoadBalancerConfig{
+			IPPools: []string{"10.0.0.0/30", "10.0.1.10"},
+		}
+		var err error
+		m, err = newNutanixManager(c)
+		Expect(err).ToNot(HaveOccurred())
+		m.client = kClient
+		lb, err = newLoadBalancer(m)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		unsetEnv(constants.CCMNamespaceKey)
+	})
+
+	Context("Test NewLoadBalancer", func() {
+		It("should fail if the IP pools are invalid", func() {
+			m.config.LoadBalancer.IPPools = []string{"not-an-ip"}
+			_, err := newLoadBalancer(m)
+			Expect(err).To(HaveOccurred())
+		})
 	})
 
 	Context("Test GetLoadBalancer", func() {
-		It("should return empty outputs", func() {
-			lbStatus, found, err := ntnxCloud.GetLoadBalancer(ctx, mock.MockCluster, &v1.Service{})
+		It("should not find a load balancer before it is ensured", func() {
+			lbStatus, found, err := lb.GetLoadBalancer(ctx, mock.MockCluster, newMockService("svc", "uid-1"))
 			Expect(lbStatus).To(BeNil())
 			Expect(found).To(BeFalse())
 			Expect(err).ShouldNot(HaveOccurred())
 		})
+
+		It("should return the allocated VIP", func() {
+			svc := newMockService("svc", "uid-1")
+			_, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, svc, []*v1.Node{})
+			Expect(err).ToNot(HaveOccurred())
+
+			lbStatus, found, err := lb.GetLoadBalancer(ctx, mock.MockCluster, svc)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(lbStatus.Ingress).To(ConsistOf(v1.LoadBalancerIngress{IP: "10.0.0.1"}))
+		})
 	})
 
 	Context("Test GetLoadBalancerName", func() {
-		It("should return empty outputs", func() {
-			n := ntnxCloud.GetLoadBalancerName(ctx, mock.MockCluster, &v1.Service{})
-			Expect(n).To(BeEmpty())
+		It("should return the default load balancer name", func() {
+			svc := newMockService("svc", "uid-1")
+			n := lb.GetLoadBalancerName(ctx, mock.MockCluster, svc)
+			Expect(n).To(Equal(cloudprovider.DefaultLoadBalancerName(svc)))
 		})
 	})
 
 	Context("Test EnsureLoadBalancer", func() {
-		It("should not return error", func() {
-			lbStatus, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, &v1.Service{}, []*v1.Node{})
-			Expect(lbStatus).To(BeNil())
+		It("should allocate distinct VIPs and skip network and broadcast addresses", func() {
+			ips := []string{}
+			for _, uid := range []string{"uid-1", "uid-2", "uid-3"} {
+				lbStatus, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, newMockService(uid, uid), []*v1.Node{})
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(lbStatus.Ingress).To(HaveLen(1))
+				ips = append(ips, lbStatus.Ingress[0].IP)
+			}
+			Expect(ips).To(Equal([]string{"10.0.0.1", "10.0.0.2", "10.0.1.10"}))
+
+			_, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, newMockService("svc", "uid-4"), []*v1.Node{})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should be idempotent on the service UID", func() {
+			first, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, newMockService("svc", "uid-1"), []*v1.Node{})
+			Expect(err).ShouldNot(HaveOccurred())
+			second, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, newMockService("svc", "uid-1"), []*v1.Node{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(second).To(Equal(first))
+		})
+
+		It("should persist allocations across restarts", func() {
+			svc := newMockService("svc", "uid-1")
+			first, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, svc, []*v1.Node{})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			cm, err := kClient.CoreV1().ConfigMaps(ccmNamespace).Get(ctx, constants.LoadBalancerAllocationsConfigMapName, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cm.Data).To(HaveKeyWithValue("uid-1", "10.0.0.1"))
+
+			restarted, err := newLoadBalancer(m)
+			Expect(err).ToNot(HaveOccurred())
+			lbStatus, found, err := restarted.GetLoadBalancer(ctx, mock.MockCluster, svc)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(lbStatus).To(Equal(first))
+		})
+
+		It("should honor the requested loadBalancerIP", func() {
+			svc := newMockService("svc", "uid-1")
+			svc.Spec.LoadBalancerIP = "10.0.1.10"
+			lbStatus, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, svc, []*v1.Node{})
 			Expect(err).ShouldNot(HaveOccurred())
+			Expect(lbStatus.Ingress).To(ConsistOf(v1.LoadBalancerIngress{IP: "10.0.1.10"}))
+		})
+
+		It("should fail if the requested loadBalancerIP is outside of the pools", func() {
+			svc := newMockService("svc", "uid-1")
+			svc.Spec.LoadBalancerIP = "192.168.0.1"
+			_, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, svc, []*v1.Node{})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail if the service has no UID", func() {
+			_, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, newMockService("svc", ""), []*v1.Node{})
+			Expect(err).To(HaveOccurred())
 		})
 	})
 
 	Context("Test UpdateLoadBalancer", func() {
 		It("should not return error", func() {
-			err := ntnxCloud.UpdateLoadBalancer(ctx, mock.MockCluster, &v1.Service{}, []*v1.Node{})
+			err := lb.UpdateLoadBalancer(ctx, mock.MockCluster, newMockService("svc", "uid-1"), []*v1.Node{})
 			Expect(err).ShouldNot(HaveOccurred())
 		})
 	})
 
-	Context("Test UpdateLoadBalancer", func() {
-		It("not return error", func() {
-			err := ntnxCloud.UpdateLoadBalancer(ctx, mock.MockCluster, &v1.Service{}, []*v1.Node{})
+	Context("Test EnsureLoadBalancerDeleted", func() {
+		It("should release the VIP", func() {
+			svc := newMockService("svc", "uid-1")
+			_, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, svc, []*v1.Node{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(lb.EnsureLoadBalancerDeleted(ctx, mock.MockCluster, svc)).To(Succeed())
+			_, found, err := lb.GetLoadBalancer(ctx, mock.MockCluster, svc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		It("should not return error if no VIP was allocated", func() {
+			err := lb.EnsureLoadBalancerDeleted(ctx, mock.MockCluster, newMockService("svc", "uid-1"))
 			Expect(err).ShouldNot(HaveOccurred())
 		})
 	})
+})
 
-	Context("Test EnsureLoadNalancerDeleted", func() {
-		It("not return error", func() {
-			err := ntnxCloud.EnsureLoadBalancerDeleted(ctx, mock.MockCluster, &v1.Service{})
-			Expect(err).ShouldNot(HaveOccurred())
+var _ = Describe("Test Loadbalancer with Prism IPAM", func() { // nolint:typecheck
+	var (
+		ctx             context.Context
+		mockEnvironment *mock.MockEnvironment
+		lb              cloudprovider.LoadBalancer
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		kClient := fake.NewSimpleClientset()
+		var err error
+		mockEnvironment, err = mock.CreateMockEnvironment(ctx, kClient)
+		Expect(err).ToNot(HaveOccurred())
+
+		c := mock.GenerateMockConfig()
+		c.LoadBalancer = &config.LoadBalancerConfig{
+			IPAM:       config.PrismLoadBalancerIPAMType,
+			SubnetUUID: mock.MockSubnetUUID,
+		}
+		m, err := newNutanixManager(c)
+		Expect(err).ToNot(HaveOccurred())
+		m.client = kClient
+		m.nutanixClient = mock.CreateMockClient(*mockEnvironment)
+		lb, err = newLoadBalancer(m)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should reserve the VIP in the subnet IPAM with the service UID as client context", func() {
+		svc := newMockService("svc", "uid-1")
+		lbStatus, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, svc, []*v1.Node{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lbStatus.Ingress).To(ConsistOf(v1.LoadBalancerIngress{IP: mock.MockSubnetIP1}))
+		Expect(mockEnvironment.GetSubnet(mock.MockSubnetUUID).ReservedIPs).To(HaveKeyWithValue(mock.MockSubnetIP1, constants.LoadBalancerClientContextPrefix+"uid-1"))
+	})
+
+	It("should be idempotent on the service UID", func() {
+		svc := newMockService("svc", "uid-1")
+		first, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, svc, []*v1.Node{})
+		Expect(err).ToNot(HaveOccurred())
+		second, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, svc, []*v1.Node{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(second).To(Equal(first))
+		Expect(mockEnvironment.GetSubnet(mock.MockSubnetUUID).ReservedIPs).To(HaveLen(1))
+	})
+
+	It("should reserve the requested loadBalancerIP", func() {
+		svc := newMockService("svc", "uid-1")
+		svc.Spec.LoadBalancerIP = mock.MockSubnetIP2
+		lbStatus, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, svc, []*v1.Node{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lbStatus.Ingress).To(ConsistOf(v1.LoadBalancerIngress{IP: mock.MockSubnetIP2}))
+	})
+
+	It("should fail when the subnet has no free IPs left", func() {
+		for _, uid := range []string{"uid-1", "uid-2"} {
+			_, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, newMockService(uid, uid), []*v1.Node{})
+			Expect(err).ToNot(HaveOccurred())
+		}
+		_, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, newMockService("svc", "uid-3"), []*v1.Node{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should unreserve the VIP when the load balancer is deleted", func() {
+		svc := newMockService("svc", "uid-1")
+		_, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, svc, []*v1.Node{})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(lb.EnsureLoadBalancerDeleted(ctx, mock.MockCluster, svc)).To(Succeed())
+		Expect(mockEnvironment.GetSubnet(mock.MockSubnetUUID).ReservedIPs).To(BeEmpty())
+		_, found, err := lb.GetLoadBalancer(ctx, mock.MockCluster, svc)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeFalse())
+	})
+})
+
+var _ = Describe("Test Loadbalancer with floating IPs", func() { // nolint:typecheck
+	var (
+		ctx             context.Context
+		mockEnvironment *mock.MockEnvironment
+		mockClient      *mock.MockClient
+		c               config.Config
+	)
+
+	newFloatingIPLoadBalancer := func() cloudprovider.LoadBalancer {
+		m, err := newNutanixManager(c)
+		Expect(err).ToNot(HaveOccurred())
+		m.nutanixClient = mockClient
+		lb, err := newLoadBalancer(m)
+		Expect(err).ToNot(HaveOccurred())
+		return lb
+	}
+
+	listFloatingIPs := func() []networkingModels.FloatingIp {
+		nClient, err := mockClient.Get()
+		Expect(err).ToNot(HaveOccurred())
+		fips, err := nClient.ListFloatingIPs(ctx, "")
+		Expect(err).ToNot(HaveOccurred())
+		return fips
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		kClient := fake.NewSimpleClientset()
+		var err error
+		mockEnvironment, err = mock.CreateMockEnvironment(ctx, kClient)
+		Expect(err).ToNot(HaveOccurred())
+		mockClient = mock.CreateMockClient(*mockEnvironment)
+		c = mock.GenerateMockConfig()
+	})
+
+	Context("associated with the VIP", func() {
+		var lb cloudprovider.LoadBalancer
+
+		BeforeEach(func() {
+			c.LoadBalancer = &config.LoadBalancerConfig{
+				IPAM:       config.PrismLoadBalancerIPAMType,
+				SubnetUUID: mock.MockSubnetUUID,
+				FloatingIP: &config.FloatingIPConfig{
+					ExternalSubnetUUID: mock.MockExternalSubnetUUID,
+					VPCUUID:            mock.MockVPCUUID,
+					Association:        config.VIPFloatingIPAssociationType,
+				},
+			}
+			lb = newFloatingIPLoadBalancer()
+		})
+
+		It("should report the floating IP before the VIP", func() {
+			svc := newMockService("svc", "uid-1")
+			lbStatus, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, svc, []*v1.Node{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lbStatus.Ingress).To(Equal([]v1.LoadBalancerIngress{
+				{IP: mock.MockFloatingIP1},
+				{IP: mock.MockSubnetIP1},
+			}))
+
+			fips := listFloatingIPs()
+			Expect(fips).To(HaveLen(1))
+			Expect(*fips[0].Name).To(Equal(constants.LoadBalancerClientContextPrefix + "uid-1"))
+			association, ok := fips[0].GetAssociation().(networkingModels.PrivateIpAssociation)
+			Expect(ok).To(BeTrue())
+			Expect(*association.PrivateIp.Ipv4.Value).To(Equal(mock.MockSubnetIP1))
+			Expect(*association.VpcReference).To(Equal(mock.MockVPCUUID))
+
+			getStatus, found, err := lb.GetLoadBalancer(ctx, mock.MockCluster, svc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(getStatus).To(Equal(lbStatus))
+		})
+
+		It("should be idempotent on the service UID", func() {
+			svc := newMockService("svc", "uid-1")
+			first, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, svc, []*v1.Node{})
+			Expect(err).ToNot(HaveOccurred())
+			second, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, svc, []*v1.Node{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(second).To(Equal(first))
+			Expect(listFloatingIPs()).To(HaveLen(1))
+		})
+
+		It("should delete the floating IP and release the VIP when the load balancer is deleted", func() {
+			svc := newMockService("svc", "uid-1")
+			_, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, svc, []*v1.Node{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(lb.EnsureLoadBalancerDeleted(ctx, mock.MockCluster, svc)).To(Succeed())
+			Expect(listFloatingIPs()).To(BeEmpty())
+			Expect(mockEnvironment.GetSubnet(mock.MockExternalSubnetUUID).ReservedIPs).To(BeEmpty())
+			Expect(mockEnvironment.GetSubnet(mock.MockSubnetUUID).ReservedIPs).To(BeEmpty())
+			_, found, err := lb.GetLoadBalancer(ctx, mock.MockCluster, svc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		It("should fail when the VPC is not connected to the external subnet", func() {
+			c.LoadBalancer.FloatingIP.ExternalSubnetUUID = mock.MockSubnetUUID
+			lb = newFloatingIPLoadBalancer()
+			_, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, newMockService("svc", "uid-1"), []*v1.Node{})
+			Expect(err).To(HaveOccurred())
+			Expect(listFloatingIPs()).To(BeEmpty())
+		})
+	})
+
+	Context("associated with a node NIC", func() {
+		var lb cloudprovider.LoadBalancer
+
+		BeforeEach(func() {
+			c.LoadBalancer = &config.LoadBalancerConfig{
+				FloatingIP: &config.FloatingIPConfig{
+					ExternalSubnetUUID: mock.MockExternalSubnetUUID,
+					VPCUUID:            mock.MockVPCUUID,
+					Association:        config.NodeNICFloatingIPAssociationType,
+				},
+			}
+			lb = newFloatingIPLoadBalancer()
+		})
+
+		It("should associate the floating IP with the VPC NIC of a node", func() {
+			nodes := []*v1.Node{
+				mockEnvironment.GetNode(mock.MockVMNamePoweredOn),
+				mockEnvironment.GetNode(mock.MockVMNameVPC),
+			}
+			lbStatus, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, newMockService("svc", "uid-1"), nodes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lbStatus.Ingress).To(ConsistOf(v1.LoadBalancerIngress{IP: mock.MockFloatingIP1}))
+
+			fips := listFloatingIPs()
+			Expect(fips).To(HaveLen(1))
+			association, ok := fips[0].GetAssociation().(networkingModels.VmNicAssociation)
+			Expect(ok).To(BeTrue())
+			Expect(*association.VmNicReference).To(Equal(mock.MockVPCNicUUID))
+		})
+
+		It("should fail when no node has a NIC in the VPC", func() {
+			nodes := []*v1.Node{mockEnvironment.GetNode(mock.MockVMNamePoweredOn)}
+			_, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, newMockService("svc", "uid-1"), nodes)
+			Expect(err).To(HaveOccurred())
+			Expect(listFloatingIPs()).To(BeEmpty())
+		})
+
+		It("should fail to update the load balancer when its node left", func() {
+			svc := newMockService("svc", "uid-1")
+			_, err := lb.EnsureLoadBalancer(ctx, mock.MockCluster, svc, []*v1.Node{mockEnvironment.GetNode(mock.MockVMNameVPC)})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lb.UpdateLoadBalancer(ctx, mock.MockCluster, svc, []*v1.Node{})).ToNot(Succeed())
+			Expect(lb.UpdateLoadBalancer(ctx, mock.MockCluster, svc, []*v1.Node{mockEnvironment.GetNode(mock.MockVMNameVPC)})).To(Succeed())
 		})
 	})
 })
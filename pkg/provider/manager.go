@@ -20,24 +20,29 @@ import (
 	"context"
 	"fmt"
 	"net/netip"
+	"slices"
 	"sort"
 	"strings"
+	"sync/atomic"
 
 	"github.com/nutanix-cloud-native/prism-go-client/converged"
-	convergedV4 "github.com/nutanix-cloud-native/prism-go-client/converged/v4"
-	prismclientv4 "github.com/nutanix-cloud-native/prism-go-client/v4"
 
 	set "github.com/hashicorp/go-set/v3"
 	clusterModels "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/models/clustermgmt/v4/config"
+	prismModels "github.com/nutanix/ntnx-api-golang-clients/prism-go-client/v4/models/prism/v4/config"
 	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
 	"go4.org/netipx"
 	v1 "k8s.io/api/core/v1"
 	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
 	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	cloudprovider "k8s.io/cloud-provider"
 	"k8s.io/cloud-provider/node/helpers"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
 
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
@@ -45,74 +50,134 @@ import (
 )
 
 type nutanixManager struct {
-	client         clientset.Interface
-	config         config.Config
-	nutanixClient  interfaces.Client
-	ignoredNodeIPs *netipx.IPSet
+	client              clientset.Interface
+	config              config.Config
+	nutanixClient       interfaces.Client
+	ignoredNodeIPs      *netipx.IPSet
+	nodeAddressRules    []nodeAddressRule
+	categoryLabelKeys   map[string]string
+	shutdownPowerStates []vmmModels.PowerState
+	// additionalPrismCentrals are the Prism Centrals after the first one, whose client is
+	// nutanixClient
+	additionalPrismCentrals []prismCentral
+	// reloaded is the manager with the last reloaded config, shared by all of its snapshots
+	reloaded *atomic.Pointer[nutanixManager]
+	// eventRecorder records the events of the CCM, e.g. the rotations of the credentials
+	eventRecorder record.EventRecorder
 }
 
 func newNutanixManager(config config.Config) (*nutanixManager, error) {
 	klog.V(1).Info("Creating new newNutanixManager") //nolint:typecheck
 
-	// Initialize the ignoredNodeIPs set
-	ignoredIPsBuilder := netipx.IPSetBuilder{}
-	for _, ip := range config.IgnoredNodeIPs {
-		switch {
-		case strings.Contains(ip, "-"):
-			ipRange, err := netipx.ParseIPRange(ip)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse ignoredNodeIPs IP range %q: %v", ip, err)
-			}
-			ignoredIPsBuilder.AddRange(ipRange)
-		case strings.Contains(ip, "/"):
-			prefix, err := netip.ParsePrefix(ip)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse ignoredNodeIPs IP prefix %q: %v", ip, err)
-			}
-			ignoredIPsBuilder.AddPrefix(prefix)
-		default:
-			parsedIP, err := netip.ParseAddr(ip)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse ignoredNodeIPs IP %q: %v", ip, err)
-			}
-			ignoredIPsBuilder.Add(parsedIP)
-		}
-	}
-
-	ignoredIPSet, err := ignoredIPsBuilder.IPSet()
-	if err != nil {
-		return nil, fmt.Errorf("failed to build ignoredNodeIPs IP set: %v", err)
+	prismCentrals := config.GetPrismCentrals()
+	additionalPrismCentrals := make([]prismCentral, 0, len(prismCentrals)-1)
+	for _, pc := range prismCentrals[1:] {
+		additionalPrismCentrals = append(additionalPrismCentrals, prismCentral{
+			name:   pc.Name,
+			client: newPrismCentralClient(config, pc.Name),
+		})
 	}
 
 	m := &nutanixManager{
-		config: config,
-		nutanixClient: &nutanixClientEnvironment{
-			config:      config,
-			clientCache: convergedV4.NewClientCache(prismclientv4.WithSessionAuth(true)),
-		},
-		ignoredNodeIPs: ignoredIPSet,
+		nutanixClient:           newPrismCentralClient(config, prismCentrals[0].Name),
+		additionalPrismCentrals: additionalPrismCentrals,
+		reloaded:                &atomic.Pointer[nutanixManager]{},
+	}
+	if err := m.applyConfig(config); err != nil {
+		return nil, err
 	}
 	return m, nil
 }
 
+// applyConfig sets the config of the manager and the state derived from it.
+func (n *nutanixManager) applyConfig(config config.Config) error {
+	ignoredIPSet, err := parseIPSet("ignoredNodeIPs", config.IgnoredNodeIPs)
+	if err != nil {
+		return err
+	}
+	nodeAddressRules, err := parseNodeAddressRules(config.NodeAddressRules)
+	if err != nil {
+		return err
+	}
+	categoryLabelKeys, err := parseCategoryLabelKeys(config.CategoryLabels)
+	if err != nil {
+		return err
+	}
+	shutdownPowerStates, err := parseShutdownPowerStates(config.ShutdownPowerStates)
+	if err != nil {
+		return err
+	}
+
+	n.config = config
+	n.ignoredNodeIPs = ignoredIPSet
+	n.nodeAddressRules = nodeAddressRules
+	n.categoryLabelKeys = categoryLabelKeys
+	n.shutdownPowerStates = shutdownPowerStates
+	return nil
+}
+
 func (n *nutanixManager) setKubernetesClient(client clientset.Interface) {
 	n.client = client
+	n.eventRecorder = newEventRecorder(client)
 	n.setInformers()
 }
 
 func (n *nutanixManager) setInformers() {
-	// Set the nutanixClient's informersFactory with the ccm namespace
+	// Set the informersFactory of the Prism Central clients with the ccm namespace
 	ccmNamespace, err := GetCCMNamespace()
 	if err != nil {
 		klog.Fatal(err.Error()) //nolint:typecheck
 	}
 	informerFactory := informers.NewSharedInformerFactoryWithOptions(
 		n.client, NoResyncPeriodFunc(), informers.WithNamespace(ccmNamespace))
-	n.nutanixClient.SetInformers(informerFactory)
+	for _, pc := range n.getPrismCentrals() {
+		pc.client.SetInformers(informerFactory)
+	}
+	n.setConfigMapInformer(informerFactory.Core().V1().ConfigMaps())
+	n.setCredentialInformers(ccmNamespace, informerFactory.Core().V1().Secrets(), informerFactory.Core().V1().ConfigMaps())
 
 	klog.Infof("Set the informers with namespace %q", ccmNamespace) //nolint:typecheck
 }
 
+// setNodeInformer invalidates the cached VM of nodes when they are added or deleted, so that
+// a recreated VM or a deleted node is not served from the cache.
+func (n *nutanixManager) setNodeInformer(nodeInformer coreinformers.NodeInformer) {
+	var cachedClients []*cachedClient
+	for _, pc := range n.getPrismCentrals() {
+		if cachedClient, ok := pc.client.(*cachedClient); ok {
+			cachedClients = append(cachedClients, cachedClient)
+		}
+	}
+	if len(cachedClients) == 0 {
+		return
+	}
+	invalidate := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		node, ok := obj.(*v1.Node)
+		if !ok {
+			return
+		}
+		// The VM is not looked up in Prism Central here: the UUIDs the node may be discovered by
+		// are invalidated instead.
+		for _, cachedClient := range cachedClients {
+			if systemUUID := node.Status.NodeInfo.SystemUUID; systemUUID != "" {
+				cachedClient.invalidateVM(strings.ToLower(systemUUID))
+			}
+			if vmUUID, ok := strings.CutPrefix(node.Spec.ProviderID, constants.ProviderName+"://"); ok && vmUUID != "" {
+				cachedClient.invalidateVM(strings.ToLower(vmUUID))
+			}
+		}
+	}
+	if _, err := nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    invalidate,
+		DeleteFunc: invalidate,
+	}); err != nil {
+		klog.Errorf("failed to add node event handler: %v", err) //nolint:typecheck
+	}
+}
+
 func (n *nutanixManager) getInstanceMetadata(ctx context.Context, node *v1.Node) (*cloudprovider.InstanceMetadata, error) {
 	if node == nil {
 		return nil, fmt.Errorf("node cannot be nil when fetching instance metadata")
@@ -121,19 +186,11 @@ func (n *nutanixManager) getInstanceMetadata(ctx context.Context, node *v1.Node)
 	nodeName := node.Name
 	klog.V(1).Infof("fetching instance metadata for node %s", nodeName) //nolint:typecheck
 
-	vmUUID, err := n.getNutanixInstanceIDForNode(ctx, node)
-	if err != nil {
-		return nil, err
-	}
-
-	nClient, err := n.nutanixClient.Get()
-	if err != nil {
-		return nil, err
-	}
-	vm, err := nClient.GetVM(ctx, vmUUID)
+	nodeVM, err := n.getNodeVM(ctx, node)
 	if err != nil {
 		return nil, err
 	}
+	nClient, vm := nodeVM.nClient, nodeVM.vm
 
 	// Generate providerID from VM (checks customAttributes first, then falls back to vmUUID)
 	providerID, err := n.generateProviderIDFromVM(ctx, vm)
@@ -144,7 +201,7 @@ func (n *nutanixManager) getInstanceMetadata(ctx context.Context, node *v1.Node)
 	klog.V(1).Infof("fetching nodeAddresses for node %s", nodeName) //nolint:typecheck
 	nodeAddresses := node.Status.Addresses
 	if !n.isNodeAddressesSet(node) {
-		nodeAddresses, err = n.getNodeAddresses(ctx, vm)
+		nodeAddresses, err = n.getNodeAddresses(ctx, nClient, vm)
 		if err != nil {
 			return nil, err
 		}
@@ -157,57 +214,82 @@ func (n *nutanixManager) getInstanceMetadata(ctx context.Context, node *v1.Node)
 
 	if n.config.EnableCustomLabeling {
 		klog.V(1).Infof("adding custom labels %s", nodeName) //nolint:typecheck
-		err = n.addCustomLabelsToNode(ctx, node)
+		err = n.addCustomLabelsToNode(ctx, nClient, vm, node)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	if err := n.addCategoryLabelsToNode(ctx, nClient, vm, node); err != nil {
+		return nil, err
+	}
+
+	taints, err := n.getNodeTaints(ctx, nClient, vm)
+	if err != nil {
+		return nil, err
+	}
+	if err := n.reconcileNodeTaints(node, taints); err != nil {
+		return nil, err
+	}
+
+	if err := n.reconcilePrismCentralAnnotation(ctx, node, nodeVM.prismCentral); err != nil {
+		return nil, err
+	}
+
 	// Metro node-group labeling is derived purely from the VM's custom attributes
 	if err := n.reconcileMetroNodeGroupLabel(node, vm); err != nil {
 		return nil, err
 	}
 
+	instanceType, err := n.getInstanceType(ctx, nClient, vm)
+	if err != nil {
+		return nil, err
+	}
+	if err := n.reconcileInstanceTypeLabels(node, instanceType); err != nil {
+		return nil, err
+	}
+
 	return &cloudprovider.InstanceMetadata{
 		ProviderID:    providerID,
-		InstanceType:  constants.InstanceType,
+		InstanceType:  instanceType,
 		NodeAddresses: nodeAddresses,
 		Region:        topologyInfo.Region,
 		Zone:          topologyInfo.Zone,
 	}, nil
 }
 
-func (n *nutanixManager) addCustomLabelsToNode(ctx context.Context, node *v1.Node) error {
-	var cluster *clusterModels.Cluster
-	var host *clusterModels.Host
-
-	labels := map[string]string{}
-
-	nClient, err := n.nutanixClient.Get()
+func (n *nutanixManager) addCustomLabelsToNode(ctx context.Context, nClient interfaces.Prism, vm *vmmModels.Vm, node *v1.Node) error {
+	labels, err := n.getCustomLabels(ctx, nClient, vm)
 	if err != nil {
 		return err
 	}
 
-	providerID, err := n.getNutanixProviderIDForNode(ctx, node)
-	if err != nil {
-		return err
-	}
-	vmUUID := n.stripNutanixIDFromProviderID(providerID)
-	vm, err := nClient.GetVM(ctx, vmUUID)
-	if err != nil {
-		return err
+	result := helpers.AddOrUpdateLabelsOnNode(n.client, labels, node)
+	if !result {
+		return fmt.Errorf("error occurred while updating labels on node %s", node.Name)
 	}
+	return nil
+}
+
+// getCustomLabels returns the Prism Element and AHV host labels of the VM. The AHV host labels
+// are not returned if the VM is not running on a host.
+func (n *nutanixManager) getCustomLabels(ctx context.Context, nClient interfaces.Prism, vm *vmmModels.Vm) (map[string]string, error) {
+	var cluster *clusterModels.Cluster
+	var host *clusterModels.Host
+	var err error
+
+	labels := map[string]string{}
 
 	if vm.Cluster != nil && vm.Cluster.ExtId != nil {
 		cluster, err = nClient.GetCluster(ctx, *vm.Cluster.ExtId)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		if vm.Host != nil && vm.Host.ExtId != nil {
 			host, err = nClient.GetClusterHost(ctx, *vm.Cluster.ExtId, *vm.Host.ExtId)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 	}
@@ -221,12 +303,7 @@ func (n *nutanixManager) addCustomLabelsToNode(ctx context.Context, node *v1.Nod
 		labels[constants.CustomHostUUIDLabel] = *host.ExtId
 		labels[constants.CustomHostNameLabel] = *host.HostName
 	}
-
-	result := helpers.AddOrUpdateLabelsOnNode(n.client, labels, node)
-	if !result {
-		return fmt.Errorf("error occurred while updating labels on node %s", node.Name)
-	}
-	return nil
+	return labels, nil
 }
 
 // reconcileMetroNodeGroupLabel labels the node with its Nutanix Metro site group name when the
@@ -261,18 +338,63 @@ func (n *nutanixManager) reconcileMetroNodeGroupLabel(node *v1.Node, vm *vmmMode
 // getVMCustomAttributeValue returns the value of the VM custom attribute matching key, where each
 // custom attribute is encoded as "key:value". It returns an empty string if not found.
 func getVMCustomAttributeValue(vm *vmmModels.Vm, key string) string {
+	return getVMCustomAttributes(vm)[key]
+}
+
+// getVMCustomAttributeIgnoreCase returns the first value of the VM custom attributes with the
+// key, which is matched case-insensitively like when generating the providerID of the VM. It
+// returns an empty string if not found.
+func getVMCustomAttributeIgnoreCase(vm *vmmModels.Vm, key string) string {
 	if vm == nil {
 		return ""
 	}
 	for _, attr := range vm.CustomAttributes {
-		parts := strings.SplitN(attr, ":", 2)
-		if len(parts) == 2 && strings.TrimSpace(parts[0]) == key {
-			return strings.TrimSpace(parts[1])
+		attrKey, value, ok := strings.Cut(attr, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(attrKey), key) && strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
 		}
 	}
 	return ""
 }
 
+// getVMCustomAttributes returns the values of the VM custom attributes encoded as "key:value" by
+// key. Custom attributes without a separator are ignored; the first value of a key wins.
+func getVMCustomAttributes(vm *vmmModels.Vm) map[string]string {
+	customAttributes := map[string]string{}
+	if vm == nil {
+		return customAttributes
+	}
+	for _, attr := range vm.CustomAttributes {
+		parts := strings.SplitN(attr, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		if _, ok := customAttributes[key]; !ok {
+			customAttributes[key] = strings.TrimSpace(parts[1])
+		}
+	}
+	return customAttributes
+}
+
+// getVMCategories returns the values of the categories assigned to the VM by category key.
+func (n *nutanixManager) getVMCategories(ctx context.Context, nClient interfaces.Prism, vm *vmmModels.Vm) (map[string][]string, error) {
+	if vm == nil {
+		return map[string][]string{}, nil
+	}
+	categoryUUIDs := make([]string, 0, len(vm.Categories))
+	for _, categoryRef := range vm.Categories {
+		if categoryRef.ExtId != nil {
+			categoryUUIDs = append(categoryUUIDs, *categoryRef.ExtId)
+		}
+	}
+	categories, err := getCategories(ctx, nClient, categoryUUIDs)
+	if err != nil {
+		return nil, err
+	}
+	return categoryValuesByKey(categories), nil
+}
+
 func (n *nutanixManager) getTopologyCategories() (config.TopologyCategories, error) {
 	topologyCategories := config.TopologyCategories{}
 	configTopologyCategories := n.config.TopologyDiscovery.TopologyCategories
@@ -297,15 +419,7 @@ func (n *nutanixManager) getTopologyCategories() (config.TopologyCategories, err
 }
 
 func (n *nutanixManager) nodeExists(ctx context.Context, node *v1.Node) (bool, error) {
-	vmUUID, err := n.getNutanixInstanceIDForNode(ctx, node)
-	if err != nil {
-		return false, err
-	}
-	nClient, err := n.nutanixClient.Get()
-	if err != nil {
-		return false, err
-	}
-	_, err = nClient.GetVM(ctx, vmUUID)
+	_, err := n.getNodeVM(ctx, node)
 	if err != nil {
 		if !converged.IsNotFound(err) {
 			return false, err
@@ -316,57 +430,62 @@ func (n *nutanixManager) nodeExists(ctx context.Context, node *v1.Node) (bool, e
 }
 
 func (n *nutanixManager) isNodeShutdown(ctx context.Context, node *v1.Node) (bool, error) {
-	vmUUID, err := n.getNutanixInstanceIDForNode(ctx, node)
+	nodeVM, err := n.getNodeVM(ctx, node)
 	if err != nil {
 		return false, err
 	}
-	nClient, err := n.nutanixClient.Get()
-	if err != nil {
-		return false, err
-	}
-	vm, err := nClient.GetVM(ctx, vmUUID)
-	if err != nil {
-		return false, err
-	}
-	if n.isVMShutdown(vm) {
+	if n.isVMShutdown(nodeVM.vm) {
 		return true, nil
 	}
 	return false, nil
 }
 
+// isVMShutdown returns true if the VM is in one of the shutdown power states. VMs whose power
+// state is missing, unknown or redacted are not reported as shutdown, as the node lifecycle
+// controller would otherwise taint nodes that may still be running.
 func (n *nutanixManager) isVMShutdown(vm *vmmModels.Vm) bool {
-	return *vm.PowerState == vmmModels.POWERSTATE_OFF
-}
-
-func (n *nutanixManager) getNutanixInstanceIDForNode(ctx context.Context, node *v1.Node) (string, error) {
-	if node == nil {
-		return "", fmt.Errorf("node cannot be nil when getting nutanix instance ID for node")
+	if vm == nil {
+		return false
 	}
-
-	nodeUUID := node.Status.NodeInfo.SystemUUID
-	if nodeUUID == "" {
-		return "", fmt.Errorf("failed to retrieve node UUID for node with name %s", node.Name)
+	if vm.PowerState == nil {
+		klog.Warningf("power state of VM %s is not known: not reporting it as shutdown", ptr.Deref(vm.ExtId, "")) //nolint:typecheck
+		return false
 	}
-	return strings.ToLower(nodeUUID), nil
-}
 
-func (n *nutanixManager) getNutanixProviderIDForNode(ctx context.Context, node *v1.Node) (string, error) {
-	if node == nil {
-		return "", fmt.Errorf("node cannot be nil when fetching providerID")
+	switch powerState := *vm.PowerState; powerState {
+	case vmmModels.POWERSTATE_ON, vmmModels.POWERSTATE_OFF, vmmModels.POWERSTATE_PAUSED, vmmModels.POWERSTATE_UNDETERMINED:
+		return slices.Contains(n.shutdownPowerStates, powerState)
+	case vmmModels.POWERSTATE_UNKNOWN, vmmModels.POWERSTATE_REDACTED:
+		klog.Warningf("power state of VM %s is %s: not reporting it as shutdown", ptr.Deref(vm.ExtId, ""), powerState.GetName()) //nolint:typecheck
+		return false
+	default:
+		klog.Warningf("unsupported power state %d of VM %s: not reporting it as shutdown", powerState, ptr.Deref(vm.ExtId, "")) //nolint:typecheck
+		return false
 	}
+}
 
-	providerID := node.Spec.ProviderID
-	if providerID == "" {
-		vmUUID, err := n.getNutanixInstanceIDForNode(ctx, node)
-		if err != nil {
-			return "", err
-		}
-		providerID, err = n.generateProviderID(ctx, vmUUID)
-		if err != nil {
-			return "", err
+// parseShutdownPowerStates returns the power states with the given names, or
+// config.DefaultShutdownPowerStates if none are given.
+func parseShutdownPowerStates(names []string) ([]vmmModels.PowerState, error) {
+	if len(names) == 0 {
+		names = config.DefaultShutdownPowerStates
+	}
+	knownPowerStates := []vmmModels.PowerState{
+		vmmModels.POWERSTATE_OFF,
+		vmmModels.POWERSTATE_PAUSED,
+		vmmModels.POWERSTATE_UNDETERMINED,
+	}
+	powerStates := make([]vmmModels.PowerState, 0, len(names))
+	for i, name := range names {
+		idx := slices.IndexFunc(knownPowerStates, func(powerState vmmModels.PowerState) bool {
+			return strings.EqualFold(powerState.GetName(), name)
+		})
+		if idx < 0 {
+			return nil, fmt.Errorf("unsupported shutdownPowerStates[%d]: %q", i, name)
 		}
+		powerStates = append(powerStates, knownPowerStates[idx])
 	}
-	return providerID, nil
+	return powerStates, nil
 }
 
 func (n *nutanixManager) generateProviderID(ctx context.Context, vmUUID string) (string, error) {
@@ -391,7 +510,7 @@ func (n *nutanixManager) generateProviderIDFromVM(ctx context.Context, vm *vmmMo
 		for _, attr := range vm.CustomAttributes {
 			// customAttributes are in the format "key:value"
 			parts := strings.SplitN(attr, ":", 2)
-			if len(parts) == 2 && strings.ToLower(strings.TrimSpace(parts[0])) == "providerid" {
+			if len(parts) == 2 && strings.EqualFold(strings.TrimSpace(parts[0]), constants.ProviderIDAttributeKey) {
 				customProviderID := strings.TrimSpace(parts[1])
 				if customProviderID != "" {
 					klog.V(2).Infof("Using custom providerID from customAttributes: %s", customProviderID) //nolint:typecheck
@@ -431,9 +550,10 @@ func (n *nutanixManager) isNodeAddressesSet(node *v1.Node) bool {
 	return hasHostname && hasInternalIP
 }
 
-func (n *nutanixManager) getNodeAddresses(_ context.Context, vm *vmmModels.Vm) ([]v1.NodeAddress, error) {
+func (n *nutanixManager) getNodeAddresses(ctx context.Context, nClient interfaces.Prism, vm *vmmModels.Vm) ([]v1.NodeAddress, error) {
 	var addresses []v1.NodeAddress
 	uniqueIPs := set.New[string](0)
+	classifier := n.newNodeAddressClassifier(nClient)
 
 	if vm == nil {
 		return nil, fmt.Errorf("vm cannot be nil when getting node addresses")
@@ -454,14 +574,14 @@ func (n *nutanixManager) getNodeAddresses(_ context.Context, vm *vmmModels.Vm) (
 		case vmmModels.VirtualEthernetNicNetworkInfo:
 			netInfo := nic.NicNetworkInfo.GetValue().(vmmModels.VirtualEthernetNicNetworkInfo)
 
-			vmAddresses, err = n.getNodeAddressesFromNicNetworkInfo(netInfo.Ipv4Config, netInfo.Ipv4Info)
+			vmAddresses, err = n.getNodeAddressesFromNicNetworkInfo(netInfo.Ipv4Config, netInfo.Ipv4Info, netInfo.Ipv6Info)
 			if err != nil {
 				return nil, err
 			}
 
 		case vmmModels.DpOffloadNicNetworkInfo:
 			netInfo := nic.NicNetworkInfo.GetValue().(vmmModels.DpOffloadNicNetworkInfo)
-			vmAddresses, err = n.getNodeAddressesFromNicNetworkInfo(netInfo.Ipv4Config, netInfo.Ipv4Info)
+			vmAddresses, err = n.getNodeAddressesFromNicNetworkInfo(netInfo.Ipv4Config, netInfo.Ipv4Info, netInfo.Ipv6Info)
 			if err != nil {
 				return nil, err
 			}
@@ -474,18 +594,28 @@ func (n *nutanixManager) getNodeAddresses(_ context.Context, vm *vmmModels.Vm) (
 		// At this point, every NIC has no duplicates in its own set of addresses.
 		// However, there can be duplicates across the sets of addresses of different NICs,
 		// so we ignore the duplicates.
+		subnetUUID := nicSubnetUUID(nic)
 		for _, addr := range vmAddresses {
-			if addr.Type != v1.NodeInternalIP {
-				addresses = append(addresses, addr)
+			if uniqueIPs.Contains(addr.Address) {
 				continue
 			}
-			if !uniqueIPs.Contains(addr.Address) {
-				uniqueIPs.Insert(addr.Address)
-				addresses = append(addresses, addr)
+			uniqueIPs.Insert(addr.Address)
+
+			// The type of the address is set by the node address rules
+			addressType, ok, err := classifier.classify(ctx, subnetUUID, netip.MustParseAddr(addr.Address))
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				klog.V(1).Infof("excluding address %s of VM %s from node addresses", addr.Address, *vm.ExtId) //nolint:typecheck
+				continue
 			}
+			addr.Type = addressType
+			addresses = append(addresses, addr)
 		}
 	}
 
+	addresses = n.orderNodeAddressesByFamily(addresses)
 	if len(addresses) == 0 {
 		return addresses, fmt.Errorf("unable to determine network interfaces from VM with UUID %s", *vm.ExtId)
 	}
@@ -497,21 +627,145 @@ func (n *nutanixManager) getNodeAddresses(_ context.Context, vm *vmmModels.Vm) (
 	return addresses, nil
 }
 
-func (n *nutanixManager) getNodeAddressesFromNicNetworkInfo(ipv4Config *vmmModels.Ipv4Config, ipv4Info *vmmModels.Ipv4Info) ([]v1.NodeAddress, error) {
-	addressSet := set.From([]v1.NodeAddress{})
+// getNodeVPCNics returns the NICs of the VM of the node attached to a subnet of the VPC. The NICs
+// and their subnets are read from the Prism Central of the VM.
+func (n *nutanixManager) getNodeVPCNics(ctx context.Context, node *v1.Node, vpcUUID string, subnetVPCs map[string]string) ([]vmmModels.Nic, error) {
+	nodeVM, err := n.current().getNodeVM(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	if nodeVM.vm.ExtId == nil || *nodeVM.vm.ExtId == "" {
+		return nil, fmt.Errorf("VM of node %s has no UUID", node.Name)
+	}
+	return n.getVPCNics(ctx, nodeVM.nClient, strings.ToLower(*nodeVM.vm.ExtId), vpcUUID, subnetVPCs)
+}
 
-	if ipv4Config != nil {
-		primaryIP := ipv4Config.IpAddress.Value
-		if primaryIP != nil {
-			parsedIP, err := netip.ParseAddr(*primaryIP)
+// getVPCNics returns the NICs of the VM attached to a subnet of the VPC. subnetVPCs caches the
+// VPC of each subnet and can be shared between calls.
+func (n *nutanixManager) getVPCNics(ctx context.Context, nClient interfaces.Prism, vmUUID string, vpcUUID string, subnetVPCs map[string]string) ([]vmmModels.Nic, error) {
+	nics, err := nClient.ListVMNics(ctx, vmUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list NICs of VM %s: %w", vmUUID, err)
+	}
+
+	vpcNics := make([]vmmModels.Nic, 0)
+	for _, nic := range nics {
+		subnetUUID := nicSubnetUUID(nic)
+		if subnetUUID == "" {
+			continue
+		}
+		subnetVPC, ok := subnetVPCs[subnetUUID]
+		if !ok {
+			subnet, err := nClient.GetSubnet(ctx, subnetUUID)
 			if err != nil {
-				return nil, fmt.Errorf("failed to parse IP address %q: %v", *primaryIP, err)
+				return nil, fmt.Errorf("failed to get subnet %s: %w", subnetUUID, err)
+			}
+			subnetVPC = ptr.Deref(subnet.VpcReference, "")
+			subnetVPCs[subnetUUID] = subnetVPC
+		}
+		if subnetVPC == vpcUUID {
+			vpcNics = append(vpcNics, nic)
+		}
+	}
+	return vpcNics, nil
+}
+
+func nicSubnetUUID(nic vmmModels.Nic) string {
+	if nic.NicNetworkInfo == nil {
+		return ""
+	}
+	var subnet *vmmModels.SubnetReference
+	switch netInfo := nic.NicNetworkInfo.GetValue().(type) {
+	case vmmModels.VirtualEthernetNicNetworkInfo:
+		subnet = netInfo.Subnet
+	case vmmModels.DpOffloadNicNetworkInfo:
+		subnet = netInfo.Subnet
+	}
+	if subnet == nil {
+		return ""
+	}
+	return ptr.Deref(subnet.ExtId, "")
+}
+
+// nicIPv4Address returns the configured IPv4 address of the NIC, or its first learned IPv4 address.
+func nicIPv4Address(nic vmmModels.Nic) string {
+	if nic.NicNetworkInfo == nil {
+		return ""
+	}
+	var ipv4Config *vmmModels.Ipv4Config
+	var ipv4Info *vmmModels.Ipv4Info
+	switch netInfo := nic.NicNetworkInfo.GetValue().(type) {
+	case vmmModels.VirtualEthernetNicNetworkInfo:
+		ipv4Config, ipv4Info = netInfo.Ipv4Config, netInfo.Ipv4Info
+	case vmmModels.DpOffloadNicNetworkInfo:
+		ipv4Config, ipv4Info = netInfo.Ipv4Config, netInfo.Ipv4Info
+	}
+	if ipv4Config != nil && ipv4Config.IpAddress != nil && ipv4Config.IpAddress.Value != nil {
+		return *ipv4Config.IpAddress.Value
+	}
+	if ipv4Info != nil {
+		for _, address := range ipv4Info.LearnedIpAddresses {
+			if address.Value != nil {
+				return *address.Value
 			}
-			if !n.ignoredNodeIPs.Contains(parsedIP) {
-				addressSet.Insert(v1.NodeAddress{
-					Type:    v1.NodeInternalIP,
-					Address: *primaryIP,
-				})
+		}
+	}
+	return ""
+}
+
+// orderNodeAddressesByFamily drops the IP addresses of the family excluded by the address
+// family preference and moves the IP addresses of the preferred family first, keeping the
+// order of the addresses within each family.
+func (n *nutanixManager) orderNodeAddressesByFamily(addresses []v1.NodeAddress) []v1.NodeAddress {
+	preferIPv6, onlyPreferred := false, false
+	switch n.config.AddressFamily {
+	case config.IPv6FirstAddressFamilyType:
+		preferIPv6 = true
+	case config.IPv4OnlyAddressFamilyType:
+		onlyPreferred = true
+	case config.IPv6OnlyAddressFamilyType:
+		preferIPv6, onlyPreferred = true, true
+	}
+
+	preferred := make([]v1.NodeAddress, 0, len(addresses))
+	others := make([]v1.NodeAddress, 0, len(addresses))
+	for _, address := range addresses {
+		ip, err := netip.ParseAddr(address.Address)
+		if err != nil || ip.Is6() == preferIPv6 {
+			preferred = append(preferred, address)
+			continue
+		}
+		if !onlyPreferred {
+			others = append(others, address)
+		}
+	}
+	return append(preferred, others...)
+}
+
+func (n *nutanixManager) getNodeAddressesFromNicNetworkInfo(ipv4Config *vmmModels.Ipv4Config, ipv4Info *vmmModels.Ipv4Info, ipv6Info *vmmModels.Ipv6Info) ([]v1.NodeAddress, error) {
+	addressSet := set.From([]v1.NodeAddress{})
+	addAddress := func(address string) error {
+		parsedIP, err := netip.ParseAddr(address)
+		if err != nil {
+			return fmt.Errorf("failed to parse IP address %q: %v", address, err)
+		}
+		// Link-local IPv6 addresses are present on every interface and cannot be used to reach the node
+		if parsedIP.Is6() && parsedIP.IsLinkLocalUnicast() {
+			return nil
+		}
+		if !n.ignoredNodeIPs.Contains(parsedIP) {
+			addressSet.Insert(v1.NodeAddress{
+				Type:    v1.NodeInternalIP,
+				Address: parsedIP.String(),
+			})
+		}
+		return nil
+	}
+
+	if ipv4Config != nil {
+		if ipv4Config.IpAddress != nil && ipv4Config.IpAddress.Value != nil {
+			if err := addAddress(*ipv4Config.IpAddress.Value); err != nil {
+				return nil, err
 			}
 		}
 
@@ -519,15 +773,8 @@ func (n *nutanixManager) getNodeAddressesFromNicNetworkInfo(ipv4Config *vmmModel
 			if ipAddress.Value == nil {
 				continue
 			}
-			parsedIP, err := netip.ParseAddr(*ipAddress.Value)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse IP address %q: %v", *ipAddress.Value, err)
-			}
-			if !n.ignoredNodeIPs.Contains(parsedIP) {
-				addressSet.Insert(v1.NodeAddress{
-					Type:    v1.NodeInternalIP,
-					Address: *ipAddress.Value,
-				})
+			if err := addAddress(*ipAddress.Value); err != nil {
+				return nil, err
 			}
 		}
 	}
@@ -537,17 +784,21 @@ func (n *nutanixManager) getNodeAddressesFromNicNetworkInfo(ipv4Config *vmmModel
 			if ipAddress.Value == nil {
 				continue
 			}
-
-			parsedIP, err := netip.ParseAddr(*ipAddress.Value)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse IP address %q: %v", *ipAddress.Value, err)
+			if err := addAddress(*ipAddress.Value); err != nil {
+				return nil, err
 			}
+		}
+	}
 
-			if !n.ignoredNodeIPs.Contains(parsedIP) {
-				addressSet.Insert(v1.NodeAddress{
-					Type:    v1.NodeInternalIP,
-					Address: *ipAddress.Value,
-				})
+	// The VMM API does not expose a static IPv6 configuration: IPv6 addresses, whether assigned
+	// statically in the guest or through SLAAC/DHCPv6, are reported as learned addresses.
+	if ipv6Info != nil {
+		for _, ipAddress := range ipv6Info.LearnedIpv6Addresses {
+			if ipAddress.Value == nil {
+				continue
+			}
+			if err := addAddress(*ipAddress.Value); err != nil {
+				return nil, err
 			}
 		}
 	}
@@ -561,10 +812,6 @@ func (n *nutanixManager) getNodeAddressesFromNicNetworkInfo(ipv4Config *vmmModel
 	return addresses, nil
 }
 
-func (n *nutanixManager) stripNutanixIDFromProviderID(providerID string) string {
-	return strings.TrimPrefix(providerID, fmt.Sprintf("%s://", constants.ProviderName))
-}
-
 func (n *nutanixManager) getTopologyInfo(ctx context.Context, nutanixClient interfaces.Prism, vm *vmmModels.Vm) (*config.TopologyInfo, error) {
 	topologyDiscovery := n.config.TopologyDiscovery
 	topologyInfo := &config.TopologyInfo{}
@@ -641,32 +888,71 @@ func (n *nutanixManager) getTopologyInfoUsingCategories(ctx context.Context, nut
 		return nil
 	}
 	klog.V(1).Infof("searching for topology info on host entity for VM: %s", *vm.Name) //nolint:typecheck
-	nClient, err := n.nutanixClient.Get()
+	err = n.getTopologyInfoFromHost(ctx, nutanixClient, vm, topologyInfo)
 	if err != nil {
 		return err
 	}
+	if !n.hasEmptyTopologyInfo(*topologyInfo) {
+		klog.V(1).Infof("topology info after searching host: %+v", *topologyInfo) //nolint:typecheck
+		return nil
+	}
 
 	klog.V(1).Infof("searching for topology info on cluster entity for VM: %s", *vm.Name) //nolint:typecheck
-	err = n.getTopologyInfoFromCluster(ctx, nClient, vm, topologyInfo)
+	err = n.getTopologyInfoFromCluster(ctx, nutanixClient, vm, topologyInfo)
+	if err != nil {
+		return err
+	}
+	if !n.hasEmptyTopologyInfo(*topologyInfo) {
+		klog.V(1).Infof("topology info after searching cluster: %+v", *topologyInfo) //nolint:typecheck
+		return nil
+	}
+
+	klog.V(1).Infof("searching for topology info on Prism Central entity for VM: %s", *vm.Name) //nolint:typecheck
+	pc, err := n.getPrismCentralCluster(ctx, nutanixClient)
 	if err != nil {
+		return fmt.Errorf("error occurred while searching for topology info on Prism Central: %v", err)
+	}
+	if err = n.getZoneInfoFromCategories(ctx, nutanixClient, pc.Categories, topologyInfo); err != nil {
 		return err
 	}
-	klog.V(1).Infof("topology info after searching cluster: %+v", *topologyInfo) //nolint:typecheck
+	klog.V(1).Infof("topology info after searching Prism Central: %+v", *topologyInfo) //nolint:typecheck
 	return nil
 }
 
 func (n *nutanixManager) getZoneInfoFromCategories(ctx context.Context, nClient interfaces.Prism, categoryUUIDs []string, ti *config.TopologyInfo) error {
-	prismCategories := make(map[string][]string)
+	categories, err := getCategories(ctx, nClient, categoryUUIDs)
+	if err != nil {
+		return err
+	}
+	return n.setZoneInfoFromCategories(categories, ti)
+}
+
+// getCategories returns the categories with the UUIDs.
+func getCategories(ctx context.Context, nClient interfaces.Prism, categoryUUIDs []string) ([]prismModels.Category, error) {
+	categories := make([]prismModels.Category, 0, len(categoryUUIDs))
 	for _, categoryUUID := range categoryUUIDs {
 		category, err := nClient.GetCategory(ctx, categoryUUID)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		if _, ok := prismCategories[*category.Key]; !ok {
-			prismCategories[*category.Key] = []string{}
+		categories = append(categories, *category)
+	}
+	return categories, nil
+}
+
+// categoryValuesByKey returns the values of the categories by category key.
+func categoryValuesByKey(categories []prismModels.Category) map[string][]string {
+	values := make(map[string][]string)
+	for _, category := range categories {
+		if category.Key != nil && category.Value != nil {
+			values[*category.Key] = append(values[*category.Key], *category.Value)
 		}
-		prismCategories[*category.Key] = append(prismCategories[*category.Key], *category.Value)
 	}
+	return values
+}
+
+func (n *nutanixManager) setZoneInfoFromCategories(categories []prismModels.Category, ti *config.TopologyInfo) error {
+	prismCategories := categoryValuesByKey(categories)
 
 	tCategories, err := n.getTopologyCategories()
 	if err != nil {
@@ -721,6 +1007,68 @@ func (n *nutanixManager) getTopologyInfoFromCluster(ctx context.Context, nClient
 	return nil
 }
 
+// getTopologyInfoFromHost searches the categories attached to the AHV host of the VM. Hosts do
+// not reference their categories, so the topology categories are listed with their associations.
+func (n *nutanixManager) getTopologyInfoFromHost(ctx context.Context, nClient interfaces.Prism, vm *vmmModels.Vm, ti *config.TopologyInfo) error {
+	if vm == nil {
+		return fmt.Errorf("vm cannot be nil when searching for topology info")
+	}
+	if ti == nil {
+		return fmt.Errorf("topology categories cannot be nil when searching for topology info")
+	}
+	if vm.Host == nil || vm.Host.ExtId == nil {
+		klog.V(1).Infof("VM %s is not running on a host, skipping host topology info", *vm.Name) //nolint:typecheck
+		return nil
+	}
+	hostUUID := *vm.Host.ExtId
+
+	tCategories, err := n.getTopologyCategories()
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, 2)
+	for _, key := range []string{tCategories.RegionCategory, tCategories.ZoneCategory} {
+		if key != "" && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	hostCategories, err := n.getHostCategories(ctx, nClient, hostUUID, keys)
+	if err != nil {
+		return fmt.Errorf("error occurred while searching for topology info on host %s: %v", hostUUID, err)
+	}
+	return n.setZoneInfoFromCategories(hostCategories, ti)
+}
+
+// getHostCategories returns the categories with one of the keys that are attached to the AHV
+// host. Hosts do not reference their categories, so they are listed with their associations.
+func (n *nutanixManager) getHostCategories(ctx context.Context, nClient interfaces.Prism, hostUUID string, keys []string) ([]prismModels.Category, error) {
+	hostCategories := make([]prismModels.Category, 0)
+	for _, key := range keys {
+		filter := fmt.Sprintf("key eq '%s'", strings.ReplaceAll(key, "'", "''"))
+		categories, err := nClient.ListCategories(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, category := range categories {
+			if isCategoryAssociatedWith(category, prismModels.RESOURCETYPE_HOST, hostUUID) {
+				hostCategories = append(hostCategories, category)
+			}
+		}
+	}
+	return hostCategories, nil
+}
+
+func isCategoryAssociatedWith(category prismModels.Category, resourceType prismModels.ResourceType, resourceUUID string) bool {
+	for _, association := range category.DetailedAssociations {
+		if association.ResourceType != nil && *association.ResourceType == resourceType &&
+			association.ResourceId != nil && *association.ResourceId == resourceUUID {
+			return true
+		}
+	}
+	return false
+}
+
 func (n *nutanixManager) getTopologyInfoFromVM(ctx context.Context, nClient interfaces.Prism, vm *vmmModels.Vm, ti *config.TopologyInfo) error {
 	if vm == nil {
 		return fmt.Errorf("vm cannot be nil when searching for topology info")
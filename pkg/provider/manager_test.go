@@ -26,6 +26,7 @@ import (
 	"github.com/onsi/gomega/gstruct"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
 
 	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
 
@@ -112,11 +113,42 @@ var _ = Describe("Test Manager", func() { // nolint:typecheck
 			Expect(vm).ToNot(BeNil())
 			Expect(m.isVMShutdown(vm)).To(BeFalse())
 		})
+
+		It("should detect if VM is paused", func() { // nolint:typecheck
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+			vm.PowerState = vmmModels.POWERSTATE_PAUSED.Ref()
+			Expect(m.isVMShutdown(vm)).To(BeTrue())
+		})
+
+		It("should not detect VMs in an undetermined power state as shutdown", func() { // nolint:typecheck
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+			vm.PowerState = vmmModels.POWERSTATE_UNDETERMINED.Ref()
+			Expect(m.isVMShutdown(vm)).To(BeFalse())
+		})
+
+		It("should not detect VMs whose power state is not known as shutdown", func() { // nolint:typecheck
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+			for _, powerState := range []*vmmModels.PowerState{nil, vmmModels.POWERSTATE_UNKNOWN.Ref(), vmmModels.POWERSTATE_REDACTED.Ref()} {
+				vm.PowerState = powerState
+				Expect(m.isVMShutdown(vm)).To(BeFalse())
+			}
+			Expect(m.isVMShutdown(nil)).To(BeFalse())
+		})
+
+		It("should detect the configured shutdown power states", func() { // nolint:typecheck
+			m.shutdownPowerStates, err = parseShutdownPowerStates([]string{"OFF", "UNDETERMINED"})
+			Expect(err).ToNot(HaveOccurred())
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+			vm.PowerState = vmmModels.POWERSTATE_UNDETERMINED.Ref()
+			Expect(m.isVMShutdown(vm)).To(BeTrue())
+			vm.PowerState = vmmModels.POWERSTATE_PAUSED.Ref()
+			Expect(m.isVMShutdown(vm)).To(BeFalse())
+		})
 	})
 
 	Context("Test GetNodeAddresses", func() {
 		It("should fail if nil node is passed", func() { // nolint:typecheck
-			_, err := m.getNodeAddresses(ctx, nil)
+			_, err := m.getNodeAddresses(ctx, nClient, nil)
 			Expect(err).Should(HaveOccurred())
 		})
 
@@ -124,7 +156,7 @@ var _ = Describe("Test Manager", func() { // nolint:typecheck
 			vm := mockEnvironment.GetVM(ctx, mock.MockVMNameNoAddresses)
 			Expect(vm).ToNot(BeNil())
 			vm.Nics = nil
-			_, err := m.getNodeAddresses(ctx, vm)
+			_, err := m.getNodeAddresses(ctx, nClient, vm)
 			Expect(err).Should(HaveOccurred())
 		})
 
@@ -136,21 +168,21 @@ var _ = Describe("Test Manager", func() { // nolint:typecheck
 					NicNetworkInfo: nil,
 				},
 			}
-			_, err := m.getNodeAddresses(ctx, vm)
+			_, err := m.getNodeAddresses(ctx, nClient, vm)
 			Expect(err).Should(HaveOccurred())
 		})
 
 		It("should fail if no node addresses are found", func() { // nolint:typecheck
 			vm := mockEnvironment.GetVM(ctx, mock.MockVMNameNoAddresses)
 			Expect(vm).ToNot(BeNil())
-			_, err := m.getNodeAddresses(ctx, vm)
+			_, err := m.getNodeAddresses(ctx, nClient, vm)
 			Expect(err).Should(HaveOccurred())
 		})
 
 		It("should fetch the correct node addresses", func() { // nolint:typecheck
 			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
 			Expect(vm).ToNot(BeNil())
-			addresses, err := m.getNodeAddresses(ctx, vm)
+			addresses, err := m.getNodeAddresses(ctx, nClient, vm)
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(len(addresses)).To(Equal(2))
 			Expect(addresses).Should(
@@ -176,7 +208,7 @@ var _ = Describe("Test Manager", func() { // nolint:typecheck
 		It("should filter node addresses if matching specified filtered addresses", func() { // nolint:typecheck
 			vm := mockEnvironment.GetVM(ctx, mock.MockVMNameFilteredNodeAddresses)
 			Expect(vm).ToNot(BeNil())
-			addresses, err := m.getNodeAddresses(ctx, vm)
+			addresses, err := m.getNodeAddresses(ctx, nClient, vm)
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(len(addresses)).To(Equal(2), "Received addresses: %v", addresses)
 			Expect(addresses).Should(ConsistOf(
@@ -188,7 +220,7 @@ var _ = Describe("Test Manager", func() { // nolint:typecheck
 		It("should fetch the correct node addresses from DpOffloadNicNetworkInfo", func() { // nolint:typecheck
 			vm := mockEnvironment.GetVM(ctx, mock.MockVMNameDpOffload)
 			Expect(vm).ToNot(BeNil())
-			addresses, err := m.getNodeAddresses(ctx, vm)
+			addresses, err := m.getNodeAddresses(ctx, nClient, vm)
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(len(addresses)).To(Equal(2))
 			Expect(addresses).Should(
@@ -214,7 +246,7 @@ var _ = Describe("Test Manager", func() { // nolint:typecheck
 		It("should fetch secondary IP addresses from SecondaryIpAddressList", func() { // nolint:typecheck
 			vm := mockEnvironment.GetVM(ctx, mock.MockVMNameSecondaryIPs)
 			Expect(vm).ToNot(BeNil())
-			addresses, err := m.getNodeAddresses(ctx, vm)
+			addresses, err := m.getNodeAddresses(ctx, nClient, vm)
 			Expect(err).ShouldNot(HaveOccurred())
 			// Should have primary IP, 2 secondary IPs, and hostname = 4 addresses
 			Expect(len(addresses)).To(Equal(4))
@@ -253,6 +285,103 @@ var _ = Describe("Test Manager", func() { // nolint:typecheck
 		})
 	})
 
+	Context("Test GetNodeAddresses with node address rules", func() {
+		managerWithRules := func(rules ...config.NodeAddressRule) *nutanixManager {
+			mgr, err := newNutanixManager(config.Config{NodeAddressRules: rules})
+			Expect(err).ShouldNot(HaveOccurred())
+			mgr.client = m.client
+			mgr.nutanixClient = m.nutanixClient
+			return mgr
+		}
+
+		It("should report the addresses of all NICs as internal IPs without rules", func() { // nolint:typecheck
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNameMultiNIC)
+			Expect(vm).ToNot(BeNil())
+			addresses, err := managerWithRules().getNodeAddresses(ctx, nClient, vm)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(addresses).Should(Equal([]v1.NodeAddress{
+				{Type: v1.NodeInternalIP, Address: mock.MockIP},
+				{Type: v1.NodeInternalIP, Address: mock.MockStorageIP},
+				{Type: v1.NodeHostName, Address: *vm.Name},
+			}))
+		})
+
+		It("should exclude the addresses of the NICs attached to a subnet selected by name", func() { // nolint:typecheck
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNameMultiNIC)
+			Expect(vm).ToNot(BeNil())
+			mgr := managerWithRules(config.NodeAddressRule{
+				SubnetName: mock.MockStorageSubnetName,
+				Type:       config.ExcludedNodeAddressType,
+			})
+			addresses, err := mgr.getNodeAddresses(ctx, nClient, vm)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(addresses).Should(Equal([]v1.NodeAddress{
+				{Type: v1.NodeInternalIP, Address: mock.MockIP},
+				{Type: v1.NodeHostName, Address: *vm.Name},
+			}))
+		})
+
+		It("should report the addresses of the NICs attached to a subnet selected by UUID as external IPs", func() { // nolint:typecheck
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNameMultiNIC)
+			Expect(vm).ToNot(BeNil())
+			mgr := managerWithRules(config.NodeAddressRule{
+				SubnetUUID: mock.MockSubnetUUID,
+				Type:       config.ExternalIPNodeAddressType,
+			})
+			addresses, err := mgr.getNodeAddresses(ctx, nClient, vm)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(addresses).Should(Equal([]v1.NodeAddress{
+				{Type: v1.NodeExternalIP, Address: mock.MockIP},
+				{Type: v1.NodeInternalIP, Address: mock.MockStorageIP},
+				{Type: v1.NodeHostName, Address: *vm.Name},
+			}))
+		})
+
+		It("should apply the first rule matching all of its selectors", func() { // nolint:typecheck
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNameMultiNIC)
+			Expect(vm).ToNot(BeNil())
+			mgr := managerWithRules(
+				config.NodeAddressRule{
+					SubnetName: mock.MockSubnetName,
+					CIDRs:      []string{mock.MockStorageIP + "/32"},
+					Type:       config.ExcludedNodeAddressType,
+				},
+				config.NodeAddressRule{
+					CIDRs: []string{"4.4.4.0/24"},
+					Type:  config.ExcludedNodeAddressType,
+				},
+				config.NodeAddressRule{
+					CIDRs: []string{"0.0.0.0/0"},
+					Type:  config.ExternalIPNodeAddressType,
+				},
+			)
+			addresses, err := mgr.getNodeAddresses(ctx, nClient, vm)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(addresses).Should(Equal([]v1.NodeAddress{
+				{Type: v1.NodeExternalIP, Address: mock.MockIP},
+				{Type: v1.NodeHostName, Address: *vm.Name},
+			}))
+		})
+
+		It("should fail if all addresses are excluded", func() { // nolint:typecheck
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNameMultiNIC)
+			Expect(vm).ToNot(BeNil())
+			mgr := managerWithRules(config.NodeAddressRule{
+				CIDRs: []string{"0.0.0.0/0"},
+				Type:  config.ExcludedNodeAddressType,
+			})
+			_, err := mgr.getNodeAddresses(ctx, nClient, vm)
+			Expect(err).Should(HaveOccurred())
+		})
+
+		It("should fail if the CIDRs of a rule are invalid", func() { // nolint:typecheck
+			_, err := newNutanixManager(config.Config{NodeAddressRules: []config.NodeAddressRule{
+				{CIDRs: []string{"4.4.4.0/33"}, Type: config.ExcludedNodeAddressType},
+			}})
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
 	Context("Test generateProviderID", func() {
 		It("should fail if vmUUID is empty", func() { // nolint:typecheck
 			_, err := m.generateProviderID(ctx, "")
@@ -354,6 +483,59 @@ var _ = Describe("Test Manager", func() { // nolint:typecheck
 		})
 	})
 
+	Context("Test getTopologyInfoFromHost", func() {
+		It("should fail if vm is empty", func() { // nolint:typecheck
+			err := m.getTopologyInfoFromHost(ctx, nClient, nil, &config.TopologyInfo{})
+			Expect(err).Should(HaveOccurred())
+		})
+
+		It("should fail if topologyInfo is empty", func() { // nolint:typecheck
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNameHostCategories)
+			err := m.getTopologyInfoFromHost(ctx, nClient, vm, nil)
+			Expect(err).Should(HaveOccurred())
+		})
+
+		It("should only use the categories associated with the host", func() { // nolint:typecheck
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+			ti := config.TopologyInfo{}
+			Expect(m.getTopologyInfoFromHost(ctx, nClient, vm, &ti)).To(Succeed())
+			Expect(ti).To(Equal(config.TopologyInfo{}))
+		})
+	})
+
+	Context("Test getTopologyInfoUsingCategories", func() {
+		It("should use the categories of the host of the VM", func() { // nolint:typecheck
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNameHostCategories)
+			ti := config.TopologyInfo{}
+			Expect(m.getTopologyInfoUsingCategories(ctx, nClient, vm, &ti)).To(Succeed())
+			Expect(ti).To(Equal(config.TopologyInfo{Region: mock.MockRegion, Zone: mock.MockHostZone}))
+		})
+
+		It("should prefer the categories of the VM over the categories of the host", func() { // nolint:typecheck
+			vm := *mockEnvironment.GetVM(ctx, mock.MockVMNameCategories)
+			vm.Host = &vmmModels.HostReference{ExtId: ptr.To(mock.MockHostCategoriesUUID)}
+			ti := config.TopologyInfo{}
+			Expect(m.getTopologyInfoUsingCategories(ctx, nClient, &vm, &ti)).To(Succeed())
+			Expect(ti).To(Equal(config.TopologyInfo{Region: mock.MockRegion, Zone: mock.MockZone}))
+		})
+
+		It("should skip the host if the VM has no host", func() { // nolint:typecheck
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOff)
+			ti := config.TopologyInfo{}
+			Expect(m.getTopologyInfoUsingCategories(ctx, nClient, vm, &ti)).To(Succeed())
+			Expect(ti).To(Equal(config.TopologyInfo{}))
+		})
+
+		It("should fall back to the categories of Prism Central", func() { // nolint:typecheck
+			pc := mockEnvironment.GetCluster(ctx, mock.MockPrismCentral)
+			pc.Categories = []string{mock.MockCategoryRegionUUID, mock.MockCategoryZoneUUID}
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+			ti := config.TopologyInfo{}
+			Expect(m.getTopologyInfoUsingCategories(ctx, nClient, vm, &ti)).To(Succeed())
+			Expect(ti).To(Equal(config.TopologyInfo{Region: mock.MockRegion, Zone: mock.MockZone}))
+		})
+	})
+
 	Context("Test getTopologyInfoUsingPrism", func() {
 		It("should fail if nutanixClient is empty", func() { // nolint:typecheck
 			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
@@ -10,7 +10,11 @@ import (
 	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
 	"go4.org/netipx"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/utils/ptr"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
 )
 
 func TestIsNodeAddressesSet(t *testing.T) {
@@ -95,6 +99,7 @@ func TestGetNodeAddresses(t *testing.T) {
 	tests := []struct {
 		name          string
 		vm            *vmmModels.Vm
+		addressFamily config.AddressFamilyType
 		wantErr       bool
 		wantAddresses []v1.NodeAddress
 	}{
@@ -223,15 +228,139 @@ func TestGetNodeAddresses(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "dual-stack VM: IPv4 addresses appear first by default and link-local IPv6 addresses are skipped",
+			vm: vmWithNICS(
+				t,
+				"my-vm",
+				"uuid-4",
+				[]vmmModels.Nic{
+					withLearnedIPv6s(t, nicWithIPs(t, "10.0.0.1", nil, nil), "fd00::1", "fe80::1"),
+					withLearnedIPv6s(t, nicWithIPs(t, "10.0.0.2", nil, nil), "FD00:0:0:0::2"),
+				}),
+			wantAddresses: []v1.NodeAddress{
+				{
+					Type:    v1.NodeInternalIP,
+					Address: "10.0.0.1",
+				},
+				{
+					Type:    v1.NodeInternalIP,
+					Address: "10.0.0.2",
+				},
+				{
+					Type:    v1.NodeInternalIP,
+					Address: "fd00::1",
+				},
+				{
+					Type:    v1.NodeInternalIP,
+					Address: "fd00::2",
+				},
+				{
+					Type:    v1.NodeHostName,
+					Address: "my-vm",
+				},
+			},
+		},
+		{
+			name: "dual-stack VM with IPv6First: IPv6 addresses appear first",
+			vm: vmWithNICS(
+				t,
+				"my-vm",
+				"uuid-5",
+				[]vmmModels.Nic{
+					withLearnedIPv6s(t, nicWithIPs(t, "10.0.0.1", nil, nil), "fd00::1"),
+					withLearnedIPv6s(t, nicWithIPs(t, "10.0.0.2", nil, nil), "fd00::2"),
+				}),
+			addressFamily: config.IPv6FirstAddressFamilyType,
+			wantAddresses: []v1.NodeAddress{
+				{
+					Type:    v1.NodeInternalIP,
+					Address: "fd00::1",
+				},
+				{
+					Type:    v1.NodeInternalIP,
+					Address: "fd00::2",
+				},
+				{
+					Type:    v1.NodeInternalIP,
+					Address: "10.0.0.1",
+				},
+				{
+					Type:    v1.NodeInternalIP,
+					Address: "10.0.0.2",
+				},
+				{
+					Type:    v1.NodeHostName,
+					Address: "my-vm",
+				},
+			},
+		},
+		{
+			name: "dual-stack VM with IPv4Only: only IPv4 addresses appear",
+			vm: vmWithNICS(
+				t,
+				"my-vm",
+				"uuid-6",
+				[]vmmModels.Nic{
+					withLearnedIPv6s(t, nicWithIPs(t, "10.0.0.1", nil, nil), "fd00::1"),
+				}),
+			addressFamily: config.IPv4OnlyAddressFamilyType,
+			wantAddresses: []v1.NodeAddress{
+				{
+					Type:    v1.NodeInternalIP,
+					Address: "10.0.0.1",
+				},
+				{
+					Type:    v1.NodeHostName,
+					Address: "my-vm",
+				},
+			},
+		},
+		{
+			name: "dual-stack VM with IPv6Only: only IPv6 addresses appear, ignored IPv6 addresses are skipped",
+			vm: vmWithNICS(
+				t,
+				"my-vm",
+				"uuid-7",
+				[]vmmModels.Nic{
+					withLearnedIPv6s(t, nicWithIPs(t, "10.0.0.1", nil, nil), "fd00::1", "fd00::99"),
+				}),
+			addressFamily: config.IPv6OnlyAddressFamilyType,
+			wantAddresses: []v1.NodeAddress{
+				{
+					Type:    v1.NodeInternalIP,
+					Address: "fd00::1",
+				},
+				{
+					Type:    v1.NodeHostName,
+					Address: "my-vm",
+				},
+			},
+		},
+		{
+			name: "IPv4-only VM with IPv6Only returns error",
+			vm: vmWithNICS(
+				t,
+				"my-vm",
+				"uuid-8",
+				[]vmmModels.Nic{
+					nicWithIPs(t, "10.0.0.1", nil, nil),
+				}),
+			addressFamily: config.IPv6OnlyAddressFamilyType,
+			wantErr:       true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			m := &nutanixManager{
+				config: config.Config{
+					AddressFamily: tt.addressFamily,
+				},
 				// We have to initialize ignoredNodeIPs, or the test will fail with a nil pointer dereference.
-				ignoredNodeIPs: ignoredIPSet("10.0.0.99"),
+				ignoredNodeIPs: ignoredIPSet("10.0.0.99", "fd00::99"),
 			}
-			gotAddresses, err := m.getNodeAddresses(ctx, tt.vm)
+			gotAddresses, err := m.getNodeAddresses(ctx, nil, tt.vm)
 			if tt.wantErr {
 				if err == nil {
 					t.Errorf("getNodeAddresses() expected error, got nil")
@@ -290,6 +419,23 @@ func nicWithIPs(t *testing.T, primaryIP string, secondaryIPs []string, learnedIP
 	return *nic
 }
 
+// withLearnedIPv6s returns the NIC with the given learned IPv6 addresses.
+func withLearnedIPv6s(t *testing.T, nic vmmModels.Nic, learnedIPs ...string) vmmModels.Nic {
+	t.Helper()
+	netInfo := nic.NicNetworkInfo.GetValue().(vmmModels.VirtualEthernetNicNetworkInfo)
+	netInfo.Ipv6Info = vmmModels.NewIpv6Info()
+	for _, learnedIP := range learnedIPs {
+		netInfo.Ipv6Info.LearnedIpv6Addresses = append(
+			netInfo.Ipv6Info.LearnedIpv6Addresses,
+			vmmCommonModels.IPv6Address{Value: ptr.To(learnedIP)},
+		)
+	}
+	if err := nic.SetNicNetworkInfo(netInfo); err != nil {
+		t.Fatalf("SetNicNetworkInfo: %v", err)
+	}
+	return nic
+}
+
 // vmWithNICS returns a VM with the given NICs.
 func vmWithNICS(t *testing.T, name, uuid string, nics []vmmModels.Nic) *vmmModels.Vm {
 	t.Helper()
@@ -300,6 +446,364 @@ func vmWithNICS(t *testing.T, name, uuid string, nics []vmmModels.Nic) *vmmModel
 	}
 }
 
+func TestInstanceTypeFromVM(t *testing.T) {
+	tests := []struct {
+		name string
+		vm   *vmmModels.Vm
+		want string
+	}{
+		{
+			name: "vCPUs and memory",
+			vm: &vmmModels.Vm{
+				NumSockets:        ptr.To(2),
+				NumCoresPerSocket: ptr.To(4),
+				NumThreadsPerCore: ptr.To(1),
+				MemorySizeBytes:   ptr.To(int64(32 << 30)),
+			},
+			want: "ahv-8c-32g",
+		},
+		{
+			name: "threads per core",
+			vm: &vmmModels.Vm{
+				NumSockets:        ptr.To(1),
+				NumCoresPerSocket: ptr.To(2),
+				NumThreadsPerCore: ptr.To(2),
+				MemorySizeBytes:   ptr.To(int64(8 << 30)),
+			},
+			want: "ahv-4c-8g",
+		},
+		{
+			name: "cores per socket not set",
+			vm: &vmmModels.Vm{
+				NumSockets:      ptr.To(4),
+				MemorySizeBytes: ptr.To(int64(4 << 30)),
+			},
+			want: "ahv-4c-4g",
+		},
+		{
+			name: "memory not a whole number of GiB",
+			vm: &vmmModels.Vm{
+				NumSockets:      ptr.To(2),
+				MemorySizeBytes: ptr.To(int64(1536 << 20)),
+			},
+			want: "ahv-2c-1536m",
+		},
+		{
+			name: "GPUs",
+			vm: &vmmModels.Vm{
+				NumSockets:        ptr.To(4),
+				NumCoresPerSocket: ptr.To(4),
+				MemorySizeBytes:   ptr.To(int64(64 << 30)),
+				Gpus:              []vmmModels.Gpu{{}, {}},
+			},
+			want: "ahv-16c-64g-2gpu",
+		},
+		{
+			name: "resources not known",
+			vm:   &vmmModels.Vm{NumSockets: ptr.To(2)},
+			want: constants.InstanceType,
+		},
+		{
+			name: "nil VM",
+			want: constants.InstanceType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := instanceTypeFromVM(tt.vm); got != tt.want {
+				t.Errorf("instanceTypeFromVM() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesInstanceType(t *testing.T) {
+	vm := &vmmModels.Vm{
+		NumSockets:        ptr.To(2),
+		NumCoresPerSocket: ptr.To(4),
+		MemorySizeBytes:   ptr.To(int64(32 << 30)),
+	}
+	gpuVM := &vmmModels.Vm{
+		NumSockets:      ptr.To(8),
+		MemorySizeBytes: ptr.To(int64(64 << 30)),
+		Gpus:            []vmmModels.Gpu{{}},
+	}
+	categories := map[string][]string{"Environment": {"Production"}}
+
+	tests := []struct {
+		name         string
+		instanceType config.InstanceTypeConfig
+		vm           *vmmModels.Vm
+		want         bool
+	}{
+		{
+			name:         "no rules",
+			instanceType: config.InstanceTypeConfig{Name: "any"},
+			vm:           vm,
+			want:         true,
+		},
+		{
+			name:         "vCPUs within bounds",
+			instanceType: config.InstanceTypeConfig{Name: "medium", MinVCPUs: 4, MaxVCPUs: 8},
+			vm:           vm,
+			want:         true,
+		},
+		{
+			name:         "vCPUs above maximum",
+			instanceType: config.InstanceTypeConfig{Name: "small", MaxVCPUs: 4},
+			vm:           vm,
+			want:         false,
+		},
+		{
+			name:         "vCPUs not known",
+			instanceType: config.InstanceTypeConfig{Name: "small", MaxVCPUs: 4},
+			vm:           &vmmModels.Vm{MemorySizeBytes: ptr.To(int64(32 << 30))},
+			want:         false,
+		},
+		{
+			name:         "memory within bounds",
+			instanceType: config.InstanceTypeConfig{Name: "medium", MinMemory: ptr.To(resource.MustParse("32Gi")), MaxMemory: ptr.To(resource.MustParse("64Gi"))},
+			vm:           vm,
+			want:         true,
+		},
+		{
+			name:         "memory below minimum",
+			instanceType: config.InstanceTypeConfig{Name: "large", MinMemory: ptr.To(resource.MustParse("64Gi"))},
+			vm:           vm,
+			want:         false,
+		},
+		{
+			name:         "GPU required",
+			instanceType: config.InstanceTypeConfig{Name: "gpu", GPU: ptr.To(true)},
+			vm:           gpuVM,
+			want:         true,
+		},
+		{
+			name:         "GPU required but not attached",
+			instanceType: config.InstanceTypeConfig{Name: "gpu", GPU: ptr.To(true)},
+			vm:           vm,
+			want:         false,
+		},
+		{
+			name:         "GPU excluded",
+			instanceType: config.InstanceTypeConfig{Name: "cpu", GPU: ptr.To(false)},
+			vm:           gpuVM,
+			want:         false,
+		},
+		{
+			name:         "categories",
+			instanceType: config.InstanceTypeConfig{Name: "production", Categories: map[string]string{"Environment": "Production"}},
+			vm:           vm,
+			want:         true,
+		},
+		{
+			name:         "categories not assigned",
+			instanceType: config.InstanceTypeConfig{Name: "staging", Categories: map[string]string{"Environment": "Staging"}},
+			vm:           vm,
+			want:         false,
+		},
+		{
+			name:         "nil VM",
+			instanceType: config.InstanceTypeConfig{Name: "any"},
+			want:         false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesInstanceType(tt.instanceType, tt.vm, categories); got != tt.want {
+				t.Errorf("matchesInstanceType() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCategoryLabelKeys(t *testing.T) {
+	tests := []struct {
+		name           string
+		categoryLabels *config.CategoryLabelsConfig
+		want           map[string]string
+		wantErr        bool
+	}{
+		{
+			name: "not configured",
+		},
+		{
+			name: "prefixed category keys",
+			categoryLabels: &config.CategoryLabelsConfig{
+				AllowedKeys: []string{"Environment", "Cost Center"},
+				Prefix:      "categories.nutanix.com/",
+			},
+			want: map[string]string{
+				"Environment": "categories.nutanix.com/Environment",
+				"Cost Center": "categories.nutanix.com/Cost_Center",
+			},
+		},
+		{
+			name: "mapped category keys",
+			categoryLabels: &config.CategoryLabelsConfig{
+				AllowedKeys: []string{"Environment", "AppTier"},
+				LabelKeys:   map[string]string{"AppTier": "example.com/tier"},
+			},
+			want: map[string]string{
+				"Environment": "Environment",
+				"AppTier":     "example.com/tier",
+			},
+		},
+		{
+			name: "invalid label key",
+			categoryLabels: &config.CategoryLabelsConfig{
+				AllowedKeys: []string{"Environment"},
+				Prefix:      "invalid prefix/",
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate label keys",
+			categoryLabels: &config.CategoryLabelsConfig{
+				AllowedKeys: []string{"Cost Center", "Cost@Center"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCategoryLabelKeys(tt.categoryLabels)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCategoryLabelKeys() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("parseCategoryLabelKeys() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetVMCustomAttributes(t *testing.T) {
+	tests := []struct {
+		name string
+		vm   *vmmModels.Vm
+		want map[string]string
+	}{
+		{
+			name: "nil VM",
+			want: map[string]string{},
+		},
+		{
+			name: "key:value custom attributes",
+			vm: &vmmModels.Vm{CustomAttributes: []string{
+				"Workload: gpu",
+				"url:https://example.com",
+				"empty:",
+				"no separator",
+				"Workload:cpu",
+			}},
+			want: map[string]string{
+				"Workload": "gpu",
+				"url":      "https://example.com",
+				"empty":    "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, getVMCustomAttributes(tt.vm)); diff != "" {
+				t.Errorf("getVMCustomAttributes() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetVMCustomAttributeIgnoreCase(t *testing.T) {
+	tests := []struct {
+		name string
+		vm   *vmmModels.Vm
+		key  string
+		want string
+	}{
+		{
+			name: "nil VM",
+			key:  "providerID",
+			want: "",
+		},
+		{
+			name: "key in another case with whitespace",
+			vm:   &vmmModels.Vm{CustomAttributes: []string{" providerid : custom-id"}},
+			key:  "providerID",
+			want: "custom-id",
+		},
+		{
+			name: "first non-empty value",
+			vm:   &vmmModels.Vm{CustomAttributes: []string{"providerID:", "no separator", "PROVIDERID:first", "providerID:second"}},
+			key:  "providerID",
+			want: "first",
+		},
+		{
+			name: "missing key",
+			vm:   &vmmModels.Vm{CustomAttributes: []string{"otherKey:value"}},
+			key:  "providerID",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getVMCustomAttributeIgnoreCase(tt.vm, tt.key); got != tt.want {
+				t.Errorf("getVMCustomAttributeIgnoreCase() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseShutdownPowerStates(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		want    []vmmModels.PowerState
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			want: []vmmModels.PowerState{vmmModels.POWERSTATE_OFF, vmmModels.POWERSTATE_PAUSED},
+		},
+		{
+			name:  "configured power states",
+			names: []string{"off", "UNDETERMINED"},
+			want:  []vmmModels.PowerState{vmmModels.POWERSTATE_OFF, vmmModels.POWERSTATE_UNDETERMINED},
+		},
+		{
+			name:    "unsupported power state",
+			names:   []string{"OFF", "SUSPENDED"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown power state",
+			names:   []string{"$UNKNOWN"},
+			wantErr: true,
+		},
+		{
+			name:    "running power state",
+			names:   []string{"OFF", "ON"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseShutdownPowerStates(tt.names)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseShutdownPowerStates() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("parseShutdownPowerStates() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestSanitizeK8sLabelValue(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -341,3 +845,140 @@ func TestSanitizeK8sLabelValue(t *testing.T) {
 		})
 	}
 }
+
+func TestParseIPSet(t *testing.T) {
+	tests := []struct {
+		name        string
+		entries     []string
+		wantErr     bool
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:        "IPv4 addresses, prefixes and ranges",
+			entries:     []string{"10.0.0.1", "10.0.1.0/24", "10.0.2.1-10.0.2.10"},
+			contains:    []string{"10.0.0.1", "10.0.1.200", "10.0.2.10"},
+			notContains: []string{"10.0.0.2", "10.0.2.11", "fd00::1"},
+		},
+		{
+			name:        "IPv6 addresses, prefixes and ranges",
+			entries:     []string{"fd00::1", "fd00:1::/64", "fd00:2::1-fd00:2::10"},
+			contains:    []string{"fd00::1", "fd00:1::abcd", "fd00:2::10"},
+			notContains: []string{"fd00::2", "fd00:2::11", "10.0.0.1"},
+		},
+		{
+			name:        "IPv4-mapped IPv6 entries match IPv4 addresses",
+			entries:     []string{"::ffff:10.0.0.1", "::ffff:10.0.1.0/120", "::ffff:10.0.2.1-::ffff:10.0.2.10"},
+			contains:    []string{"10.0.0.1", "10.0.1.200", "10.0.2.5"},
+			notContains: []string{"10.0.0.2"},
+		},
+		{
+			name:    "IPv6 zones are not supported",
+			entries: []string{"fe80::1%eth0"},
+			wantErr: true,
+		},
+		{
+			name:    "ranges mixing IPv4 and IPv6 are not supported",
+			entries: []string{"10.0.0.1-fd00::1"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ipSet, err := parseIPSet("ignoredNodeIPs", tt.entries)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseIPSet() expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseIPSet() err = %v", err)
+			}
+			for _, ip := range tt.contains {
+				if !ipSet.Contains(netip.MustParseAddr(ip)) {
+					t.Errorf("parseIPSet() does not contain %s", ip)
+				}
+			}
+			for _, ip := range tt.notContains {
+				if ipSet.Contains(netip.MustParseAddr(ip)) {
+					t.Errorf("parseIPSet() contains %s", ip)
+				}
+			}
+		})
+	}
+}
+
+func TestParseVIPPool(t *testing.T) {
+	tests := []struct {
+		name        string
+		ipPools     []string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:        "network and broadcast addresses of IPv4 prefixes are excluded",
+			ipPools:     []string{"10.0.0.0/24", "10.0.1.0/31", "10.0.2.1-10.0.2.255"},
+			contains:    []string{"10.0.0.1", "10.0.0.254", "10.0.1.0", "10.0.1.1", "10.0.2.255"},
+			notContains: []string{"10.0.0.0", "10.0.0.255"},
+		},
+		{
+			name:        "network and broadcast addresses of unmasked IPv4 prefixes are excluded",
+			ipPools:     []string{"10.0.0.7/24"},
+			contains:    []string{"10.0.0.1", "10.0.0.254"},
+			notContains: []string{"10.0.0.0", "10.0.0.255"},
+		},
+		{
+			name:        "network and broadcast addresses of IPv4-mapped IPv6 prefixes are excluded",
+			ipPools:     []string{"::ffff:10.0.0.0/120"},
+			contains:    []string{"10.0.0.1", "10.0.0.254"},
+			notContains: []string{"10.0.0.0", "10.0.0.255"},
+		},
+		{
+			name:     "IPv6 prefixes are kept whole",
+			ipPools:  []string{"fd00::/120"},
+			contains: []string{"fd00::", "fd00::ff"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, err := parseVIPPool(tt.ipPools)
+			if err != nil {
+				t.Fatalf("parseVIPPool() err = %v", err)
+			}
+			for _, ip := range tt.contains {
+				if !pool.Contains(netip.MustParseAddr(ip)) {
+					t.Errorf("parseVIPPool() does not contain %s", ip)
+				}
+			}
+			for _, ip := range tt.notContains {
+				if pool.Contains(netip.MustParseAddr(ip)) {
+					t.Errorf("parseVIPPool() contains %s", ip)
+				}
+			}
+		})
+	}
+}
+
+func TestIsUUID(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want bool
+	}{
+		{name: "lowercase UUID", s: "00000000-0000-0000-0000-00000000010a", want: true},
+		{name: "uppercase UUID", s: "00000000-0000-0000-0000-00000000010A", want: true},
+		{name: "custom providerID", s: "custom-provider-uuid-1234", want: false},
+		{name: "UUID with suffix", s: "00000000-0000-0000-0000-000000000100-1", want: false},
+		{name: "empty", s: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isUUID(tt.s); got != tt.want {
+				t.Errorf("isUUID(%q) = %v, want %v", tt.s, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nutanix-cloud-native/prism-go-client/converged"
+	convergedV4 "github.com/nutanix-cloud-native/prism-go-client/converged/v4"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const (
+	metricsNamespace  = "cloudprovider_nutanix"
+	prismAPISubsystem = "prism_api"
+)
+
+// Error kinds of failed Prism Central API requests
+const (
+	notFoundErrorKind  = "not_found"
+	authErrorKind      = "auth"
+	timeoutErrorKind   = "timeout"
+	rateLimitErrorKind = "rate_limit"
+	internalErrorKind  = "internal"
+	otherErrorKind     = "other"
+)
+
+// Results of config reloads
+const (
+	configReloadSuccess = "success"
+	configReloadFailure = "failure"
+)
+
+var (
+	prismAPIRequestsTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      prismAPISubsystem,
+			Name:           "requests_total",
+			Help:           "Number of Prism Central API requests by operation.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"operation"},
+	)
+
+	prismAPIRequestDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      prismAPISubsystem,
+			Name:           "request_duration_seconds",
+			Help:           "Latency of Prism Central API requests by operation.",
+			Buckets:        metrics.ExponentialBuckets(0.01, 2, 12),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"operation"},
+	)
+
+	prismAPIRequestErrorsTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      prismAPISubsystem,
+			Name:           "request_errors_total",
+			Help:           "Number of failed Prism Central API requests by operation and error kind.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"operation", "error_kind"},
+	)
+
+	configReloadsTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Name:           "config_reloads_total",
+			Help:           "Number of config reloads by result.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"result"},
+	)
+
+	credentialRotationsTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Name:           "credential_rotations_total",
+			Help:           "Number of rotations of the Prism Central credentials and trust bundle by Prism Central and kind.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"prism_central", "kind"},
+	)
+
+	registerMetricsOnce sync.Once
+)
+
+// registerMetrics registers the metrics with the legacy registry served on the /metrics
+// endpoint of the cloud controller manager.
+func registerMetrics() {
+	registerMetricsOnce.Do(func() {
+		legacyregistry.MustRegister(prismAPIRequestsTotal)
+		legacyregistry.MustRegister(prismAPIRequestDuration)
+		legacyregistry.MustRegister(prismAPIRequestErrorsTotal)
+		legacyregistry.MustRegister(configReloadsTotal)
+		legacyregistry.MustRegister(credentialRotationsTotal)
+	})
+}
+
+// observePrismAPIRequest records a Prism Central API request started at start. It is meant to
+// be deferred with a pointer to the returned error.
+func observePrismAPIRequest(operation string, start time.Time, err *error) {
+	prismAPIRequestsTotal.WithLabelValues(operation).Inc()
+	prismAPIRequestDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil && *err != nil {
+		prismAPIRequestErrorsTotal.WithLabelValues(operation, prismErrorKind(*err)).Inc()
+	}
+}
+
+// prismErrorKind classifies the error of a failed Prism Central API request.
+func prismErrorKind(err error) string {
+	switch {
+	case converged.IsNotFound(err):
+		return notFoundErrorKind
+	case converged.IsRateLimit(err):
+		return rateLimitErrorKind
+	case isAuthError(err):
+		return authErrorKind
+	case isTimeoutError(err):
+		return timeoutErrorKind
+	case converged.IsInternal(err):
+		return internalErrorKind
+	default:
+		return otherErrorKind
+	}
+}
+
+func isAuthError(err error) bool {
+	code := prismErrorStatusCode(err)
+	return code == http.StatusUnauthorized || code == http.StatusForbidden
+}
+
+func isTimeoutError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// prismErrorStatusCode returns the HTTP status code of the SDK error wrapped by err, or 0.
+func prismErrorStatusCode(err error) int {
+	for ; err != nil; err = errors.Unwrap(err) {
+		status, _ := convergedV4.GetStatusAndBody(err)
+		if status == "" {
+			continue
+		}
+		if code, convErr := strconv.Atoi(strings.SplitN(status, " ", 2)[0]); convErr == nil {
+			return code
+		}
+	}
+	return 0
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:typecheck // Test file uses ginkgo/gomega which typecheck doesn't understand well
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nutanix-cloud-native/prism-go-client/converged"
+	convergedV4 "github.com/nutanix-cloud-native/prism-go-client/converged/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+// fakeOpenAPIError mimics the GenericOpenAPIError of the v4 SDK clients.
+type fakeOpenAPIError struct {
+	Status string
+	Body   []byte
+}
+
+func (e *fakeOpenAPIError) Error() string {
+	return e.Status
+}
+
+var _ = Describe("Test Prism API metrics", func() { // nolint:typecheck
+	BeforeEach(func() {
+		registerMetrics()
+		prismAPIRequestsTotal.Reset()
+		prismAPIRequestDuration.Reset()
+		prismAPIRequestErrorsTotal.Reset()
+	})
+
+	DescribeTable("prismErrorKind",
+		func(err error, expected string) {
+			Expect(prismErrorKind(err)).To(Equal(expected))
+		},
+		Entry("not found", convergedV4.CategoriseFromOpenAPI(&fakeOpenAPIError{Status: "404 Not Found"}), notFoundErrorKind),
+		Entry("wrapped not found", fmt.Errorf("failed to get VM: %w", converged.ErrNotFound), notFoundErrorKind),
+		Entry("rate limit", convergedV4.CategoriseFromOpenAPI(&fakeOpenAPIError{Status: "429 Too Many Requests"}), rateLimitErrorKind),
+		Entry("unauthorized", convergedV4.CategoriseFromOpenAPI(&fakeOpenAPIError{Status: "401 Unauthorized"}), authErrorKind),
+		Entry("forbidden", fmt.Errorf("failed to get VPC: %w", &fakeOpenAPIError{Status: "403 Forbidden"}), authErrorKind),
+		Entry("deadline exceeded", fmt.Errorf("failed to list clusters: %w", context.DeadlineExceeded), timeoutErrorKind),
+		Entry("internal", convergedV4.CategoriseFromOpenAPI(&fakeOpenAPIError{Status: "503 Service Unavailable"}), internalErrorKind),
+		Entry("other", errors.New("unexpected response"), otherErrorKind),
+	)
+
+	It("should record requests, latencies and errors by operation", func() {
+		observe := func(operation string, err error) {
+			defer observePrismAPIRequest(operation, time.Now(), &err)
+		}
+		observe("GetVM", nil)
+		observe("GetVM", convergedV4.CategoriseFromOpenAPI(&fakeOpenAPIError{Status: "404 Not Found"}))
+		observe("GetCluster", context.DeadlineExceeded)
+
+		requests, err := testutil.GetCounterMetricValue(prismAPIRequestsTotal.WithLabelValues("GetVM"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requests).To(Equal(2.0))
+		observations, err := testutil.GetHistogramMetricCount(prismAPIRequestDuration.WithLabelValues("GetVM"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(observations).To(Equal(uint64(2)))
+
+		errorsTotal, err := testutil.GetCounterMetricValue(prismAPIRequestErrorsTotal.WithLabelValues("GetVM", notFoundErrorKind))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(errorsTotal).To(Equal(1.0))
+		errorsTotal, err = testutil.GetCounterMetricValue(prismAPIRequestErrorsTotal.WithLabelValues("GetCluster", timeoutErrorKind))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(errorsTotal).To(Equal(1.0))
+	})
+})
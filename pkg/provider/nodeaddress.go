@@ -0,0 +1,127 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"go4.org/netipx"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+// nodeAddressRule is a config.NodeAddressRule with its CIDRs parsed.
+type nodeAddressRule struct {
+	config.NodeAddressRule
+	cidrs *netipx.IPSet
+}
+
+func parseNodeAddressRules(rules []config.NodeAddressRule) ([]nodeAddressRule, error) {
+	parsed := make([]nodeAddressRule, 0, len(rules))
+	for i, rule := range rules {
+		parsedRule := nodeAddressRule{NodeAddressRule: rule}
+		if len(rule.CIDRs) > 0 {
+			cidrs, err := parseIPSet(fmt.Sprintf("nodeAddressRules[%d].cidrs", i), rule.CIDRs)
+			if err != nil {
+				return nil, err
+			}
+			parsedRule.cidrs = cidrs
+		}
+		parsed = append(parsed, parsedRule)
+	}
+	return parsed, nil
+}
+
+// nodeAddressClassifier assigns a type to node addresses using the node address rules.
+// Subnet names are only looked up in the Prism Central of the VM when a rule selects subnets by
+// name, and are cached for the lifetime of the classifier.
+type nodeAddressClassifier struct {
+	nutanixManager *nutanixManager
+	nClient        interfaces.Prism
+	subnetNames    map[string]string
+}
+
+func (n *nutanixManager) newNodeAddressClassifier(nClient interfaces.Prism) *nodeAddressClassifier {
+	return &nodeAddressClassifier{
+		nutanixManager: n,
+		nClient:        nClient,
+		subnetNames:    make(map[string]string),
+	}
+}
+
+// classify returns the type of the address of a NIC attached to the subnet, or false if the
+// address is excluded.
+func (c *nodeAddressClassifier) classify(ctx context.Context, subnetUUID string, address netip.Addr) (v1.NodeAddressType, bool, error) {
+	for _, rule := range c.nutanixManager.nodeAddressRules {
+		matches, err := c.matches(ctx, rule, subnetUUID, address)
+		if err != nil {
+			return "", false, err
+		}
+		if !matches {
+			continue
+		}
+		switch rule.Type {
+		case config.ExternalIPNodeAddressType:
+			return v1.NodeExternalIP, true, nil
+		case config.ExcludedNodeAddressType:
+			return "", false, nil
+		default:
+			return v1.NodeInternalIP, true, nil
+		}
+	}
+	return v1.NodeInternalIP, true, nil
+}
+
+func (c *nodeAddressClassifier) matches(ctx context.Context, rule nodeAddressRule, subnetUUID string, address netip.Addr) (bool, error) {
+	if rule.SubnetUUID != "" && rule.SubnetUUID != subnetUUID {
+		return false, nil
+	}
+	if rule.cidrs != nil && !rule.cidrs.Contains(address) {
+		return false, nil
+	}
+	if rule.SubnetName != "" {
+		subnetName, err := c.subnetName(ctx, subnetUUID)
+		if err != nil {
+			return false, err
+		}
+		if rule.SubnetName != subnetName {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (c *nodeAddressClassifier) subnetName(ctx context.Context, subnetUUID string) (string, error) {
+	if subnetUUID == "" {
+		return "", nil
+	}
+	if name, ok := c.subnetNames[subnetUUID]; ok {
+		return name, nil
+	}
+	subnet, err := c.nClient.GetSubnet(ctx, subnetUUID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get subnet %s: %w", subnetUUID, err)
+	}
+	name := ptr.Deref(subnet.Name, "")
+	c.subnetNames[subnetUUID] = name
+	return name, nil
+}
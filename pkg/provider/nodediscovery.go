@@ -0,0 +1,211 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nutanix-cloud-native/prism-go-client/converged"
+	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+// getNutanixInstanceIDForNode returns the UUID of the VM of the node.
+func (n *nutanixManager) getNutanixInstanceIDForNode(ctx context.Context, node *v1.Node) (string, error) {
+	if node == nil {
+		return "", fmt.Errorf("node cannot be nil when getting nutanix instance ID for node")
+	}
+	nodeVM, err := n.getNodeVM(ctx, node)
+	if err != nil {
+		return "", err
+	}
+	vm := nodeVM.vm
+	if vm.ExtId == nil || *vm.ExtId == "" {
+		return "", fmt.Errorf("VM of node %s has no UUID", node.Name)
+	}
+	return strings.ToLower(*vm.ExtId), nil
+}
+
+// findNodeVM returns the VM of the node found in the Prism Central by the first node discovery
+// strategy finding one. It returns a not found error if a strategy applies to the node but none
+// finds a VM, and an error if no strategy applies, e.g. if the node has neither a system UUID
+// nor a providerID.
+func (n *nutanixManager) findNodeVM(ctx context.Context, nClient interfaces.Prism, node *v1.Node) (*vmmModels.Vm, error) {
+	if node == nil {
+		return nil, fmt.Errorf("node cannot be nil when getting the VM of the node")
+	}
+
+	nodeDiscovery := n.getNodeDiscoveryConfig()
+	var tried []config.NodeDiscoveryStrategy
+	for _, strategy := range nodeDiscovery.Strategies {
+		vm, err := n.getNodeVMWithStrategy(ctx, nClient, node, strategy, nodeDiscovery)
+		if err != nil {
+			if converged.IsNotFound(err) {
+				tried = append(tried, strategy)
+				continue
+			}
+			return nil, err
+		}
+		if vm == nil {
+			continue
+		}
+		if len(tried) > 0 {
+			klog.V(1).Infof("found VM %s of node %s with node discovery strategy %s after %v", *vm.ExtId, node.Name, strategy, tried) //nolint:typecheck
+		}
+		return vm, nil
+	}
+
+	if len(tried) == 0 {
+		return nil, fmt.Errorf("failed to retrieve node UUID for node with name %s: none of the node discovery strategies %v applies", node.Name, nodeDiscovery.Strategies)
+	}
+	return nil, &converged.APIError{
+		Kind:    converged.ErrNotFound,
+		Message: fmt.Sprintf("VM of node %s not found with node discovery strategies %v", node.Name, tried),
+	}
+}
+
+// getNodeVMWithStrategy returns the VM of the node found by the strategy, nil if the strategy
+// does not apply to the node, or a not found error.
+func (n *nutanixManager) getNodeVMWithStrategy(ctx context.Context, nClient interfaces.Prism, node *v1.Node, strategy config.NodeDiscoveryStrategy, nodeDiscovery *config.NodeDiscoveryConfig) (*vmmModels.Vm, error) {
+	switch strategy {
+	case config.SystemUUIDNodeDiscoveryStrategy:
+		systemUUID := node.Status.NodeInfo.SystemUUID
+		if systemUUID == "" {
+			return nil, nil
+		}
+		return nClient.GetVM(ctx, strings.ToLower(systemUUID))
+	case config.ProviderIDNodeDiscoveryStrategy:
+		return n.getVMByProviderID(ctx, nClient, node)
+	case config.VMNameNodeDiscoveryStrategy:
+		filter := fmt.Sprintf("name eq '%s'", escapeODataString(node.Name))
+		return getSingleVM(ctx, nClient, node, filter, fmt.Sprintf("named %s", node.Name), func(vm *vmmModels.Vm) bool {
+			return vm.Name != nil && *vm.Name == node.Name
+		})
+	case config.CustomAttributeNodeDiscoveryStrategy:
+		return getVMByCustomAttribute(ctx, nClient, node, nodeDiscovery.CustomAttributeKey, node.Name, nil)
+	}
+	return nil, fmt.Errorf("unsupported node discovery strategy: %s", strategy)
+}
+
+// getVMByProviderID returns the VM whose providerID is the providerID of the node, nil if the
+// node has no nutanix providerID, or a not found error. The providerID is either derived from
+// the VM UUID or set with the providerID:<id> custom attribute of the VM.
+func (n *nutanixManager) getVMByProviderID(ctx context.Context, nClient interfaces.Prism, node *v1.Node) (*vmmModels.Vm, error) {
+	providerID := node.Spec.ProviderID
+	id, ok := strings.CutPrefix(providerID, constants.ProviderName+"://")
+	if !ok || id == "" {
+		return nil, nil
+	}
+
+	if isUUID(id) {
+		vm, err := nClient.GetVM(ctx, strings.ToLower(id))
+		if err != nil && !converged.IsNotFound(err) {
+			return nil, err
+		}
+		// A VM with a custom providerID is not the VM of the node, even if its UUID is the
+		// providerID of the node.
+		if err == nil && n.hasProviderID(ctx, vm, providerID) {
+			return vm, nil
+		}
+	}
+
+	return getVMByCustomAttribute(ctx, nClient, node, constants.ProviderIDAttributeKey, id, func(vm *vmmModels.Vm) bool {
+		return n.hasProviderID(ctx, vm, providerID)
+	})
+}
+
+// getVMByCustomAttribute returns the only VM with the key:value custom attribute also matching
+// the match function, if any. Prism Central filters can only match the custom attributes
+// exactly, so the VMs are listed with the attribute written with the key as given or in
+// lowercase, and with or without a space after the separator.
+func getVMByCustomAttribute(ctx context.Context, nClient interfaces.Prism, node *v1.Node, key, value string, match func(vm *vmmModels.Vm) bool) (*vmmModels.Vm, error) {
+	return getSingleVM(ctx, nClient, node, customAttributeFilter(key, value), fmt.Sprintf("with custom attribute %s:%s", key, value), func(vm *vmmModels.Vm) bool {
+		return strings.EqualFold(getVMCustomAttributeIgnoreCase(vm, key), value) && (match == nil || match(vm))
+	})
+}
+
+// customAttributeFilter returns the filter of the VMs with one of the spellings of the key:value
+// custom attribute.
+func customAttributeFilter(key, value string) string {
+	var filters []string
+	for _, attrKey := range []string{key, strings.ToLower(key)} {
+		for _, separator := range []string{":", ": "} {
+			filter := fmt.Sprintf("customAttributes/any(a:a eq '%s')", escapeODataString(attrKey+separator+value))
+			if !slices.Contains(filters, filter) {
+				filters = append(filters, filter)
+			}
+		}
+	}
+	return strings.Join(filters, " or ")
+}
+
+// hasProviderID returns true if the providerID generated for the VM is the providerID.
+func (n *nutanixManager) hasProviderID(ctx context.Context, vm *vmmModels.Vm, providerID string) bool {
+	vmProviderID, err := n.generateProviderIDFromVM(ctx, vm)
+	return err == nil && strings.EqualFold(vmProviderID, providerID)
+}
+
+// getSingleVM returns the only VM matching both the filter and the match function, which are
+// described by description. It returns a not found error if no VM matches, and an error if
+// several VMs match, as the VM of the node is ambiguous.
+func getSingleVM(ctx context.Context, nClient interfaces.Prism, node *v1.Node, filter, description string, match func(vm *vmmModels.Vm) bool) (*vmmModels.Vm, error) {
+	vms, err := nClient.ListVMs(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VMs with filter %q: %w", filter, err)
+	}
+	var vmUUIDs []string
+	for i := range vms {
+		if vms[i].ExtId != nil && match(&vms[i]) {
+			vmUUIDs = append(vmUUIDs, *vms[i].ExtId)
+		}
+	}
+	switch len(vmUUIDs) {
+	case 0:
+		return nil, &converged.APIError{
+			Kind:    converged.ErrNotFound,
+			Message: fmt.Sprintf("no VM %s", description),
+		}
+	case 1:
+		return nClient.GetVM(ctx, vmUUIDs[0])
+	}
+	return nil, fmt.Errorf("found VMs %v %s: cannot tell which one is the VM of node %s", vmUUIDs, description, node.Name)
+}
+
+// getNodeDiscoveryConfig returns the node discovery configuration, or the default one if the
+// manager was not created from a completed configuration.
+func (n *nutanixManager) getNodeDiscoveryConfig() *config.NodeDiscoveryConfig {
+	if n.config.NodeDiscovery != nil {
+		return n.config.NodeDiscovery
+	}
+	return &config.NodeDiscoveryConfig{
+		Strategies:         slices.Clone(config.DefaultNodeDiscoveryStrategies),
+		CustomAttributeKey: config.DefaultNodeDiscoveryCustomAttributeKey,
+	}
+}
+
+// escapeODataString escapes the single quotes of an OData string literal.
+func escapeODataString(value string) string {
+	return strings.ReplaceAll(value, "'", "''")
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:typecheck // Test file uses ginkgo/gomega which typecheck doesn't understand well
+package provider
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nutanix-cloud-native/prism-go-client/converged"
+	credentialTypes "github.com/nutanix-cloud-native/prism-go-client/environment/credentials"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+var _ = Describe("Test Node Discovery", func() { // nolint:typecheck
+	var (
+		ctx             context.Context
+		kClient         *fake.Clientset
+		mockEnvironment *mock.MockEnvironment
+		nClient         interfaces.Prism
+		nodeDiscovery   *config.NodeDiscoveryConfig
+		m               *nutanixManager
+	)
+
+	// getNode returns a copy of the node of the VM whose system UUID does not match any VM.
+	getNode := func(vmName string) *v1.Node {
+		node := mockEnvironment.GetNode(vmName).DeepCopy()
+		node.Status.NodeInfo.SystemUUID = string(uuid.NewUUID())
+		return node
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		kClient = fake.NewSimpleClientset()
+		var err error
+		mockEnvironment, err = mock.CreateMockEnvironment(ctx, kClient)
+		Expect(err).ToNot(HaveOccurred())
+		nodeDiscovery = nil
+	})
+
+	JustBeforeEach(func() {
+		cBytes, err := json.Marshal(config.Config{
+			PrismCentral:  credentialTypes.NutanixPrismEndpoint{Address: "pc.example.com"},
+			NodeDiscovery: nodeDiscovery,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		c, err := config.NewConfigFromBytes(cBytes)
+		Expect(err).ToNot(HaveOccurred())
+		m, err = newNutanixManager(c)
+		Expect(err).ToNot(HaveOccurred())
+		m.client = kClient
+		nutanixClient := mock.CreateMockClient(*mockEnvironment)
+		m.nutanixClient = nutanixClient
+		nClient, err = nutanixClient.Get()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should find the VM by system UUID", func() {
+		vm, err := m.findNodeVM(ctx, nClient, mockEnvironment.GetNode(mock.MockVMNamePoweredOn))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(vm.ExtId).To(Equal(ptr.To(mock.MockVMPoweredOnUUID)))
+	})
+
+	It("should fall back to the providerID by default", func() {
+		node := getNode(mock.MockVMNamePoweredOn)
+		node.Spec.ProviderID = "nutanix://" + mock.MockVMPoweredOnUUID
+		vmUUID, err := m.getNutanixInstanceIDForNode(ctx, node)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(vmUUID).To(Equal(mock.MockVMPoweredOnUUID))
+	})
+
+	It("should not find the VM by name by default", func() {
+		node := getNode(mock.MockVMNamePoweredOn)
+		_, err := m.findNodeVM(ctx, nClient, node)
+		Expect(converged.IsNotFound(err)).To(BeTrue())
+		exists, err := m.nodeExists(ctx, node)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exists).To(BeFalse())
+	})
+
+	It("should fail if no strategy applies to the node", func() {
+		_, err := m.findNodeVM(ctx, nClient, mockEnvironment.GetNode(mock.MockNodeNameNoSystemUUID))
+		Expect(err).To(HaveOccurred())
+		Expect(converged.IsNotFound(err)).To(BeFalse())
+	})
+
+	Context("Test custom providerID", func() {
+		var node *v1.Node
+
+		BeforeEach(func() {
+			node = mockEnvironment.GetNode(mock.MockVMNameCustomProviderID).DeepCopy()
+			node.Status.NodeInfo.SystemUUID = ""
+			node.Spec.ProviderID = "nutanix://" + mock.MockCustomProviderID
+		})
+
+		It("should find the VM by its custom providerID", func() {
+			vmUUID, err := m.getNutanixInstanceIDForNode(ctx, node)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vmUUID).To(Equal(mock.MockVMCustomProviderIDUUID))
+		})
+
+		DescribeTable("should find the VM with the normalised spellings of the custom attribute",
+			func(attribute string) {
+				mockEnvironment.GetVM(ctx, mock.MockVMNameCustomProviderID).CustomAttributes = []string{attribute}
+				vmUUID, err := m.getNutanixInstanceIDForNode(ctx, node)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(vmUUID).To(Equal(mock.MockVMCustomProviderIDUUID))
+				exists, err := m.nodeExists(ctx, node)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(exists).To(BeTrue())
+			},
+			Entry("lowercase key", "providerid:"+mock.MockCustomProviderID),
+			Entry("space after the separator", "providerID: "+mock.MockCustomProviderID),
+		)
+
+		It("should only list the VMs with the custom attribute", func() {
+			vms, err := nClient.ListVMs(ctx, customAttributeFilter(constants.ProviderIDAttributeKey, mock.MockCustomProviderID))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vms).To(ConsistOf(HaveField("ExtId", ptr.To(mock.MockVMCustomProviderIDUUID))))
+		})
+
+		It("should report the node as existing and running", func() {
+			exists, err := m.nodeExists(ctx, node)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeTrue())
+			shutdown, err := m.isNodeShutdown(ctx, node)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(shutdown).To(BeFalse())
+		})
+
+		It("should not find a VM with a custom providerID by its UUID", func() {
+			node.Spec.ProviderID = "nutanix://" + mock.MockVMCustomProviderIDUUID
+			exists, err := m.nodeExists(ctx, node)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeFalse())
+		})
+
+		It("should report the node as not existing if no VM has the custom providerID", func() {
+			mockEnvironment.GetVM(ctx, mock.MockVMNameCustomProviderID).CustomAttributes = nil
+			exists, err := m.nodeExists(ctx, node)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeFalse())
+		})
+	})
+
+	Context("Test VMName strategy", func() {
+		BeforeEach(func() {
+			nodeDiscovery = &config.NodeDiscoveryConfig{
+				Strategies: []config.NodeDiscoveryStrategy{config.SystemUUIDNodeDiscoveryStrategy, config.VMNameNodeDiscoveryStrategy},
+			}
+		})
+
+		It("should find the VM by name", func() {
+			vm, err := m.findNodeVM(ctx, nClient, getNode(mock.MockVMNamePoweredOn))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vm.ExtId).To(Equal(ptr.To(mock.MockVMPoweredOnUUID)))
+		})
+
+		It("should fail if several VMs have the name of the node", func() {
+			mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOff).Name = ptr.To(mock.MockVMNamePoweredOn)
+			_, err := m.findNodeVM(ctx, nClient, getNode(mock.MockVMNamePoweredOn))
+			Expect(err).To(HaveOccurred())
+			Expect(converged.IsNotFound(err)).To(BeFalse())
+		})
+
+		It("should report the node as not existing if no VM has its name", func() {
+			node := getNode(mock.MockVMNamePoweredOn)
+			node.Name = "mock-node-without-vm"
+			exists, err := m.nodeExists(ctx, node)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeFalse())
+		})
+	})
+
+	Context("Test CustomAttribute strategy", func() {
+		BeforeEach(func() {
+			nodeDiscovery = &config.NodeDiscoveryConfig{
+				Strategies:         []config.NodeDiscoveryStrategy{config.CustomAttributeNodeDiscoveryStrategy, config.SystemUUIDNodeDiscoveryStrategy},
+				CustomAttributeKey: "k8sNodeName",
+			}
+		})
+
+		It("should find the VM by custom attribute before the system UUID", func() {
+			node := mockEnvironment.GetNode(mock.MockVMNamePoweredOn)
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOff)
+			vm.CustomAttributes = append(vm.CustomAttributes, "k8sNodeName:"+node.Name)
+			vmUUID, err := m.getNutanixInstanceIDForNode(ctx, node)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vmUUID).To(Equal(mock.MockVMPoweredOffUUID))
+		})
+
+		It("should find the VM regardless of the case and whitespace of the custom attribute", func() {
+			node := mockEnvironment.GetNode(mock.MockVMNamePoweredOn)
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOff)
+			vm.CustomAttributes = append(vm.CustomAttributes, "k8snodename: "+node.Name)
+			vmUUID, err := m.getNutanixInstanceIDForNode(ctx, node)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vmUUID).To(Equal(mock.MockVMPoweredOffUUID))
+		})
+
+		It("should fall back to the system UUID", func() {
+			vmUUID, err := m.getNutanixInstanceIDForNode(ctx, mockEnvironment.GetNode(mock.MockVMNamePoweredOn))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vmUUID).To(Equal(mock.MockVMPoweredOnUUID))
+		})
+	})
+})
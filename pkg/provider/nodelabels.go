@@ -0,0 +1,209 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/nutanix-cloud-native/prism-go-client/converged"
+	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	cloudproviderapi "k8s.io/cloud-provider/api"
+	"k8s.io/klog/v2"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+// ownedNodeLabels are the node labels set by the CCM besides the category labels, which are
+// removed from the nodes when they no longer apply.
+var ownedNodeLabels = []string{
+	constants.CustomPEUUIDLabel,
+	constants.CustomPENameLabel,
+	constants.CustomHostUUIDLabel,
+	constants.CustomHostNameLabel,
+	constants.MetroNodeGroupLabel,
+}
+
+// nodeLabelController periodically reconciles the labels and taints owned by the CCM on the
+// initialized nodes with Prism Central. The cloud node controller only sets them when
+// initializing nodes.
+type nodeLabelController struct {
+	manager     *nutanixManager
+	nodeLister  corelisters.NodeLister
+	nodesSynced cache.InformerSynced
+	period      time.Duration
+}
+
+func newNodeLabelController(manager *nutanixManager, nodeInformer coreinformers.NodeInformer, period time.Duration) *nodeLabelController {
+	return &nodeLabelController{
+		manager:     manager,
+		nodeLister:  nodeInformer.Lister(),
+		nodesSynced: nodeInformer.Informer().HasSynced,
+		period:      period,
+	}
+}
+
+// run reconciles the node labels every period until stopCh is closed.
+func (c *nodeLabelController) run(stopCh <-chan struct{}) {
+	if !cache.WaitForCacheSync(stopCh, c.nodesSynced) {
+		klog.Error("failed to sync the node informer cache of the node label controller") //nolint:typecheck
+		return
+	}
+	klog.Infof("Starting the node label controller with period %s", c.period) //nolint:typecheck
+	ctx := wait.ContextForChannel(stopCh)
+	wait.Until(func() { c.reconcileNodes(ctx) }, c.period, stopCh)
+}
+
+func (c *nodeLabelController) reconcileNodes(ctx context.Context) {
+	nodes, err := c.nodeLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list nodes: %v", err) //nolint:typecheck
+		return
+	}
+	for _, node := range nodes {
+		if err := c.reconcileNode(ctx, node); err != nil {
+			klog.Errorf("failed to reconcile labels and taints of node %s: %v", node.Name, err) //nolint:typecheck
+		}
+	}
+}
+
+// reconcileNode updates the labels and taints owned by the CCM on the node, and removes the ones
+// that no longer apply. It also updates the Prism Central annotation of the node. Nodes that
+// are not initialized yet and nodes whose VM does not exist are skipped.
+func (c *nodeLabelController) reconcileNode(ctx context.Context, node *v1.Node) error {
+	if hasCloudTaint(node) {
+		return nil
+	}
+
+	m := c.manager.current()
+	nodeVM, err := m.getNodeVM(ctx, node)
+	if err != nil {
+		if converged.IsNotFound(err) {
+			klog.V(1).Infof("skipping node %s: VM not found", node.Name) //nolint:typecheck
+			return nil
+		}
+		return err
+	}
+	nClient, vm := nodeVM.nClient, nodeVM.vm
+
+	desired, err := m.getNodeLabels(ctx, nClient, vm)
+	if err != nil {
+		return err
+	}
+
+	patch := map[string]*string{}
+	for key := range node.Labels {
+		if _, ok := desired[key]; !ok && m.isOwnedNodeLabel(key) {
+			patch[key] = nil
+		}
+	}
+	for key, value := range desired {
+		if current, ok := node.Labels[key]; !ok || current != value {
+			patch[key] = &value
+		}
+	}
+	if len(patch) > 0 {
+		klog.V(1).Infof("reconciling labels of node %s", node.Name) //nolint:typecheck
+		if err := m.patchNodeLabels(ctx, node.Name, patch); err != nil {
+			return err
+		}
+	}
+
+	taints, err := m.getNodeTaints(ctx, nClient, vm)
+	if err != nil {
+		return err
+	}
+	if err := m.reconcileNodeTaints(node, taints); err != nil {
+		return err
+	}
+	return m.reconcilePrismCentralAnnotation(ctx, node, nodeVM.prismCentral)
+}
+
+// getNodeLabels returns the labels owned by the CCM that apply to the node of the VM.
+func (n *nutanixManager) getNodeLabels(ctx context.Context, nClient interfaces.Prism, vm *vmmModels.Vm) (map[string]string, error) {
+	var err error
+	nodeLabels := map[string]string{}
+	if n.config.EnableCustomLabeling {
+		if nodeLabels, err = n.getCustomLabels(ctx, nClient, vm); err != nil {
+			return nil, err
+		}
+	}
+
+	categoryLabels, err := n.getCategoryLabels(ctx, nClient, vm)
+	if err != nil {
+		return nil, err
+	}
+	maps.Copy(nodeLabels, categoryLabels)
+
+	groupName := getVMCustomAttributeValue(vm, constants.MetroNodeGroupNameAttributeKey)
+	if groupName != "" && len(k8svalidation.IsValidLabelValue(groupName)) == 0 {
+		nodeLabels[constants.MetroNodeGroupLabel] = groupName
+	}
+	return nodeLabels, nil
+}
+
+// isOwnedNodeLabel returns true if the label is set by the CCM: a custom label, the metro
+// node-group label, or the category label of an allowed key. Other labels with the prefix of the
+// category labels are left to their owners.
+func (n *nutanixManager) isOwnedNodeLabel(key string) bool {
+	if slices.Contains(ownedNodeLabels, key) {
+		return true
+	}
+	for _, labelKey := range n.categoryLabelKeys {
+		if labelKey == key {
+			return true
+		}
+	}
+	return false
+}
+
+// patchNodeLabels sets the labels of the node with a non-nil value and removes the others.
+func (n *nutanixManager) patchNodeLabels(ctx context.Context, nodeName string, nodeLabels map[string]*string) error {
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"labels": nodeLabels,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := n.client.CoreV1().Nodes().Patch(ctx, nodeName, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to patch labels of node %s: %w", nodeName, err)
+	}
+	return nil
+}
+
+// hasCloudTaint returns true if the node is not initialized by the cloud node controller yet.
+func hasCloudTaint(node *v1.Node) bool {
+	return slices.ContainsFunc(node.Spec.Taints, func(taint v1.Taint) bool {
+		return taint.Key == cloudproviderapi.TaintExternalCloudProvider
+	})
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:typecheck // Test file uses ginkgo/gomega which typecheck doesn't understand well
+package provider
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	cloudproviderapi "k8s.io/cloud-provider/api"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+)
+
+var _ = Describe("Test Node Label Controller", func() { // nolint:typecheck
+	var (
+		ctx             context.Context
+		kClient         *fake.Clientset
+		mockEnvironment *mock.MockEnvironment
+		m               *nutanixManager
+		c               *nodeLabelController
+	)
+
+	setNodeLabels := func(nodeName string, nodeLabels map[string]string) *v1.Node {
+		node, err := kClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		node.Labels = nodeLabels
+		node, err = kClient.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		return node
+	}
+
+	getNodeLabels := func(nodeName string) map[string]string {
+		node, err := kClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		return node.Labels
+	}
+
+	BeforeEach(func() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		kClient = fake.NewSimpleClientset()
+		var err error
+		mockEnvironment, err = mock.CreateMockEnvironment(ctx, kClient)
+		Expect(err).ToNot(HaveOccurred())
+
+		m, err = newNutanixManager(config.Config{EnableCustomLabeling: true})
+		Expect(err).ToNot(HaveOccurred())
+		m.client = kClient
+		m.nutanixClient = mock.CreateMockClient(*mockEnvironment)
+
+		informerFactory := informers.NewSharedInformerFactory(kClient, 0)
+		c = newNodeLabelController(m, informerFactory.Core().V1().Nodes(), time.Minute)
+		informerFactory.Start(ctx.Done())
+		informerFactory.WaitForCacheSync(ctx.Done())
+	})
+
+	It("should update the host labels after the VM migrated to another host", func() {
+		node := setNodeLabels(mock.MockVMNamePoweredOn, map[string]string{
+			constants.CustomPEUUIDLabel:   mock.MockClusterUUID,
+			constants.CustomPENameLabel:   mock.MockCluster,
+			constants.CustomHostUUIDLabel: mock.MockHostCategoriesUUID,
+			constants.CustomHostNameLabel: "previous-host",
+		})
+		Expect(c.reconcileNode(ctx, node)).To(Succeed())
+
+		vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+		cluster := mockEnvironment.GetCluster(ctx, mock.MockCluster)
+		nClient, err := m.nutanixClient.Get()
+		Expect(err).ToNot(HaveOccurred())
+		host, err := nClient.GetClusterHost(ctx, *cluster.ExtId, *vm.Host.ExtId)
+		Expect(err).ToNot(HaveOccurred())
+		node.Labels = getNodeLabels(node.Name)
+		mock.CheckAdditionalLabels(node, vm, cluster, host)
+	})
+
+	It("should remove the host labels when the VM is not running on a host", func() {
+		node := setNodeLabels(mock.MockVMNamePoweredOff, map[string]string{
+			constants.CustomPEUUIDLabel:   mock.MockClusterUUID,
+			constants.CustomPENameLabel:   mock.MockCluster,
+			constants.CustomHostUUIDLabel: mock.MockHostUUID,
+			constants.CustomHostNameLabel: "mock-host",
+		})
+		Expect(c.reconcileNode(ctx, node)).To(Succeed())
+
+		node.Labels = getNodeLabels(node.Name)
+		vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOff)
+		mock.CheckAdditionalLabels(node, vm, mockEnvironment.GetCluster(ctx, mock.MockCluster), nil)
+	})
+
+	It("should remove the custom labels when custom labeling is disabled", func() {
+		m.config.EnableCustomLabeling = false
+		node := setNodeLabels(mock.MockVMNamePoweredOn, map[string]string{
+			constants.CustomPEUUIDLabel:   mock.MockClusterUUID,
+			constants.CustomPENameLabel:   mock.MockCluster,
+			constants.CustomHostUUIDLabel: mock.MockHostUUID,
+			constants.CustomHostNameLabel: "mock-host",
+			"kubernetes.io/hostname":      mock.MockVMNamePoweredOn,
+		})
+		Expect(c.reconcileNode(ctx, node)).To(Succeed())
+		Expect(getNodeLabels(node.Name)).To(Equal(map[string]string{"kubernetes.io/hostname": mock.MockVMNamePoweredOn}))
+	})
+
+	It("should sync the metro node-group label", func() {
+		node := setNodeLabels(mock.MockVMNameMetro, nil)
+		Expect(c.reconcileNode(ctx, node)).To(Succeed())
+		Expect(getNodeLabels(node.Name)).To(HaveKeyWithValue(constants.MetroNodeGroupLabel, mock.MockMetroNodeGroupName))
+
+		node = setNodeLabels(mock.MockVMNamePoweredOn, map[string]string{constants.MetroNodeGroupLabel: mock.MockMetroNodeGroupName})
+		Expect(c.reconcileNode(ctx, node)).To(Succeed())
+		Expect(getNodeLabels(node.Name)).ToNot(HaveKey(constants.MetroNodeGroupLabel))
+	})
+
+	It("should skip nodes that are not initialized", func() {
+		node, err := kClient.CoreV1().Nodes().Get(ctx, mock.MockVMNamePoweredOn, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		node.Spec.Taints = []v1.Taint{{Key: cloudproviderapi.TaintExternalCloudProvider, Effect: v1.TaintEffectNoSchedule}}
+		Expect(c.reconcileNode(ctx, node)).To(Succeed())
+		Expect(getNodeLabels(node.Name)).To(BeEmpty())
+	})
+
+	It("should skip nodes whose VM does not exist", func() {
+		node := mockEnvironment.GetNode(mock.MockNodeNameVMNotExisting)
+		Expect(c.reconcileNode(ctx, node)).To(Succeed())
+	})
+
+	It("should reconcile the labels of all nodes", func() {
+		Eventually(func() ([]*v1.Node, error) {
+			return c.nodeLister.List(labels.Everything())
+		}).ShouldNot(BeEmpty())
+		c.reconcileNodes(ctx)
+		Expect(getNodeLabels(mock.MockVMNamePoweredOn)).To(HaveKey(constants.CustomHostNameLabel))
+		Expect(getNodeLabels(mock.MockVMNameMetro)).To(HaveKeyWithValue(constants.MetroNodeGroupLabel, mock.MockMetroNodeGroupName))
+	})
+})
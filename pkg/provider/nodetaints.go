@@ -0,0 +1,115 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"slices"
+
+	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/cloud-provider/node/helpers"
+	"k8s.io/klog/v2"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+// getNodeTaints returns the taints of the taint rules matching the VM. The first matching rule
+// wins for a taint key and effect.
+func (n *nutanixManager) getNodeTaints(ctx context.Context, nClient interfaces.Prism, vm *vmmModels.Vm) ([]v1.Taint, error) {
+	taints := []v1.Taint{}
+	if len(n.config.TaintRules) == 0 {
+		return taints, nil
+	}
+
+	var vmCategories map[string][]string
+	customAttributes := getVMCustomAttributes(vm)
+	for _, rule := range n.config.TaintRules {
+		if len(rule.Categories) > 0 && vmCategories == nil {
+			var err error
+			if vmCategories, err = n.getVMCategories(ctx, nClient, vm); err != nil {
+				return nil, err
+			}
+		}
+		if !matchesTaintRule(rule, vmCategories, customAttributes) {
+			continue
+		}
+		taint := v1.Taint{Key: rule.Key, Value: rule.Value, Effect: rule.Effect}
+		if !slices.ContainsFunc(taints, func(t v1.Taint) bool { return t.MatchTaint(&taint) }) {
+			taints = append(taints, taint)
+		}
+	}
+	return taints, nil
+}
+
+// matchesTaintRule returns true if the VM is assigned all of the categories and has all of the
+// custom attributes of the rule.
+func matchesTaintRule(rule config.TaintRule, vmCategories map[string][]string, customAttributes map[string]string) bool {
+	for key, value := range rule.Categories {
+		if !slices.Contains(vmCategories[key], value) {
+			return false
+		}
+	}
+	for key, value := range rule.CustomAttributes {
+		current, ok := customAttributes[key]
+		if !ok || (value != "" && current != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// isOwnedNodeTaint returns true if the taint has the key and effect of a taint rule.
+func (n *nutanixManager) isOwnedNodeTaint(taint *v1.Taint) bool {
+	return slices.ContainsFunc(n.config.TaintRules, func(rule config.TaintRule) bool {
+		return rule.Key == taint.Key && rule.Effect == taint.Effect
+	})
+}
+
+// reconcileNodeTaints adds or updates the taints on the node, and removes the taints owned by
+// the CCM that no longer apply.
+func (n *nutanixManager) reconcileNodeTaints(node *v1.Node, taints []v1.Taint) error {
+	var stale []*v1.Taint
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if n.isOwnedNodeTaint(taint) && !slices.ContainsFunc(taints, func(t v1.Taint) bool { return t.MatchTaint(taint) }) {
+			stale = append(stale, taint)
+		}
+	}
+	var changed []*v1.Taint
+	for i := range taints {
+		taint := &taints[i]
+		if !slices.ContainsFunc(node.Spec.Taints, func(t v1.Taint) bool { return t.MatchTaint(taint) && t.Value == taint.Value }) {
+			changed = append(changed, taint)
+		}
+	}
+
+	if len(stale) > 0 {
+		klog.V(1).Infof("removing taints %v from node %s", stale, node.Name) //nolint:typecheck
+		if err := helpers.RemoveTaintOffNode(n.client, node.Name, node, stale...); err != nil {
+			return err
+		}
+	}
+	if len(changed) > 0 {
+		klog.V(1).Infof("adding taints %v to node %s", changed, node.Name) //nolint:typecheck
+		if err := helpers.AddOrUpdateTaintOnNode(n.client, node.Name, changed...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
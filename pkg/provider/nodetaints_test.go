@@ -0,0 +1,159 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:typecheck // Test file uses ginkgo/gomega which typecheck doesn't understand well
+package provider
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+var _ = Describe("Test Node Taints", func() { // nolint:typecheck
+	var (
+		ctx             context.Context
+		kClient         *fake.Clientset
+		mockEnvironment *mock.MockEnvironment
+		nClient         interfaces.Prism
+		m               *nutanixManager
+	)
+
+	zoneTaint := v1.Taint{Key: "example.com/zone", Value: mock.MockZone, Effect: v1.TaintEffectNoSchedule}
+	metroTaint := v1.Taint{Key: "example.com/metro", Value: "true", Effect: v1.TaintEffectPreferNoSchedule}
+
+	setNodeTaints := func(nodeName string, taints []v1.Taint) *v1.Node {
+		node, err := kClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		node.Spec.Taints = taints
+		node, err = kClient.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		return node
+	}
+
+	getNodeTaints := func(nodeName string) []v1.Taint {
+		node, err := kClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		return node.Spec.Taints
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		kClient = fake.NewSimpleClientset()
+		var err error
+		mockEnvironment, err = mock.CreateMockEnvironment(ctx, kClient)
+		Expect(err).ToNot(HaveOccurred())
+
+		m, err = newNutanixManager(config.Config{
+			TopologyDiscovery: config.TopologyDiscovery{Type: config.PrismTopologyDiscoveryType},
+			TaintRules: []config.TaintRule{
+				{
+					Categories: map[string]string{mock.MockDefaultZone: mock.MockZone},
+					Key:        zoneTaint.Key,
+					Value:      zoneTaint.Value,
+					Effect:     zoneTaint.Effect,
+				},
+				{
+					Categories: map[string]string{mock.MockDefaultRegion: mock.MockRegion},
+					Key:        zoneTaint.Key,
+					Value:      "other",
+					Effect:     zoneTaint.Effect,
+				},
+				{
+					CustomAttributes: map[string]string{constants.MetroNodeGroupNameAttributeKey: ""},
+					Key:              metroTaint.Key,
+					Value:            metroTaint.Value,
+					Effect:           metroTaint.Effect,
+				},
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		m.client = kClient
+		nutanixClient := mock.CreateMockClient(*mockEnvironment)
+		m.nutanixClient = nutanixClient
+		nClient, err = nutanixClient.Get()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should taint nodes based on the categories of the VM", func() {
+		vm := mockEnvironment.GetVM(ctx, mock.MockVMNameCategories)
+		taints, err := m.getNodeTaints(ctx, nClient, vm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(taints).To(Equal([]v1.Taint{zoneTaint}))
+	})
+
+	It("should apply the first matching rule of a taint key and effect", func() {
+		vm := mockEnvironment.GetVM(ctx, mock.MockVMNameCategories)
+		vm.Categories = vm.Categories[:1]
+		taints, err := m.getNodeTaints(ctx, nClient, vm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(taints).To(ConsistOf(v1.Taint{Key: zoneTaint.Key, Value: "other", Effect: zoneTaint.Effect}))
+	})
+
+	It("should taint nodes based on the custom attributes of the VM", func() {
+		vm := mockEnvironment.GetVM(ctx, mock.MockVMNameMetro)
+		taints, err := m.getNodeTaints(ctx, nClient, vm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(taints).To(Equal([]v1.Taint{metroTaint}))
+
+		m.config.TaintRules[2].CustomAttributes[constants.MetroNodeGroupNameAttributeKey] = "other-group"
+		taints, err = m.getNodeTaints(ctx, nClient, vm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(taints).To(BeEmpty())
+	})
+
+	It("should not taint nodes of VMs matching no rule", func() {
+		vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+		taints, err := m.getNodeTaints(ctx, nClient, vm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(taints).To(BeEmpty())
+	})
+
+	It("should add the taints when initializing nodes", func() {
+		node := mockEnvironment.GetNode(mock.MockVMNameCategories)
+		_, err := m.getInstanceMetadata(ctx, node)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(getNodeTaints(node.Name)).To(ConsistOf(zoneTaint))
+	})
+
+	It("should remove the taints that no longer apply", func() {
+		otherTaint := v1.Taint{Key: zoneTaint.Key, Value: mock.MockZone, Effect: v1.TaintEffectNoExecute}
+		node := setNodeTaints(mock.MockVMNameCategories, []v1.Taint{zoneTaint, metroTaint, otherTaint})
+
+		vm := mockEnvironment.GetVM(ctx, mock.MockVMNameCategories)
+		vm.Categories = nil
+		c := &nodeLabelController{manager: m}
+		Expect(c.reconcileNode(ctx, node)).To(Succeed())
+		Expect(getNodeTaints(node.Name)).To(ConsistOf(otherTaint))
+	})
+
+	It("should update the taints whose value changed", func() {
+		node := setNodeTaints(mock.MockVMNameCategories, []v1.Taint{{Key: zoneTaint.Key, Value: "previous", Effect: zoneTaint.Effect}})
+
+		c := &nodeLabelController{manager: m}
+		Expect(c.reconcileNode(ctx, node)).To(Succeed())
+		Expect(getNodeTaints(node.Name)).To(ConsistOf(zoneTaint))
+	})
+})
@@ -0,0 +1,142 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/nutanix-cloud-native/prism-go-client/converged"
+	convergedV4 "github.com/nutanix-cloud-native/prism-go-client/converged/v4"
+	prismclientv4 "github.com/nutanix-cloud-native/prism-go-client/v4"
+	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+// prismCentral is a Prism Central the nodes are resolved against. The name is empty if only
+// config.PrismCentral is configured.
+type prismCentral struct {
+	name   string
+	client interfaces.Client
+}
+
+// nodeVM is the VM of a node, with the client and the name of the Prism Central managing it.
+type nodeVM struct {
+	vm           *vmmModels.Vm
+	nClient      interfaces.Prism
+	prismCentral string
+}
+
+// newPrismCentralClient returns the client of the named Prism Central of config.PrismCentrals,
+// or of config.PrismCentral if the name is empty, retrying and caching the requests as
+// configured.
+func newPrismCentralClient(cfg config.Config, name string) interfaces.Client {
+	var nutanixClient interfaces.Client = &nutanixClientEnvironment{
+		config:        cfg,
+		clientCache:   convergedV4.NewClientCache(prismclientv4.WithSessionAuth(true)),
+		v4ClientCache: prismclientv4.NewClientCache(prismclientv4.WithSessionAuth(true)),
+		prismCentral:  name,
+	}
+	if cfg.Retry != nil && cfg.CircuitBreaker != nil && cfg.RateLimit != nil {
+		nutanixClient = newRetryingClient(nutanixClient, *cfg.Retry, *cfg.CircuitBreaker, *cfg.RateLimit)
+	}
+	if cfg.Cache != nil && !cfg.Cache.Disabled {
+		nutanixClient = newCachedClient(nutanixClient, *cfg.Cache)
+	}
+	return nutanixClient
+}
+
+// getPrismCentrals returns the Prism Centrals in the configured order.
+func (n *nutanixManager) getPrismCentrals() []prismCentral {
+	first := prismCentral{client: n.nutanixClient}
+	if len(n.config.PrismCentrals) > 0 {
+		first.name = n.config.PrismCentrals[0].Name
+	}
+	return append([]prismCentral{first}, n.additionalPrismCentrals...)
+}
+
+// getNodeVM returns the VM of the node, looked up in the Prism Central of the node annotation
+// first, and then in the other Prism Centrals in order. It returns a not found error only if
+// no Prism Central failed, so that nodes are not deleted while a Prism Central is unreachable.
+func (n *nutanixManager) getNodeVM(ctx context.Context, node *v1.Node) (*nodeVM, error) {
+	if node == nil {
+		return nil, fmt.Errorf("node cannot be nil when getting the VM of the node")
+	}
+
+	prismCentrals := n.getPrismCentrals()
+	if name := node.Annotations[constants.PrismCentralAnnotation]; name != "" {
+		if idx := slices.IndexFunc(prismCentrals, func(pc prismCentral) bool { return pc.name == name }); idx > 0 {
+			owner := prismCentrals[idx]
+			prismCentrals = append([]prismCentral{owner}, slices.Delete(prismCentrals, idx, idx+1)...)
+		}
+	}
+
+	var errs []error
+	var notFoundErr error
+	for _, pc := range prismCentrals {
+		nClient, err := pc.client.Get()
+		if err == nil {
+			var vm *vmmModels.Vm
+			if vm, err = n.findNodeVM(ctx, nClient, node); err == nil {
+				return &nodeVM{vm: vm, nClient: nClient, prismCentral: pc.name}, nil
+			}
+		}
+		if pc.name != "" {
+			err = fmt.Errorf("prism central %s: %w", pc.name, err)
+		}
+		if converged.IsNotFound(err) {
+			notFoundErr = err
+			continue
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return nil, notFoundErr
+}
+
+// reconcilePrismCentralAnnotation annotates the node with the name of the Prism Central
+// managing its VM. Nodes are not annotated if only config.PrismCentral is configured.
+func (n *nutanixManager) reconcilePrismCentralAnnotation(ctx context.Context, node *v1.Node, name string) error {
+	if name == "" || node.Annotations[constants.PrismCentralAnnotation] == name {
+		return nil
+	}
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]string{constants.PrismCentralAnnotation: name},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := n.client.CoreV1().Nodes().Patch(ctx, node.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to annotate node %s with prism central %s: %w", node.Name, name, err)
+	}
+	klog.V(1).Infof("annotated node %s with prism central %s", node.Name, name) //nolint:typecheck
+	return nil
+}
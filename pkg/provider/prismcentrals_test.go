@@ -0,0 +1,208 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:typecheck // Test file uses ginkgo/gomega which typecheck doesn't understand well
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/nutanix-cloud-native/prism-go-client/converged"
+	credentialTypes "github.com/nutanix-cloud-native/prism-go-client/environment/credentials"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	cloudprovider "k8s.io/cloud-provider"
+	"k8s.io/utils/ptr"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+)
+
+var _ = Describe("Test Prism Centrals", func() { // nolint:typecheck
+	const pc2Region = "mock-prism-central-2"
+
+	var (
+		ctx              context.Context
+		kClient          *fake.Clientset
+		mockEnvironment  *mock.MockEnvironment
+		mockEnvironment2 *mock.MockEnvironment
+		mockClient       *mock.MockClient
+		mockClient2      *mock.MockClient
+		m                *nutanixManager
+	)
+
+	getNodeAnnotations := func(nodeName string) map[string]string {
+		node, err := kClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		return node.Annotations
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		kClient = fake.NewSimpleClientset()
+		var err error
+		mockEnvironment, err = mock.CreateMockEnvironment(ctx, kClient)
+		Expect(err).ToNot(HaveOccurred())
+		mockEnvironment2, err = mock.CreateMockEnvironment(ctx, fake.NewSimpleClientset())
+		Expect(err).ToNot(HaveOccurred())
+		mockEnvironment2.GetCluster(ctx, mock.MockPrismCentral).Name = ptr.To(pc2Region)
+
+		cBytes, err := json.Marshal(config.Config{
+			TopologyDiscovery: config.TopologyDiscovery{Type: config.PrismTopologyDiscoveryType},
+			PrismCentrals: []config.PrismCentralConfig{
+				{Name: "pc1", NutanixPrismEndpoint: credentialTypes.NutanixPrismEndpoint{Address: "pc1.example.com", Port: 9440}},
+				{Name: "pc2", NutanixPrismEndpoint: credentialTypes.NutanixPrismEndpoint{Address: "pc2.example.com", Port: 9440}},
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		c, err := config.NewConfigFromBytes(cBytes)
+		Expect(err).ToNot(HaveOccurred())
+		m, err = newNutanixManager(c)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.additionalPrismCentrals).To(HaveLen(1))
+		m.client = kClient
+		mockClient = mock.CreateMockClient(*mockEnvironment)
+		mockClient2 = mock.CreateMockClient(*mockEnvironment2)
+		m.nutanixClient = mockClient
+		m.additionalPrismCentrals[0].client = mockClient2
+	})
+
+	It("should find the VM in the first Prism Central", func() {
+		nodeVM, err := m.getNodeVM(ctx, mockEnvironment.GetNode(mock.MockVMNamePoweredOn))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodeVM.prismCentral).To(Equal("pc1"))
+		Expect(mockClient2.Calls("GetVM")).To(BeZero())
+	})
+
+	It("should find the VM in the other Prism Centrals", func() {
+		mockEnvironment.DeleteVM(mock.MockVMNamePoweredOn)
+		nodeVM, err := m.getNodeVM(ctx, mockEnvironment.GetNode(mock.MockVMNamePoweredOn))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodeVM.prismCentral).To(Equal("pc2"))
+		Expect(nodeVM.vm.ExtId).To(Equal(ptr.To(mock.MockVMPoweredOnUUID)))
+	})
+
+	It("should look up the VM in the Prism Central of the node annotation first", func() {
+		node := mockEnvironment.GetNode(mock.MockVMNamePoweredOn).DeepCopy()
+		node.Annotations = map[string]string{constants.PrismCentralAnnotation: "pc2"}
+		nodeVM, err := m.getNodeVM(ctx, node)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodeVM.prismCentral).To(Equal("pc2"))
+		Expect(mockClient.Calls("GetVM")).To(BeZero())
+	})
+
+	It("should annotate the node and derive the region from its Prism Central", func() {
+		mockEnvironment.DeleteVM(mock.MockVMNamePoweredOn)
+		metadata, err := m.getInstanceMetadata(ctx, mockEnvironment.GetNode(mock.MockVMNamePoweredOn))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(metadata.Region).To(Equal(pc2Region))
+		Expect(getNodeAnnotations(mock.MockVMNamePoweredOn)).To(HaveKeyWithValue(constants.PrismCentralAnnotation, "pc2"))
+	})
+
+	It("should get the metadata of the node from its Prism Central", func() {
+		var err error
+		m.nodeAddressRules, err = parseNodeAddressRules([]config.NodeAddressRule{
+			{SubnetName: mock.MockStorageSubnetName, Type: config.ExcludedNodeAddressType},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		m.config.EnableCustomLabeling = true
+		mockEnvironment.DeleteVM(mock.MockVMNameMultiNIC)
+		node := mockEnvironment.GetNode(mock.MockVMNameMultiNIC).DeepCopy()
+		node.Status.Addresses = nil
+
+		_, err = m.getNodeVM(ctx, node)
+		Expect(err).ToNot(HaveOccurred())
+		nodeVMCalls := mockClient.Calls("GetVM")
+		metadata, err := m.getInstanceMetadata(ctx, node)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(metadata.NodeAddresses).ToNot(ContainElement(HaveField("Address", mock.MockStorageIP)))
+		Expect(mockClient.Calls("GetSubnet")).To(BeZero())
+		Expect(mockClient2.Calls("GetSubnet")).ToNot(BeZero())
+		// The VM of the node is looked up once
+		Expect(mockClient.Calls("GetVM")).To(Equal(2 * nodeVMCalls))
+	})
+
+	It("should create the route via the NIC of the node in its Prism Central", func() {
+		m.config.Routes = &config.RoutesConfig{VPCUUID: mock.MockVPCUUID}
+		r, err := newRoutes(m)
+		Expect(err).ToNot(HaveOccurred())
+		mockEnvironment.DeleteVM(mock.MockVMNameVPC)
+
+		err = r.CreateRoute(ctx, "mock-k8s-cluster", "hint", &cloudprovider.Route{
+			TargetNode:      mock.MockVMNameVPC,
+			DestinationCIDR: "10.244.1.0/24",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mockEnvironment.GetRouteTable(mock.MockRouteTableUUID).Routes).To(HaveLen(1))
+		Expect(mockClient.Calls("ListVMNics")).To(BeZero())
+		Expect(mockClient2.Calls("ListVMNics")).ToNot(BeZero())
+	})
+
+	It("should associate the floating IP with the NIC of the node in its Prism Central", func() {
+		m.config.LoadBalancer = &config.LoadBalancerConfig{
+			FloatingIP: &config.FloatingIPConfig{
+				ExternalSubnetUUID: mock.MockExternalSubnetUUID,
+				VPCUUID:            mock.MockVPCUUID,
+				Association:        config.NodeNICFloatingIPAssociationType,
+			},
+		}
+		lb, err := newLoadBalancer(m)
+		Expect(err).ToNot(HaveOccurred())
+		mockEnvironment.DeleteVM(mock.MockVMNameVPC)
+
+		_, err = lb.EnsureLoadBalancer(ctx, mock.MockCluster, newMockService("svc", "uid-1"), []*v1.Node{mockEnvironment.GetNode(mock.MockVMNameVPC)})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mockClient.Calls("ListVMNics")).To(BeZero())
+		Expect(mockClient2.Calls("ListVMNics")).ToNot(BeZero())
+	})
+
+	It("should report the node as not existing if no Prism Central has its VM", func() {
+		mockEnvironment.DeleteVM(mock.MockVMNamePoweredOn)
+		mockEnvironment2.DeleteVM(mock.MockVMNamePoweredOn)
+		exists, err := m.nodeExists(ctx, mockEnvironment.GetNode(mock.MockVMNamePoweredOn))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exists).To(BeFalse())
+	})
+
+	It("should fail if a Prism Central fails and no other one has the VM", func() {
+		mockEnvironment2.DeleteVM(mock.MockVMNamePoweredOn)
+		mockClient.InjectFailures("GetVM", errors.New("connection refused"))
+		_, err := m.nodeExists(ctx, mockEnvironment.GetNode(mock.MockVMNamePoweredOn))
+		Expect(err).To(HaveOccurred())
+		Expect(converged.IsNotFound(err)).To(BeFalse())
+	})
+
+	It("should find the VM in another Prism Central if a Prism Central fails", func() {
+		mockClient.InjectFailures("GetVM", errors.New("connection refused"))
+		exists, err := m.nodeExists(ctx, mockEnvironment.GetNode(mock.MockVMNamePoweredOn))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exists).To(BeTrue())
+	})
+
+	It("should not annotate the node with a single Prism Central", func() {
+		m.config.PrismCentrals = nil
+		m.additionalPrismCentrals = nil
+		_, err := m.getInstanceMetadata(ctx, mockEnvironment.GetNode(mock.MockVMNamePoweredOn))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(getNodeAnnotations(mock.MockVMNamePoweredOn)).ToNot(HaveKey(constants.PrismCentralAnnotation))
+	})
+})
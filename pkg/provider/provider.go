@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"io"
 
+	"k8s.io/client-go/informers"
 	clientset "k8s.io/client-go/kubernetes"
 	cloudprovider "k8s.io/cloud-provider"
 	"k8s.io/klog/v2"
@@ -31,10 +32,14 @@ import (
 type NtnxCloud struct {
 	name string
 
-	client      clientset.Interface
-	config      config.Config
-	manager     *nutanixManager
-	instancesV2 cloudprovider.InstancesV2
+	client       clientset.Interface
+	config       config.Config
+	manager      *nutanixManager
+	instancesV2  cloudprovider.InstancesV2
+	loadBalancer cloudprovider.LoadBalancer
+	routes       cloudprovider.Routes
+
+	stopCh <-chan struct{}
 }
 
 func init() {
@@ -59,6 +64,7 @@ func newNtnxCloud(configReader io.Reader) (cloudprovider.Interface, error) {
 	if err != nil {
 		return nil, err
 	}
+	registerMetrics()
 
 	ntnx := &NtnxCloud{
 		name:        constants.ProviderName,
@@ -67,6 +73,20 @@ func newNtnxCloud(configReader io.Reader) (cloudprovider.Interface, error) {
 		instancesV2: newInstancesV2(nutanixManager),
 	}
 
+	if nutanixConfig.LoadBalancer != nil {
+		ntnx.loadBalancer, err = newLoadBalancer(nutanixManager)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if nutanixConfig.Routes != nil {
+		ntnx.routes, err = newRoutes(nutanixManager)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return ntnx, err
 }
 
@@ -75,6 +95,7 @@ func (nc *NtnxCloud) Initialize(clientBuilder cloudprovider.ControllerClientBuil
 	stopCh <-chan struct{},
 ) {
 	klog.Info("Initializing client ...") //nolint:typecheck
+	nc.stopCh = stopCh
 	nc.addKubernetesClient(clientBuilder.ClientOrDie("cloud-provider-nutanix"))
 	klog.Infof("Client initialized") //nolint:typecheck
 }
@@ -84,6 +105,21 @@ func (nc *NtnxCloud) addKubernetesClient(kclient clientset.Interface) {
 	nc.manager.setKubernetesClient(kclient)
 }
 
+// SetInformers implements cloudprovider.InformerUser. The node informer keeps the
+// Prism Central response cache consistent with node lifecycle events, and drives the
+// node label and host maintenance controllers.
+func (nc *NtnxCloud) SetInformers(informerFactory informers.SharedInformerFactory) {
+	nodeInformer := informerFactory.Core().V1().Nodes()
+	nc.manager.setNodeInformer(nodeInformer)
+
+	if nc.stopCh != nil && nc.config.NodeLabelSync != nil && !nc.config.NodeLabelSync.Disabled {
+		go newNodeLabelController(nc.manager, nodeInformer, nc.config.NodeLabelSync.Period.Duration).run(nc.stopCh)
+	}
+	if nc.stopCh != nil && nc.config.HostMaintenance != nil {
+		go newHostMaintenanceController(nc.manager, nodeInformer, nc.config.HostMaintenance).run(nc.stopCh)
+	}
+}
+
 // ProviderName returns the cloud provider ID.
 func (nc *NtnxCloud) ProviderName() string {
 	return nc.name
@@ -94,12 +130,14 @@ func (nc *NtnxCloud) HasClusterID() bool {
 	return true
 }
 
+// LoadBalancer is only supported when the load balancer is configured
 func (nc *NtnxCloud) LoadBalancer() (cloudprovider.LoadBalancer, bool) {
-	return nc, false
+	return nc.loadBalancer, nc.loadBalancer != nil
 }
 
+// Routes is only supported when routes are configured
 func (nc *NtnxCloud) Routes() (cloudprovider.Routes, bool) {
-	return nil, false
+	return nc.routes, nc.routes != nil
 }
 
 func (nc *NtnxCloud) Clusters() (cloudprovider.Clusters, bool) {
@@ -21,10 +21,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"os"
+	"slices"
 	"testing"
 
+	credentialTypes "github.com/nutanix-cloud-native/prism-go-client/environment/credentials"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes/fake"
 
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
@@ -35,6 +38,8 @@ import (
 var _ = Describe("Test Provider", func() { //nolint:typecheck
 	const mockReaderValue = "mock-reader"
 
+	mockPrismCentral := credentialTypes.NutanixPrismEndpoint{Address: "pc.example.com", Port: 9440}
+
 	var (
 		kClient   *fake.Clientset
 		ntnxCloud NtnxCloud
@@ -82,19 +87,244 @@ var _ = Describe("Test Provider", func() { //nolint:typecheck
 	})
 
 	Context("Test LoadBalancer", func() {
-		It("should not support load balancer functionality", func() {
+		It("should not support load balancer functionality if not configured", func() {
 			nc, b := ntnxCloud.LoadBalancer()
 			Expect(b).To(BeFalse())
-			Expect(nc).To(Equal(&ntnxCloud))
+			Expect(nc).To(BeNil())
+		})
+
+		It("should support load balancer functionality if configured", func() {
+			c := config.Config{
+				PrismCentral: mockPrismCentral,
+				LoadBalancer: &config.LoadBalancerConfig{
+					IPPools: []string{"10.0.0.0/24"},
+				},
+			}
+			cBytes, err := json.Marshal(c)
+			Expect(err).ToNot(HaveOccurred())
+			cloud, err := newNtnxCloud(bytes.NewReader(cBytes))
+			Expect(err).ToNot(HaveOccurred())
+			lb, b := cloud.LoadBalancer()
+			Expect(b).To(BeTrue())
+			Expect(lb).ToNot(BeNil())
+		})
+
+		It("should fail if the load balancer has no IP pools", func() {
+			c := config.Config{
+				PrismCentral: mockPrismCentral,
+				LoadBalancer: &config.LoadBalancerConfig{},
+			}
+			cBytes, err := json.Marshal(c)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = newNtnxCloud(bytes.NewReader(cBytes))
+			Expect(err).To(HaveOccurred())
 		})
 	})
 
 	Context("Test Routes", func() {
-		It("should not support routes functionality", func() {
+		It("should not support routes functionality if not configured", func() {
 			nc, b := ntnxCloud.Routes()
 			Expect(b).To(BeFalse())
 			Expect(nc).To(BeNil())
 		})
+
+		It("should support routes functionality if configured", func() {
+			c := config.Config{
+				PrismCentral: mockPrismCentral,
+				Routes: &config.RoutesConfig{
+					VPCUUID: mock.MockVPCUUID,
+				},
+			}
+			cBytes, err := json.Marshal(c)
+			Expect(err).ToNot(HaveOccurred())
+			cloud, err := newNtnxCloud(bytes.NewReader(cBytes))
+			Expect(err).ToNot(HaveOccurred())
+			r, b := cloud.Routes()
+			Expect(b).To(BeTrue())
+			Expect(r).ToNot(BeNil())
+		})
+
+		It("should fail if routes have no VPC", func() {
+			c := config.Config{
+				PrismCentral: mockPrismCentral,
+				Routes:       &config.RoutesConfig{},
+			}
+			cBytes, err := json.Marshal(c)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = newNtnxCloud(bytes.NewReader(cBytes))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Test InstanceTypes", func() {
+		It("should fail if an instance type has no name", func() {
+			c := config.Config{
+				PrismCentral:  mockPrismCentral,
+				InstanceTypes: []config.InstanceTypeConfig{{MinVCPUs: 4}},
+			}
+			cBytes, err := json.Marshal(c)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = newNtnxCloud(bytes.NewReader(cBytes))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail if the minimum vCPUs of an instance type are greater than the maximum", func() {
+			c := config.Config{
+				PrismCentral:  mockPrismCentral,
+				InstanceTypes: []config.InstanceTypeConfig{{Name: "medium", MinVCPUs: 8, MaxVCPUs: 4}},
+			}
+			cBytes, err := json.Marshal(c)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = newNtnxCloud(bytes.NewReader(cBytes))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail if an instance type name is not a label value", func() {
+			c := config.Config{
+				PrismCentral:      mockPrismCentral,
+				InstanceTypeNames: map[string]string{mock.MockInstanceType: "general purpose"},
+			}
+			cBytes, err := json.Marshal(c)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = newNtnxCloud(bytes.NewReader(cBytes))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Test TaintRules", func() {
+		It("should fail if a taint rule has no selector", func() {
+			c := config.Config{
+				PrismCentral: mockPrismCentral,
+				TaintRules:   []config.TaintRule{{Key: "nvidia.com/gpu", Value: "true", Effect: v1.TaintEffectNoSchedule}},
+			}
+			cBytes, err := json.Marshal(c)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = newNtnxCloud(bytes.NewReader(cBytes))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail if the effect of a taint rule is not supported", func() {
+			c := config.Config{
+				PrismCentral: mockPrismCentral,
+				TaintRules: []config.TaintRule{{
+					Categories: map[string]string{"Workload": "gpu"},
+					Key:        "nvidia.com/gpu",
+					Effect:     "NoRun",
+				}},
+			}
+			cBytes, err := json.Marshal(c)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = newNtnxCloud(bytes.NewReader(cBytes))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Test HostMaintenance", func() {
+		It("should default the host maintenance taint and condition", func() {
+			c := config.Config{PrismCentral: mockPrismCentral, HostMaintenance: &config.HostMaintenanceConfig{}}
+			cBytes, err := json.Marshal(c)
+			Expect(err).ToNot(HaveOccurred())
+			cloud, err := newNtnxCloud(bytes.NewReader(cBytes))
+			Expect(err).ToNot(HaveOccurred())
+			hostMaintenance := cloud.(*NtnxCloud).config.HostMaintenance
+			Expect(hostMaintenance.States).To(Equal(config.DefaultHostMaintenanceStates))
+			Expect(hostMaintenance.Taint).To(Equal(&v1.Taint{Key: config.DefaultHostMaintenanceTaintKey, Effect: v1.TaintEffectNoSchedule}))
+			Expect(hostMaintenance.ConditionType).To(Equal(config.DefaultHostMaintenanceConditionType))
+		})
+
+		It("should fail if the effect of the host maintenance taint is not supported", func() {
+			c := config.Config{
+				PrismCentral: mockPrismCentral,
+				HostMaintenance: &config.HostMaintenanceConfig{
+					Taint: &v1.Taint{Key: config.DefaultHostMaintenanceTaintKey, Effect: "NoRun"},
+				},
+			}
+			cBytes, err := json.Marshal(c)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = newNtnxCloud(bytes.NewReader(cBytes))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Test NodeDiscovery", func() {
+		It("should default the node discovery strategies", func() {
+			cBytes, err := json.Marshal(config.Config{PrismCentral: mockPrismCentral})
+			Expect(err).ToNot(HaveOccurred())
+			cloud, err := newNtnxCloud(bytes.NewReader(cBytes))
+			Expect(err).ToNot(HaveOccurred())
+			nodeDiscovery := cloud.(*NtnxCloud).config.NodeDiscovery
+			Expect(nodeDiscovery.Strategies).To(Equal(config.DefaultNodeDiscoveryStrategies))
+			Expect(nodeDiscovery.CustomAttributeKey).To(Equal(config.DefaultNodeDiscoveryCustomAttributeKey))
+
+			// The defaults are not shared with the config
+			defaultStrategies := slices.Clone(config.DefaultNodeDiscoveryStrategies)
+			nodeDiscovery.Strategies[0] = config.VMNameNodeDiscoveryStrategy
+			Expect(config.DefaultNodeDiscoveryStrategies).To(Equal(defaultStrategies))
+		})
+
+		It("should fail if a node discovery strategy is not supported", func() {
+			c := config.Config{
+				PrismCentral: mockPrismCentral,
+				NodeDiscovery: &config.NodeDiscoveryConfig{
+					Strategies: []config.NodeDiscoveryStrategy{config.SystemUUIDNodeDiscoveryStrategy, "Hostname"},
+				},
+			}
+			cBytes, err := json.Marshal(c)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = newNtnxCloud(bytes.NewReader(cBytes))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail if a node discovery strategy is duplicated", func() {
+			c := config.Config{
+				PrismCentral: mockPrismCentral,
+				NodeDiscovery: &config.NodeDiscoveryConfig{
+					Strategies: []config.NodeDiscoveryStrategy{config.VMNameNodeDiscoveryStrategy, config.VMNameNodeDiscoveryStrategy},
+				},
+			}
+			cBytes, err := json.Marshal(c)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = newNtnxCloud(bytes.NewReader(cBytes))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Test PrismCentrals", func() {
+		prismCentral := func(name string) config.PrismCentralConfig {
+			return config.PrismCentralConfig{
+				Name:                 name,
+				NutanixPrismEndpoint: credentialTypes.NutanixPrismEndpoint{Address: name + ".example.com", Port: 9440},
+			}
+		}
+
+		It("should create a client per Prism Central", func() {
+			c := config.Config{PrismCentrals: []config.PrismCentralConfig{prismCentral("pc1"), prismCentral("pc2")}}
+			cBytes, err := json.Marshal(c)
+			Expect(err).ToNot(HaveOccurred())
+			cloud, err := newNtnxCloud(bytes.NewReader(cBytes))
+			Expect(err).ToNot(HaveOccurred())
+			prismCentrals := cloud.(*NtnxCloud).manager.getPrismCentrals()
+			Expect(prismCentrals).To(HaveLen(2))
+			Expect(prismCentrals[0].name).To(Equal("pc1"))
+			Expect(prismCentrals[1].name).To(Equal("pc2"))
+		})
+
+		It("should fail if both prismCentral and prismCentrals are set", func() {
+			c := mock.GenerateMockConfig()
+			c.PrismCentrals = []config.PrismCentralConfig{prismCentral("pc1")}
+			cBytes, err := json.Marshal(c)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = newNtnxCloud(bytes.NewReader(cBytes))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail if Prism Centrals have the same name", func() {
+			c := config.Config{PrismCentrals: []config.PrismCentralConfig{prismCentral("pc1"), prismCentral("pc1")}}
+			cBytes, err := json.Marshal(c)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = newNtnxCloud(bytes.NewReader(cBytes))
+			Expect(err).To(HaveOccurred())
+		})
 	})
 
 	Context("Test Clusters", func() {
@@ -138,6 +368,7 @@ var _ = Describe("Test Provider", func() { //nolint:typecheck
 
 		It("should fail topologyCategories are not set but discovery type is Categories", func() {
 			config := config.Config{
+				PrismCentral: mockPrismCentral,
 				TopologyDiscovery: config.TopologyDiscovery{
 					Type: config.CategoriesTopologyDiscoveryType,
 				},
@@ -151,6 +382,7 @@ var _ = Describe("Test Provider", func() { //nolint:typecheck
 
 		It("should fail if invalid topology discovery type is passed", func() {
 			config := config.Config{
+				PrismCentral: mockPrismCentral,
 				TopologyDiscovery: config.TopologyDiscovery{
 					Type: "invalid",
 				},
@@ -164,6 +396,7 @@ var _ = Describe("Test Provider", func() { //nolint:typecheck
 
 		It("should fail if invalid topology discovery type is passed", func() {
 			config := config.Config{
+				PrismCentral: mockPrismCentral,
 				TopologyDiscovery: config.TopologyDiscovery{
 					Type: "invalid",
 				},
@@ -177,6 +410,7 @@ var _ = Describe("Test Provider", func() { //nolint:typecheck
 
 		It("should default to Prism topology Discovery", func() {
 			c := config.Config{
+				PrismCentral:      mockPrismCentral,
 				TopologyDiscovery: config.TopologyDiscovery{},
 			}
 			cBytes, err := json.Marshal(c)
@@ -188,6 +422,7 @@ var _ = Describe("Test Provider", func() { //nolint:typecheck
 
 		It("should return valid NtnxCloud when valid reader is passed", func() {
 			config := config.Config{
+				PrismCentral: mockPrismCentral,
 				TopologyDiscovery: config.TopologyDiscovery{
 					Type: config.CategoriesTopologyDiscoveryType,
 					TopologyCategories: &config.TopologyCategories{
@@ -0,0 +1,119 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/flowcontrol"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+)
+
+// responseHeaderError is implemented by errors carrying the headers of the HTTP response.
+type responseHeaderError interface {
+	ResponseHeader() http.Header
+}
+
+// prismRateLimiter limits the rate of Prism Central requests with a token bucket, and holds off
+// all requests after Prism Central throttled one. A nil prismRateLimiter does not limit requests.
+type prismRateLimiter struct {
+	tokenBucket       flowcontrol.RateLimiter
+	defaultRetryAfter time.Duration
+
+	mu             sync.Mutex
+	throttledUntil time.Time
+}
+
+func newPrismRateLimiter(rlConfig config.RateLimitConfig) *prismRateLimiter {
+	l := &prismRateLimiter{
+		defaultRetryAfter: rlConfig.DefaultRetryAfter.Duration,
+	}
+	if !rlConfig.Disabled {
+		l.tokenBucket = flowcontrol.NewTokenBucketRateLimiter(rlConfig.QPS, rlConfig.Burst)
+	}
+	return l
+}
+
+// wait blocks until a request may be sent to Prism Central.
+func (l *prismRateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	delay := time.Until(l.throttledUntil)
+	l.mu.Unlock()
+	if delay > 0 {
+		if err := sleepWithContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+	if l.tokenBucket == nil {
+		return nil
+	}
+	return l.tokenBucket.Wait(ctx)
+}
+
+// throttle holds off requests after err throttled a request and returns the delay. The delay is
+// the Retry-After header of the throttled response if the error carries it, and the default
+// delay otherwise.
+func (l *prismRateLimiter) throttle(err error) time.Duration {
+	if l == nil {
+		return 0
+	}
+	now := time.Now()
+	delay, ok := retryAfter(err, now)
+	if !ok {
+		delay = l.defaultRetryAfter
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := now.Add(delay); until.After(l.throttledUntil) {
+		l.throttledUntil = until
+	}
+	return delay
+}
+
+// retryAfter returns the delay of the Retry-After header of the response of err, given either in
+// seconds or as an HTTP date.
+func retryAfter(err error, now time.Time) (time.Duration, bool) {
+	var headerErr responseHeaderError
+	if !errors.As(err, &headerErr) {
+		return 0, false
+	}
+	value := strings.TrimSpace(headerErr.ResponseHeader().Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:typecheck // Test file uses ginkgo/gomega which typecheck doesn't understand well
+package provider
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/nutanix-cloud-native/prism-go-client/converged"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/clock"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+var _ = Describe("Test Prism rate limiting", func() { // nolint:typecheck
+	var (
+		ctx        context.Context
+		mockClient *mock.MockClient
+		nConfig    config.Config
+		nClient    interfaces.Prism
+	)
+
+	rateLimitErr := func() error {
+		return mock.RateLimitError("")
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		kClient := fake.NewSimpleClientset()
+		mockEnvironment, err := mock.CreateMockEnvironment(ctx, kClient)
+		Expect(err).ToNot(HaveOccurred())
+		mockClient = mock.CreateMockClient(*mockEnvironment)
+
+		nConfig, err = config.NewConfigFromBytes([]byte(`{"prismCentral": {"address": "pc.example.com"}, "rateLimit": {"defaultRetryAfter": "50ms"}}`))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		client := &retryingClient{
+			client:      mockClient,
+			retryConfig: *nConfig.Retry,
+			breaker:     newCircuitBreaker(*nConfig.CircuitBreaker, clock.RealClock{}),
+			limiter:     newPrismRateLimiter(*nConfig.RateLimit),
+		}
+		var err error
+		nClient, err = client.Get()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Context("Test token bucket", func() {
+		BeforeEach(func() {
+			nConfig.RateLimit.QPS = 20
+			nConfig.RateLimit.Burst = 1
+		})
+
+		It("should limit the rate of requests", func() {
+			start := time.Now()
+			for range 3 {
+				_, err := nClient.GetVM(ctx, mock.MockVMPoweredOnUUID)
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(time.Since(start)).To(BeNumerically(">=", 90*time.Millisecond))
+		})
+
+		It("should not limit requests when disabled", func() {
+			nConfig.RateLimit.Disabled = true
+			Expect(newPrismRateLimiter(*nConfig.RateLimit).tokenBucket).To(BeNil())
+		})
+	})
+
+	Context("Test throttled requests", func() {
+		It("should wait for the default delay and retry", func() {
+			mockClient.InjectFailures("GetVM", rateLimitErr())
+			start := time.Now()
+			_, err := nClient.GetVM(ctx, mock.MockVMPoweredOnUUID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+			Expect(mockClient.Calls("GetVM")).To(Equal(2))
+		})
+
+		It("should wait for the Retry-After delay of the response and retry", func() {
+			mockClient.InjectFailures("GetVM", mock.RateLimitError("1"))
+			start := time.Now()
+			_, err := nClient.GetVM(ctx, mock.MockVMPoweredOnUUID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(time.Since(start)).To(BeNumerically(">=", time.Second))
+			Expect(mockClient.Calls("GetVM")).To(Equal(2))
+		})
+
+		It("should retry throttled writes", func() {
+			mockClient.InjectFailures("CreateRoute", rateLimitErr())
+			err := nClient.CreateRoute(ctx, mock.MockRouteTableUUID, &networkingModels.Route{})
+			Expect(converged.IsRateLimit(err)).To(BeFalse())
+			Expect(mockClient.Calls("CreateRoute")).To(Equal(2))
+		})
+	})
+
+	Context("Test throttled requests with limited attempts", func() {
+		BeforeEach(func() {
+			nConfig.Retry.MaxAttempts = 2
+		})
+
+		It("should fail once the maximum number of attempts is reached", func() {
+			mockClient.InjectFailures("GetVM", rateLimitErr(), rateLimitErr())
+			_, err := nClient.GetVM(ctx, mock.MockVMPoweredOnUUID)
+			Expect(converged.IsRateLimit(err)).To(BeTrue())
+			Expect(mockClient.Calls("GetVM")).To(Equal(2))
+		})
+	})
+
+	Context("Test throttled requests with circuit breaker", func() {
+		BeforeEach(func() {
+			nConfig.CircuitBreaker.FailureThreshold = 1
+			nConfig.CircuitBreaker.OpenDuration = metav1.Duration{Duration: time.Minute}
+		})
+
+		It("should not open the circuit breaker", func() {
+			mockClient.InjectFailures("GetVM", rateLimitErr(), rateLimitErr())
+			_, err := nClient.GetVM(ctx, mock.MockVMPoweredOnUUID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mockClient.Calls("GetVM")).To(Equal(3))
+		})
+	})
+})
+
+var _ = Describe("Test Retry-After", func() { // nolint:typecheck
+	now := time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	DescribeTable("retryAfter",
+		func(retryAfterHeader string, expectedDelay time.Duration, expectedOK bool) {
+			delay, ok := retryAfter(mock.RateLimitError(retryAfterHeader), now)
+			Expect(ok).To(Equal(expectedOK))
+			Expect(delay).To(Equal(expectedDelay))
+		},
+		Entry("seconds", "3", 3*time.Second, true),
+		Entry("HTTP date", now.Add(5*time.Second).Format(http.TimeFormat), 5*time.Second, true),
+		Entry("past HTTP date", now.Add(-5*time.Second).Format(http.TimeFormat), time.Duration(0), true),
+		Entry("no header", "", time.Duration(0), false),
+		Entry("negative seconds", "-1", time.Duration(0), false),
+		Entry("invalid value", "soon", time.Duration(0), false),
+	)
+})
@@ -0,0 +1,392 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/nutanix-cloud-native/prism-go-client/converged"
+	clusterModels "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/models/clustermgmt/v4/config"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
+	prismModels "github.com/nutanix/ntnx-api-golang-clients/prism-go-client/v4/models/prism/v4/config"
+	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+// retryBackoffJitter is the fraction of the backoff randomly added to it between attempts.
+const retryBackoffJitter = 0.5
+
+// CircuitOpenError is returned without calling Prism Central while the circuit breaker is open.
+type CircuitOpenError struct {
+	// RetryAfter is the time left until the circuit breaker lets a probe request through
+	RetryAfter time.Duration
+	// LastError is the last transient error returned by Prism Central
+	LastError error
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("Prism Central circuit breaker is open, retry after %s: last error: %v", e.RetryAfter, e.LastError)
+}
+
+// IsCircuitOpen returns true if the error is or wraps a CircuitOpenError.
+func IsCircuitOpen(err error) bool {
+	var circuitOpenErr *CircuitOpenError
+	return errors.As(err, &circuitOpenErr)
+}
+
+// isTransientPrismError returns true if the request may succeed when retried.
+func isTransientPrismError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	switch prismErrorKind(err) {
+	case internalErrorKind, rateLimitErrorKind, timeoutErrorKind:
+		return true
+	case notFoundErrorKind, authErrorKind:
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// circuitBreaker counts consecutive transient errors. Once failureThreshold is reached, the
+// circuit breaker opens and requests fail fast for openDuration. It then lets a single probe
+// request through, which closes the circuit breaker if it does not fail with a transient error.
+// A nil circuitBreaker lets all requests through.
+type circuitBreaker struct {
+	clock            clock.PassiveClock
+	failureThreshold int
+	openDuration     time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+	lastErr  error
+}
+
+func newCircuitBreaker(cbConfig config.CircuitBreakerConfig, clock clock.PassiveClock) *circuitBreaker {
+	if cbConfig.Disabled {
+		return nil
+	}
+	return &circuitBreaker{
+		clock:            clock,
+		failureThreshold: cbConfig.FailureThreshold,
+		openDuration:     cbConfig.OpenDuration.Duration,
+	}
+}
+
+// allow returns a CircuitOpenError if the request must not be sent to Prism Central, and
+// whether the request is the probe of an open circuit breaker.
+func (b *circuitBreaker) allow() (bool, error) {
+	if b == nil {
+		return false, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.failureThreshold {
+		return false, nil
+	}
+	retryAfter := b.openDuration - b.clock.Since(b.openedAt)
+	if retryAfter > 0 || b.probing {
+		return false, &CircuitOpenError{RetryAfter: max(retryAfter, 0), LastError: b.lastErr}
+	}
+	b.probing = true
+	return true, nil
+}
+
+// record records the result of a request let through by allow. Only the result of the probe
+// lets another probe through, the requests let through before the circuit breaker opened may
+// complete while the probe is in flight.
+func (b *circuitBreaker) record(err error, probe bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.probing = false
+	}
+	// Throttled requests are not failures of Prism Central, they are held off by the rate limiter
+	if errors.Is(err, context.Canceled) || converged.IsRateLimit(err) {
+		return
+	}
+	if !isTransientPrismError(err) {
+		if b.failures >= b.failureThreshold {
+			klog.Info("Prism Central circuit breaker closed") //nolint:typecheck
+		}
+		b.failures = 0
+		b.lastErr = nil
+		return
+	}
+	b.failures++
+	b.lastErr = err
+	if b.failures >= b.failureThreshold {
+		if b.failures == b.failureThreshold {
+			klog.Warningf("Prism Central circuit breaker opened after %d consecutive errors: %v", b.failures, err) //nolint:typecheck
+		}
+		b.openedAt = b.clock.Now()
+	}
+}
+
+// retryingClient wraps a client so that the Prism clients it returns retry reads failing with
+// transient errors, and share a circuitBreaker and a prismRateLimiter.
+type retryingClient struct {
+	client      interfaces.Client
+	retryConfig config.RetryConfig
+	breaker     *circuitBreaker
+	limiter     *prismRateLimiter
+}
+
+func newRetryingClient(client interfaces.Client, retryConfig config.RetryConfig, cbConfig config.CircuitBreakerConfig, rlConfig config.RateLimitConfig) *retryingClient {
+	return &retryingClient{
+		client:      client,
+		retryConfig: retryConfig,
+		breaker:     newCircuitBreaker(cbConfig, clock.RealClock{}),
+		limiter:     newPrismRateLimiter(rlConfig),
+	}
+}
+
+func (c *retryingClient) Get() (interfaces.Prism, error) {
+	nClient, err := c.client.Get()
+	if err != nil {
+		return nil, err
+	}
+	return &retryingPrism{prism: nClient, client: c}, nil
+}
+
+func (c *retryingClient) SetInformers(sharedInformers informers.SharedInformerFactory) {
+	c.client.SetInformers(sharedInformers)
+}
+
+func (c *retryingClient) Invalidate() {
+	c.client.Invalidate()
+}
+
+// call calls Prism Central through the circuit breaker and the rate limiter. Idempotent requests
+// are retried with a jittered exponential backoff while they fail with transient errors. Requests
+// throttled by Prism Central are retried after the Retry-After delay, as they were not processed.
+func (c *retryingClient) call(ctx context.Context, operation string, idempotent bool, call func() error) error {
+	backoff := wait.Backoff{
+		Duration: c.retryConfig.InitialBackoff.Duration,
+		Factor:   2,
+		Jitter:   retryBackoffJitter,
+		Steps:    c.retryConfig.MaxAttempts,
+		Cap:      c.retryConfig.MaxBackoff.Duration,
+	}
+	for attempt := 1; ; attempt++ {
+		if err := c.limiter.wait(ctx); err != nil {
+			return err
+		}
+		probe, err := c.breaker.allow()
+		if err != nil {
+			return err
+		}
+		err = call()
+		c.breaker.record(err, probe)
+		if err == nil {
+			return nil
+		}
+		if converged.IsRateLimit(err) {
+			delay := c.limiter.throttle(err)
+			if attempt >= c.retryConfig.MaxAttempts {
+				return err
+			}
+			klog.V(2).Infof("Prism Central throttled %s, retrying in %s", operation, delay) //nolint:typecheck
+			continue
+		}
+		if !idempotent || attempt >= c.retryConfig.MaxAttempts || !isTransientPrismError(err) {
+			return err
+		}
+
+		delay := backoff.Step()
+		klog.V(3).Infof("Retrying %s in %s after attempt %d failed: %v", operation, delay, attempt, err) //nolint:typecheck
+		if sleepWithContext(ctx, delay) != nil {
+			return err
+		}
+	}
+}
+
+// sleepWithContext sleeps for the duration unless the context is done first.
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func callPrism[T any](ctx context.Context, c *retryingClient, operation string, idempotent bool, call func() (T, error)) (T, error) {
+	var out T
+	err := c.call(ctx, operation, idempotent, func() error {
+		var err error
+		out, err = call()
+		return err
+	})
+	return out, err
+}
+
+// retryingPrism sends the requests of the wrapped Prism client through retryingClient.call.
+type retryingPrism struct {
+	prism  interfaces.Prism
+	client *retryingClient
+}
+
+func (p *retryingPrism) GetVM(ctx context.Context, vmUUID string) (*vmmModels.Vm, error) {
+	return callPrism(ctx, p.client, "GetVM", true, func() (*vmmModels.Vm, error) {
+		return p.prism.GetVM(ctx, vmUUID)
+	})
+}
+
+func (p *retryingPrism) ListVMs(ctx context.Context, filter string) ([]vmmModels.Vm, error) {
+	return callPrism(ctx, p.client, "ListVMs", true, func() ([]vmmModels.Vm, error) {
+		return p.prism.ListVMs(ctx, filter)
+	})
+}
+
+func (p *retryingPrism) GetCluster(ctx context.Context, clusterUUID string) (*clusterModels.Cluster, error) {
+	return callPrism(ctx, p.client, "GetCluster", true, func() (*clusterModels.Cluster, error) {
+		return p.prism.GetCluster(ctx, clusterUUID)
+	})
+}
+
+func (p *retryingPrism) ListAllCluster(ctx context.Context) ([]clusterModels.Cluster, error) {
+	return callPrism(ctx, p.client, "ListAllCluster", true, func() ([]clusterModels.Cluster, error) {
+		return p.prism.ListAllCluster(ctx)
+	})
+}
+
+func (p *retryingPrism) GetCategory(ctx context.Context, categoryUUID string) (*prismModels.Category, error) {
+	return callPrism(ctx, p.client, "GetCategory", true, func() (*prismModels.Category, error) {
+		return p.prism.GetCategory(ctx, categoryUUID)
+	})
+}
+
+func (p *retryingPrism) ListCategories(ctx context.Context, filter string) ([]prismModels.Category, error) {
+	return callPrism(ctx, p.client, "ListCategories", true, func() ([]prismModels.Category, error) {
+		return p.prism.ListCategories(ctx, filter)
+	})
+}
+
+func (p *retryingPrism) GetClusterHost(ctx context.Context, clusterUUID string, hostUUID string) (*clusterModels.Host, error) {
+	return callPrism(ctx, p.client, "GetClusterHost", true, func() (*clusterModels.Host, error) {
+		return p.prism.GetClusterHost(ctx, clusterUUID, hostUUID)
+	})
+}
+
+func (p *retryingPrism) ReserveSubnetIPs(ctx context.Context, subnetUUID string, spec *networkingModels.IpReserveSpec) error {
+	return p.client.call(ctx, "ReserveSubnetIPs", false, func() error {
+		return p.prism.ReserveSubnetIPs(ctx, subnetUUID, spec)
+	})
+}
+
+func (p *retryingPrism) UnreserveSubnetIPs(ctx context.Context, subnetUUID string, spec *networkingModels.IpUnreserveSpec) error {
+	return p.client.call(ctx, "UnreserveSubnetIPs", false, func() error {
+		return p.prism.UnreserveSubnetIPs(ctx, subnetUUID, spec)
+	})
+}
+
+func (p *retryingPrism) ListReservedSubnetIPs(ctx context.Context, subnetUUID string) ([]networkingModels.ReservedIp, error) {
+	return callPrism(ctx, p.client, "ListReservedSubnetIPs", true, func() ([]networkingModels.ReservedIp, error) {
+		return p.prism.ListReservedSubnetIPs(ctx, subnetUUID)
+	})
+}
+
+func (p *retryingPrism) GetSubnet(ctx context.Context, subnetUUID string) (*networkingModels.Subnet, error) {
+	return callPrism(ctx, p.client, "GetSubnet", true, func() (*networkingModels.Subnet, error) {
+		return p.prism.GetSubnet(ctx, subnetUUID)
+	})
+}
+
+func (p *retryingPrism) GetVPC(ctx context.Context, vpcUUID string) (*networkingModels.Vpc, error) {
+	return callPrism(ctx, p.client, "GetVPC", true, func() (*networkingModels.Vpc, error) {
+		return p.prism.GetVPC(ctx, vpcUUID)
+	})
+}
+
+func (p *retryingPrism) ListVMNics(ctx context.Context, vmUUID string) ([]vmmModels.Nic, error) {
+	return callPrism(ctx, p.client, "ListVMNics", true, func() ([]vmmModels.Nic, error) {
+		return p.prism.ListVMNics(ctx, vmUUID)
+	})
+}
+
+func (p *retryingPrism) ListFloatingIPs(ctx context.Context, filter string) ([]networkingModels.FloatingIp, error) {
+	return callPrism(ctx, p.client, "ListFloatingIPs", true, func() ([]networkingModels.FloatingIp, error) {
+		return p.prism.ListFloatingIPs(ctx, filter)
+	})
+}
+
+func (p *retryingPrism) CreateFloatingIP(ctx context.Context, floatingIP *networkingModels.FloatingIp) error {
+	return p.client.call(ctx, "CreateFloatingIP", false, func() error {
+		return p.prism.CreateFloatingIP(ctx, floatingIP)
+	})
+}
+
+func (p *retryingPrism) UpdateFloatingIP(ctx context.Context, floatingIP *networkingModels.FloatingIp) error {
+	return p.client.call(ctx, "UpdateFloatingIP", false, func() error {
+		return p.prism.UpdateFloatingIP(ctx, floatingIP)
+	})
+}
+
+func (p *retryingPrism) DeleteFloatingIP(ctx context.Context, floatingIPUUID string) error {
+	return p.client.call(ctx, "DeleteFloatingIP", false, func() error {
+		return p.prism.DeleteFloatingIP(ctx, floatingIPUUID)
+	})
+}
+
+func (p *retryingPrism) ListRouteTables(ctx context.Context, filter string) ([]networkingModels.RouteTable, error) {
+	return callPrism(ctx, p.client, "ListRouteTables", true, func() ([]networkingModels.RouteTable, error) {
+		return p.prism.ListRouteTables(ctx, filter)
+	})
+}
+
+func (p *retryingPrism) ListRoutes(ctx context.Context, routeTableUUID string, filter string) ([]networkingModels.Route, error) {
+	return callPrism(ctx, p.client, "ListRoutes", true, func() ([]networkingModels.Route, error) {
+		return p.prism.ListRoutes(ctx, routeTableUUID, filter)
+	})
+}
+
+func (p *retryingPrism) CreateRoute(ctx context.Context, routeTableUUID string, route *networkingModels.Route) error {
+	return p.client.call(ctx, "CreateRoute", false, func() error {
+		return p.prism.CreateRoute(ctx, routeTableUUID, route)
+	})
+}
+
+func (p *retryingPrism) DeleteRoute(ctx context.Context, routeTableUUID string, routeUUID string) error {
+	return p.client.call(ctx, "DeleteRoute", false, func() error {
+		return p.prism.DeleteRoute(ctx, routeTableUUID, routeUUID)
+	})
+}
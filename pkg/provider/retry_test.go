@@ -0,0 +1,209 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:typecheck // Test file uses ginkgo/gomega which typecheck doesn't understand well
+package provider
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/nutanix-cloud-native/prism-go-client/converged"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+var _ = Describe("Test Prism retries", func() { // nolint:typecheck
+	var (
+		ctx         context.Context
+		mockClient  *mock.MockClient
+		fakeClock   *clocktesting.FakeClock
+		retryConfig config.RetryConfig
+		cbConfig    config.CircuitBreakerConfig
+		nClient     interfaces.Prism
+	)
+
+	internalErr := func() error {
+		return &converged.APIError{Kind: converged.ErrInternal, Cause: errors.New("503 Service Unavailable")}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		kClient := fake.NewSimpleClientset()
+		mockEnvironment, err := mock.CreateMockEnvironment(ctx, kClient)
+		Expect(err).ToNot(HaveOccurred())
+		mockClient = mock.CreateMockClient(*mockEnvironment)
+		fakeClock = clocktesting.NewFakeClock(time.Now())
+
+		c, err := config.NewConfigFromBytes([]byte(`{"prismCentral": {"address": "pc.example.com"}, "retry": {"initialBackoff": "1ms", "maxBackoff": "5ms"}}`))
+		Expect(err).ToNot(HaveOccurred())
+		retryConfig = *c.Retry
+		cbConfig = *c.CircuitBreaker
+	})
+
+	JustBeforeEach(func() {
+		client := &retryingClient{
+			client:      mockClient,
+			retryConfig: retryConfig,
+			breaker:     newCircuitBreaker(cbConfig, fakeClock),
+		}
+		var err error
+		nClient, err = client.Get()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Context("Test retries", func() {
+		It("should retry reads failing with transient errors", func() {
+			mockClient.InjectFailures("GetVM", internalErr(), &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET})
+			vm, err := nClient.GetVM(ctx, mock.MockVMPoweredOnUUID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*vm.ExtId).To(Equal(mock.MockVMPoweredOnUUID))
+			Expect(mockClient.Calls("GetVM")).To(Equal(3))
+		})
+
+		It("should give up after the maximum number of attempts", func() {
+			mockClient.InjectFailures("GetVM", internalErr(), internalErr(), internalErr(), internalErr())
+			_, err := nClient.GetVM(ctx, mock.MockVMPoweredOnUUID)
+			Expect(converged.IsInternal(err)).To(BeTrue())
+			Expect(mockClient.Calls("GetVM")).To(Equal(retryConfig.MaxAttempts))
+		})
+
+		It("should not retry not found errors", func() {
+			_, err := nClient.GetVM(ctx, "00000000-0000-0000-0000-999999999999")
+			Expect(converged.IsNotFound(err)).To(BeTrue())
+			Expect(mockClient.Calls("GetVM")).To(Equal(1))
+		})
+
+		It("should not retry writes", func() {
+			mockClient.InjectFailures("CreateRoute", internalErr())
+			err := nClient.CreateRoute(ctx, mock.MockRouteTableUUID, &networkingModels.Route{})
+			Expect(converged.IsInternal(err)).To(BeTrue())
+			Expect(mockClient.Calls("CreateRoute")).To(Equal(1))
+		})
+
+		It("should stop retrying when the context is done", func() {
+			retryCtx, cancel := context.WithCancel(ctx)
+			cancel()
+			mockClient.InjectFailures("GetVM", internalErr(), internalErr())
+			_, err := nClient.GetVM(retryCtx, mock.MockVMPoweredOnUUID)
+			Expect(converged.IsInternal(err)).To(BeTrue())
+			Expect(mockClient.Calls("GetVM")).To(Equal(1))
+		})
+	})
+
+	Context("Test circuit breaker", func() {
+		BeforeEach(func() {
+			retryConfig.MaxAttempts = 1
+			cbConfig.FailureThreshold = 2
+			cbConfig.OpenDuration = metav1.Duration{Duration: time.Minute}
+		})
+
+		It("should fail fast while open and close after a successful probe", func() {
+			mockClient.InjectFailures("GetVM", internalErr(), internalErr())
+			for range 2 {
+				_, err := nClient.GetVM(ctx, mock.MockVMPoweredOnUUID)
+				Expect(converged.IsInternal(err)).To(BeTrue())
+			}
+
+			_, err := nClient.GetCluster(ctx, mock.MockClusterUUID)
+			Expect(IsCircuitOpen(err)).To(BeTrue())
+			var circuitOpenErr *CircuitOpenError
+			Expect(errors.As(err, &circuitOpenErr)).To(BeTrue())
+			Expect(circuitOpenErr.RetryAfter).To(Equal(time.Minute))
+			Expect(converged.IsInternal(circuitOpenErr.LastError)).To(BeTrue())
+			Expect(mockClient.Calls("GetCluster")).To(Equal(0))
+
+			fakeClock.Step(time.Minute)
+			_, err = nClient.GetVM(ctx, mock.MockVMPoweredOnUUID)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = nClient.GetCluster(ctx, mock.MockClusterUUID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mockClient.Calls("GetVM")).To(Equal(3))
+		})
+
+		It("should reopen if the probe fails", func() {
+			mockClient.InjectFailures("GetVM", internalErr(), internalErr(), internalErr())
+			for range 2 {
+				_, err := nClient.GetVM(ctx, mock.MockVMPoweredOnUUID)
+				Expect(converged.IsInternal(err)).To(BeTrue())
+			}
+
+			fakeClock.Step(time.Minute)
+			_, err := nClient.GetVM(ctx, mock.MockVMPoweredOnUUID)
+			Expect(converged.IsInternal(err)).To(BeTrue())
+			_, err = nClient.GetVM(ctx, mock.MockVMPoweredOnUUID)
+			Expect(IsCircuitOpen(err)).To(BeTrue())
+			Expect(mockClient.Calls("GetVM")).To(Equal(3))
+		})
+
+		It("should let a single probe through while earlier requests complete", func() {
+			breaker := newCircuitBreaker(cbConfig, fakeClock)
+			probe, err := breaker.allow()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(probe).To(BeFalse())
+			for range 2 {
+				breaker.record(internalErr(), false)
+			}
+
+			fakeClock.Step(time.Minute)
+			probe, err = breaker.allow()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(probe).To(BeTrue())
+			// The request let through before the circuit breaker opened completes during the probe
+			breaker.record(internalErr(), false)
+			fakeClock.Step(time.Minute)
+			_, err = breaker.allow()
+			Expect(IsCircuitOpen(err)).To(BeTrue())
+
+			breaker.record(nil, true)
+			probe, err = breaker.allow()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(probe).To(BeFalse())
+		})
+
+		It("should not count errors that are not transient", func() {
+			for range 3 {
+				_, err := nClient.GetVM(ctx, "00000000-0000-0000-0000-999999999999")
+				Expect(converged.IsNotFound(err)).To(BeTrue())
+			}
+			_, err := nClient.GetVM(ctx, mock.MockVMPoweredOnUUID)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should fail writes fast while open", func() {
+			mockClient.InjectFailures("GetVM", internalErr(), internalErr())
+			for range 2 {
+				_, err := nClient.GetVM(ctx, mock.MockVMPoweredOnUUID)
+				Expect(converged.IsInternal(err)).To(BeTrue())
+			}
+
+			err := nClient.CreateRoute(ctx, mock.MockRouteTableUUID, &networkingModels.Route{})
+			Expect(IsCircuitOpen(err)).To(BeTrue())
+			Expect(mockClient.Calls("CreateRoute")).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,209 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/nutanix-cloud-native/prism-go-client/converged"
+	networkingCommonModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/common/v1/config"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cloudprovider "k8s.io/cloud-provider"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+// routes programs pod CIDR routes into the route table of a Flow VPC. Routes are named
+// <cluster name>-<node name> and tagged with the cluster name in their description, so that
+// only the routes owned by the cluster are listed, and garbage-collected, by the route controller.
+type routes struct {
+	nutanixManager *nutanixManager
+	vpcUUID        string
+}
+
+func newRoutes(nutanixManager *nutanixManager) (cloudprovider.Routes, error) {
+	routesConfig := nutanixManager.config.Routes
+	if routesConfig == nil {
+		return nil, fmt.Errorf("routes config cannot be nil when creating routes")
+	}
+	return &routes{
+		nutanixManager: nutanixManager,
+		vpcUUID:        routesConfig.VPCUUID,
+	}, nil
+}
+
+// ListRoutes lists the routes owned by the cluster in the VPC route table.
+func (r *routes) ListRoutes(ctx context.Context, clusterName string) ([]*cloudprovider.Route, error) {
+	nClient, err := r.nutanixManager.nutanixClient.Get()
+	if err != nil {
+		return nil, err
+	}
+	routeTableUUID, err := r.getRouteTable(ctx, nClient)
+	if err != nil {
+		return nil, err
+	}
+	vpcRoutes, err := nClient.ListRoutes(ctx, routeTableUUID, routeOwnerFilter(clusterName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routes of route table %s: %w", routeTableUUID, err)
+	}
+
+	result := make([]*cloudprovider.Route, 0, len(vpcRoutes))
+	for _, vpcRoute := range vpcRoutes {
+		if vpcRoute.ExtId == nil {
+			continue
+		}
+		destination, err := routeDestination(vpcRoute)
+		if err != nil {
+			klog.Warningf("skipping route %s: %v", *vpcRoute.ExtId, err) //nolint:typecheck
+			continue
+		}
+		result = append(result, &cloudprovider.Route{
+			Name:            *vpcRoute.ExtId,
+			TargetNode:      types.NodeName(strings.TrimPrefix(ptr.Deref(vpcRoute.Name, ""), clusterName+"-")),
+			DestinationCIDR: destination.String(),
+		})
+	}
+	return result, nil
+}
+
+// CreateRoute creates a route for the pod CIDR of the target node, with the VPC NIC of the
+// node VM as next hop.
+func (r *routes) CreateRoute(ctx context.Context, clusterName string, nameHint string, route *cloudprovider.Route) error {
+	destination, err := netip.ParsePrefix(route.DestinationCIDR)
+	if err != nil {
+		return fmt.Errorf("failed to parse destination CIDR %q: %w", route.DestinationCIDR, err)
+	}
+	if !destination.Addr().Is4() {
+		return fmt.Errorf("destination CIDR %s is not an IPv4 CIDR: only IPv4 routes are supported", route.DestinationCIDR)
+	}
+
+	nClient, err := r.nutanixManager.nutanixClient.Get()
+	if err != nil {
+		return err
+	}
+	nextHop, err := r.getNextHop(ctx, route.TargetNode)
+	if err != nil {
+		return err
+	}
+	routeTableUUID, err := r.getRouteTable(ctx, nClient)
+	if err != nil {
+		return err
+	}
+
+	vpcRoute := networkingModels.NewRoute()
+	vpcRoute.Name = ptr.To(fmt.Sprintf("%s-%s", clusterName, route.TargetNode))
+	vpcRoute.Description = ptr.To(routeOwnerTag(clusterName))
+	vpcRoute.RouteType = networkingModels.ROUTETYPE_STATIC.Ref()
+	vpcRoute.VpcReference = ptr.To(r.vpcUUID)
+	vpcRoute.Destination = ipv4Subnet(destination)
+	vpcRoute.Nexthop = networkingModels.NewNexthop()
+	vpcRoute.Nexthop.NexthopType = networkingModels.NEXTHOPTYPE_IP_ADDRESS.Ref()
+	vpcRoute.Nexthop.NexthopIpAddress = ptr.To(ipv4Address(nextHop))
+	if err := nClient.CreateRoute(ctx, routeTableUUID, vpcRoute); err != nil {
+		return fmt.Errorf("failed to create route %s via %s for node %s: %w", destination, nextHop, route.TargetNode, err)
+	}
+	klog.Infof("created route %s via %s for node %s", destination, nextHop, route.TargetNode) //nolint:typecheck
+	return nil
+}
+
+// DeleteRoute deletes a route returned by ListRoutes.
+func (r *routes) DeleteRoute(ctx context.Context, clusterName string, route *cloudprovider.Route) error {
+	nClient, err := r.nutanixManager.nutanixClient.Get()
+	if err != nil {
+		return err
+	}
+	routeTableUUID, err := r.getRouteTable(ctx, nClient)
+	if err != nil {
+		return err
+	}
+	if err := nClient.DeleteRoute(ctx, routeTableUUID, route.Name); err != nil {
+		if converged.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to delete route %s for node %s: %w", route.Name, route.TargetNode, err)
+	}
+	klog.Infof("deleted route %s via node %s", route.DestinationCIDR, route.TargetNode) //nolint:typecheck
+	return nil
+}
+
+func (r *routes) getRouteTable(ctx context.Context, nClient interfaces.Prism) (string, error) {
+	routeTables, err := nClient.ListRouteTables(ctx, fmt.Sprintf("vpcReference eq '%s'", r.vpcUUID))
+	if err != nil {
+		return "", fmt.Errorf("failed to list route tables of VPC %s: %w", r.vpcUUID, err)
+	}
+	for _, routeTable := range routeTables {
+		if routeTable.ExtId != nil {
+			return *routeTable.ExtId, nil
+		}
+	}
+	return "", fmt.Errorf("no route table found for VPC %s", r.vpcUUID)
+}
+
+// getNextHop returns the IPv4 address of the VPC NIC of the node VM, read from the Prism Central
+// of the VM.
+func (r *routes) getNextHop(ctx context.Context, nodeName types.NodeName) (netip.Addr, error) {
+	node, err := r.nutanixManager.client.CoreV1().Nodes().Get(ctx, string(nodeName), metav1.GetOptions{})
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("failed to get node %s: %w", nodeName, err)
+	}
+	nics, err := r.nutanixManager.getNodeVPCNics(ctx, node, r.vpcUUID, map[string]string{})
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	for _, nic := range nics {
+		if address, err := netip.ParseAddr(nicIPv4Address(nic)); err == nil && address.Is4() {
+			return address, nil
+		}
+	}
+	return netip.Addr{}, fmt.Errorf("node %s has no NIC with an IPv4 address in VPC %s", nodeName, r.vpcUUID)
+}
+
+func routeOwnerTag(clusterName string) string {
+	return "kubernetes-cluster=" + clusterName
+}
+
+func routeOwnerFilter(clusterName string) string {
+	return fmt.Sprintf("description eq '%s'", routeOwnerTag(clusterName))
+}
+
+func routeDestination(route networkingModels.Route) (netip.Prefix, error) {
+	if route.Destination == nil || route.Destination.Ipv4 == nil || route.Destination.Ipv4.Ip == nil ||
+		route.Destination.Ipv4.Ip.Value == nil || route.Destination.Ipv4.PrefixLength == nil {
+		return netip.Prefix{}, fmt.Errorf("route has no IPv4 destination")
+	}
+	address, err := netip.ParseAddr(*route.Destination.Ipv4.Ip.Value)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("failed to parse route destination: %w", err)
+	}
+	return netip.PrefixFrom(address, *route.Destination.Ipv4.PrefixLength), nil
+}
+
+func ipv4Subnet(prefix netip.Prefix) *networkingModels.IPSubnet {
+	subnet := networkingModels.NewIPSubnet()
+	subnet.Ipv4 = networkingModels.NewIPv4Subnet()
+	subnet.Ipv4.Ip = networkingCommonModels.NewIPv4Address()
+	subnet.Ipv4.Ip.Value = ptr.To(prefix.Masked().Addr().String())
+	subnet.Ipv4.PrefixLength = ptr.To(prefix.Bits())
+	return subnet
+}
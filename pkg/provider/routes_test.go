@@ -0,0 +1,158 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:typecheck // Test file uses ginkgo/gomega which typecheck doesn't understand well
+package provider
+
+import (
+	"context"
+
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	cloudprovider "k8s.io/cloud-provider"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+)
+
+var _ = Describe("Test Routes", func() { // nolint:typecheck
+	const (
+		clusterName = "mock-k8s-cluster"
+		podCIDR     = "10.244.1.0/24"
+	)
+
+	var (
+		ctx             context.Context
+		mockEnvironment *mock.MockEnvironment
+		m               *nutanixManager
+		r               cloudprovider.Routes
+	)
+
+	vpcRoute := func() *networkingModels.Route {
+		routes := mockEnvironment.GetRouteTable(mock.MockRouteTableUUID).Routes
+		Expect(routes).To(HaveLen(1))
+		for _, route := range routes {
+			return route
+		}
+		return nil
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		kClient := fake.NewSimpleClientset()
+		var err error
+		mockEnvironment, err = mock.CreateMockEnvironment(ctx, kClient)
+		Expect(err).ToNot(HaveOccurred())
+
+		c := mock.GenerateMockConfig()
+		c.Routes = &config.RoutesConfig{
+			VPCUUID: mock.MockVPCUUID,
+		}
+		m, err = newNutanixManager(c)
+		Expect(err).ToNot(HaveOccurred())
+		m.client = kClient
+		m.nutanixClient = mock.CreateMockClient(*mockEnvironment)
+		r, err = newRoutes(m)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Context("Test CreateRoute", func() {
+		It("should create a route via the VPC NIC of the node", func() {
+			err := r.CreateRoute(ctx, clusterName, "hint", &cloudprovider.Route{
+				TargetNode:      mock.MockVMNameVPC,
+				DestinationCIDR: podCIDR,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			route := vpcRoute()
+			Expect(*route.Name).To(Equal(clusterName + "-" + mock.MockVMNameVPC))
+			Expect(*route.Description).To(Equal(routeOwnerTag(clusterName)))
+			Expect(*route.RouteType).To(Equal(networkingModels.ROUTETYPE_STATIC))
+			Expect(*route.Destination.Ipv4.Ip.Value).To(Equal("10.244.1.0"))
+			Expect(*route.Destination.Ipv4.PrefixLength).To(Equal(24))
+			Expect(*route.Nexthop.NexthopType).To(Equal(networkingModels.NEXTHOPTYPE_IP_ADDRESS))
+			Expect(*route.Nexthop.NexthopIpAddress.Ipv4.Value).To(Equal(mock.MockIP))
+		})
+
+		It("should fail if the node has no NIC in the VPC", func() {
+			err := r.CreateRoute(ctx, clusterName, "hint", &cloudprovider.Route{
+				TargetNode:      mock.MockVMNamePoweredOn,
+				DestinationCIDR: podCIDR,
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(mockEnvironment.GetRouteTable(mock.MockRouteTableUUID).Routes).To(BeEmpty())
+		})
+
+		It("should fail for IPv6 destination CIDRs", func() {
+			err := r.CreateRoute(ctx, clusterName, "hint", &cloudprovider.Route{
+				TargetNode:      mock.MockVMNameVPC,
+				DestinationCIDR: "fd00:10:244:1::/64",
+			})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Test ListRoutes", func() {
+		It("should only list the routes owned by the cluster", func() {
+			for _, cluster := range []string{clusterName, "other-cluster"} {
+				err := r.CreateRoute(ctx, cluster, "hint", &cloudprovider.Route{
+					TargetNode:      mock.MockVMNameVPC,
+					DestinationCIDR: podCIDR,
+				})
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			routes, err := r.ListRoutes(ctx, clusterName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(routes).To(HaveLen(1))
+			Expect(routes[0].Name).ToNot(BeEmpty())
+			Expect(routes[0].TargetNode).To(Equal(types.NodeName(mock.MockVMNameVPC)))
+			Expect(routes[0].DestinationCIDR).To(Equal(podCIDR))
+		})
+
+		It("should fail if the VPC has no route table", func() {
+			m.config.Routes.VPCUUID = "00000000-0000-0000-0000-999999999999"
+			r, err := newRoutes(m)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = r.ListRoutes(ctx, clusterName)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Test DeleteRoute", func() {
+		It("should delete the route", func() {
+			err := r.CreateRoute(ctx, clusterName, "hint", &cloudprovider.Route{
+				TargetNode:      mock.MockVMNameVPC,
+				DestinationCIDR: podCIDR,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			routes, err := r.ListRoutes(ctx, clusterName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(routes).To(HaveLen(1))
+
+			Expect(r.DeleteRoute(ctx, clusterName, routes[0])).To(Succeed())
+			Expect(mockEnvironment.GetRouteTable(mock.MockRouteTableUUID).Routes).To(BeEmpty())
+		})
+
+		It("should not return error if the route no longer exists", func() {
+			err := r.DeleteRoute(ctx, clusterName, &cloudprovider.Route{Name: "missing"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+})
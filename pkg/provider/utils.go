@@ -8,7 +8,10 @@ import (
 	"time"
 	"unicode"
 
+	"go4.org/netipx"
+
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
 )
 
 // GetCCMNamespace returns the CCM controller pod namespace
@@ -66,3 +69,29 @@ func SanitizeK8sLabelValue(s string) string {
 
 	return cleaned
 }
+
+var uuidRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// isUUID returns true if the string is a UUID such as the ExtId of a Prism Central entity.
+func isUUID(s string) bool {
+	return uuidRegexp.MatchString(s)
+}
+
+// parseIPSet builds an IPSet from a list of IPv4 or IPv6 addresses, CIDR prefixes and IP ranges
+// parsed with config.ParseIPRange. The field name is used in error messages.
+func parseIPSet(field string, entries []string) (*netipx.IPSet, error) {
+	builder := netipx.IPSetBuilder{}
+	for _, ip := range entries {
+		ipRange, err := config.ParseIPRange(ip)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s %q: %v", field, ip, err)
+		}
+		builder.AddRange(ipRange)
+	}
+
+	ipSet, err := builder.IPSet()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build %s IP set: %v", field, err)
+	}
+	return ipSet, nil
+}
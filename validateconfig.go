@@ -0,0 +1,103 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+)
+
+// newValidateConfigCommand returns the validate-config command, which validates a cloud config
+// without starting the CCM, e.g. before rolling out its ConfigMap.
+func newValidateConfigCommand() *cobra.Command {
+	var file string
+	cmd := &cobra.Command{
+		Use:   "validate-config --file <path>",
+		Short: "Validate a cloud config",
+		Long: `Validate a cloud config and report all of its invalid fields. The file is either the
+cloud config or a ConfigMap manifest with the cloud config in its ` + constants.ConfigMapKey + ` key.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return validateConfigFile(cmd.OutOrStdout(), file)
+		},
+	}
+	cmd.Flags().StringVar(&file, "file", "", "path of the cloud config or of its ConfigMap manifest")
+	_ = cmd.MarkFlagRequired("file")
+
+	// The usage and help of the cloud controller manager list its flags, which do not apply.
+	defaultCmd := &cobra.Command{}
+	cmd.SetUsageFunc(defaultCmd.UsageFunc())
+	cmd.SetHelpFunc(defaultCmd.HelpFunc())
+	return cmd
+}
+
+// validateConfigFile validates the cloud config of the file and writes its invalid fields.
+func validateConfigFile(out io.Writer, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	data, err = getCloudConfig(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+
+	if _, err := config.NewConfigFromBytes(data); err != nil {
+		var aggregate utilerrors.Aggregate
+		if !errors.As(err, &aggregate) {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		for _, fieldErr := range aggregate.Errors() {
+			fmt.Fprintln(out, fieldErr) //nolint:errcheck
+		}
+		return fmt.Errorf("%s: found %d invalid fields", file, len(aggregate.Errors()))
+	}
+	fmt.Fprintf(out, "%s: valid\n", file) //nolint:errcheck
+	return nil
+}
+
+// getCloudConfig returns the cloud config of the ConfigMap manifest, or the data itself if it is
+// not a ConfigMap.
+func getCloudConfig(data []byte) ([]byte, error) {
+	jsonData, err := yaml.ToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	var configMap struct {
+		Kind string            `json:"kind"`
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(jsonData, &configMap); err != nil || configMap.Kind != "ConfigMap" {
+		return data, nil
+	}
+	cloudConfig, ok := configMap.Data[constants.ConfigMapKey]
+	if !ok {
+		return nil, fmt.Errorf("ConfigMap has no %s key", constants.ConfigMapKey)
+	}
+	return []byte(cloudConfig), nil
+}